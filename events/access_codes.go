@@ -0,0 +1,363 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+)
+
+// AccessCode is a code that lists and sells hidden ticket types of an event, such as comp, press or presale
+// tickets. A code without ticket types unlocks every hidden type of the event.
+type AccessCode struct {
+	ID            pgtype.UUID   `json:"id"`
+	EventID       pgtype.UUID   `json:"event_id"`
+	Code          string        `json:"code"`
+	TicketTypeIDs []pgtype.UUID `json:"ticket_type_ids"`
+	// MaxTickets caps how many tickets can be bought with the code, 0 means unlimited. Tickets awaiting payment
+	// count as used.
+	MaxTickets  int32              `json:"max_tickets"`
+	UsedTickets int32              `json:"used_tickets"`
+	StartsAt    pgtype.Timestamptz `json:"starts_at"`
+	EndsAt      pgtype.Timestamptz `json:"ends_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+func toAccessCode(code db.AccessCode) AccessCode {
+	return AccessCode{
+		ID:            code.ID,
+		EventID:       code.EventID,
+		Code:          code.Code,
+		TicketTypeIDs: code.TicketTypeIds,
+		MaxTickets:    code.MaxTickets,
+		UsedTickets:   code.UsedTickets,
+		StartsAt:      code.StartsAt,
+		EndsAt:        code.EndsAt,
+		CreatedAt:     code.CreatedAt,
+		UpdatedAt:     code.UpdatedAt,
+	}
+}
+
+// CreateAccessCodeRequest describes a new access code of an event.
+type CreateAccessCodeRequest struct {
+	Code string `json:"code"`
+	// TicketTypeIDs limits the code to these hidden types of the event, left out it unlocks all of them
+	TicketTypeIDs []uuid.UUID `json:"ticket_type_ids"`
+	MaxTickets    int32       `json:"max_tickets"`
+	// StartsAt and EndsAt bound when the code can be used, left out it can be used right away and until sales end
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// Validate requires a code and checks the ticket limit and validity window.
+func (req *CreateAccessCodeRequest) Validate() error {
+	if err := validateCodeName(req.Code); err != nil {
+		return err
+	}
+	if req.MaxTickets < 0 {
+		return errs.B().Code(errs.InvalidArgument).Msg("Max tickets must not be negative").Err()
+	}
+	return validateCodeWindow(req.StartsAt, req.EndsAt)
+}
+
+// UpdateAccessCodeRequest changes an access code. Fields left out stay as they are.
+type UpdateAccessCodeRequest struct {
+	Code *string `json:"code"`
+	// TicketTypeIDs replaces the types the code unlocks, an empty list makes it unlock all hidden types
+	TicketTypeIDs []uuid.UUID `json:"ticket_type_ids"`
+	MaxTickets    *int32      `json:"max_tickets"`
+	StartsAt      *time.Time  `json:"starts_at"`
+	EndsAt        *time.Time  `json:"ends_at"`
+}
+
+// Validate checks the fields that are set. The validity window is checked once merged with the code.
+func (req *UpdateAccessCodeRequest) Validate() error {
+	if req.Code != nil {
+		if err := validateCodeName(*req.Code); err != nil {
+			return err
+		}
+	}
+	if req.MaxTickets != nil && *req.MaxTickets < 0 {
+		return errs.B().Code(errs.InvalidArgument).Msg("Max tickets must not be negative").Err()
+	}
+
+	return nil
+}
+
+// getAccessCode looks up a code of an event as a buyer entered it and rejects it when it cannot be used right now.
+func getAccessCode(ctx context.Context, q *db.Queries, eventID pgtype.UUID, code string, now time.Time) (db.AccessCode, error) {
+	eb := errs.B()
+
+	accessCode, err := q.GetAccessCodeByCode(ctx, db.GetAccessCodeByCodeParams{
+		EventID: eventID,
+		Code:    code,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return accessCode, eb.Code(errs.NotFound).Msgf("Access code %s not found", code).Err()
+	}
+	if err != nil {
+		return accessCode, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the access code").Err()
+	}
+
+	eb = eb.Code(errs.FailedPrecondition)
+	if accessCode.StartsAt.Valid && now.Before(accessCode.StartsAt.Time) {
+		return accessCode, eb.Msgf("Access code %s can be used from %s", accessCode.Code, accessCode.StartsAt.Time.Format(time.RFC3339)).Err()
+	}
+	if accessCode.EndsAt.Valid && !now.Before(accessCode.EndsAt.Time) {
+		return accessCode, eb.Msgf("Access code %s has expired", accessCode.Code).Err()
+	}
+	if accessCode.MaxTickets > 0 && accessCode.UsedTickets >= accessCode.MaxTickets {
+		return accessCode, eb.Msgf("Access code %s has been used up", accessCode.Code).Err()
+	}
+
+	return accessCode, nil
+}
+
+// accessCodeUnlocks reports whether a code unlocks the given hidden ticket type. A missing code unlocks nothing.
+func accessCodeUnlocks(code db.AccessCode, ticketTypeID pgtype.UUID) bool {
+	if !code.ID.Valid {
+		return false
+	}
+	return len(code.TicketTypeIds) == 0 || slices.Contains(code.TicketTypeIds, ticketTypeID)
+}
+
+// unlockedTicketTypes returns the hidden ticket types of an event a code unlocks.
+func unlockedTicketTypes(ctx context.Context, q *db.Queries, code db.AccessCode) ([]pgtype.UUID, error) {
+	if len(code.TicketTypeIds) > 0 {
+		return code.TicketTypeIds, nil
+	}
+
+	ticketTypes, err := q.ListTicketType(ctx, code.EventID)
+	if err != nil {
+		return nil, err
+	}
+
+	var unlocked []pgtype.UUID
+	for _, ticketType := range ticketTypes {
+		if ticketType.Visibility == db.TicketVisibilityHidden {
+			unlocked = append(unlocked, ticketType.ID)
+		}
+	}
+
+	return unlocked, nil
+}
+
+// redeemAccessCode counts the hidden tickets of an order against its code. They are given back when the order's
+// reservation is released.
+func redeemAccessCode(ctx context.Context, q *db.Queries, code db.AccessCode, orderID pgtype.UUID, tickets int) error {
+	eb := errs.B()
+
+	// the tickets are only counted while the code has enough left, so concurrent orders cannot overrun the cap
+	used, err := q.UseAccessCode(ctx, db.UseAccessCodeParams{
+		Tickets:      int32(tickets),
+		AccessCodeID: code.ID,
+	})
+	if err != nil {
+		return eb.Cause(err).Code(errs.Internal).Msg("An error occurred while redeeming the access code").Err()
+	}
+	if used == 0 {
+		return eb.Code(errs.ResourceExhausted).Msgf("Access code %s has %d tickets left", code.Code, code.MaxTickets-code.UsedTickets).Err()
+	}
+
+	err = q.InsertAccessCodeRedemption(ctx, db.InsertAccessCodeRedemptionParams{
+		AccessCodeID: code.ID,
+		OrderID:      orderID,
+		Tickets:      int32(tickets),
+	})
+	if err != nil {
+		return eb.Cause(err).Code(errs.Internal).Msg("An error occurred while redeeming the access code").Err()
+	}
+
+	return nil
+}
+
+// CreateAccessCode Create an access code that unlocks hidden ticket types of an event
+//
+//encore:api auth method=POST path=/v1/events/:id/access-codes
+func CreateAccessCode(ctx context.Context, id uuid.UUID, req *CreateAccessCodeRequest) (*BaseResponse[AccessCode], error) {
+	eb := errs.B()
+
+	eventID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	ticketTypeIDs, err := eventTicketTypes(ctx, query, eventID, req.TicketTypeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := query.InsertAccessCode(ctx, db.InsertAccessCodeParams{
+		EventID:       eventID,
+		Code:          req.Code,
+		TicketTypeIds: ticketTypeIDs,
+		MaxTickets:    req.MaxTickets,
+		StartsAt:      timestamptz(req.StartsAt),
+		EndsAt:        timestamptz(req.EndsAt),
+	})
+	if isUniqueViolation(err) {
+		return nil, eb.Code(errs.AlreadyExists).Msgf("The event already has an access code %q", req.Code).Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while creating access code", "CreateAccessCode:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while creating access code").Err()
+	}
+
+	return &BaseResponse[AccessCode]{
+		Data:    toAccessCode(code),
+		Message: "Access code created successfully",
+	}, nil
+}
+
+// ListAccessCodes List the access codes of an event with how many tickets were bought with them
+//
+//encore:api auth method=GET path=/v1/events/:id/access-codes
+func ListAccessCodes(ctx context.Context, id uuid.UUID, params *ListQuery) (*BaseResponse[[]AccessCode], error) {
+	eb := errs.B()
+
+	extractedParam := extractQuery(params)
+	data, err := query.ListAccessCode(ctx, db.ListAccessCodeParams{
+		EventID: pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		},
+		Offsets: extractedParam.Page,
+		Limits:  extractedParam.Limit,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while retrieving access codes", "ListAccessCodes:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving access codes").Err()
+	}
+
+	accessCodes := make([]AccessCode, 0, len(data))
+	for _, code := range data {
+		accessCodes = append(accessCodes, toAccessCode(code))
+	}
+
+	return &BaseResponse[[]AccessCode]{
+		Data:    accessCodes,
+		Message: "Access codes retrieved successfully",
+	}, nil
+}
+
+// GetAccessCode Get an access code
+//
+//encore:api auth method=GET path=/v1/access-codes/:id
+func GetAccessCode(ctx context.Context, id uuid.UUID) (*BaseResponse[AccessCode], error) {
+	eb := errs.B()
+
+	code, err := query.GetAccessCode(ctx, pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Access code not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving access code", "GetAccessCode:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving access code").Err()
+	}
+
+	return &BaseResponse[AccessCode]{
+		Data:    toAccessCode(code),
+		Message: "Access code retrieved successfully",
+	}, nil
+}
+
+// UpdateAccessCode Update an access code
+//
+// Tickets already bought with the code stay counted, lowering max tickets below them only stops further use.
+//
+//encore:api auth method=PUT path=/v1/access-codes/:id
+func UpdateAccessCode(ctx context.Context, id uuid.UUID, req *UpdateAccessCodeRequest) (*BaseResponse[AccessCode], error) {
+	eb := errs.B()
+
+	accessCodeID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	current, err := query.GetAccessCode(ctx, accessCodeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Access code not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving access code", "UpdateAccessCode:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving access code").Err()
+	}
+
+	startsAt, endsAt := timestampPtr(current.StartsAt), timestampPtr(current.EndsAt)
+	if req.StartsAt != nil {
+		startsAt = req.StartsAt
+	}
+	if req.EndsAt != nil {
+		endsAt = req.EndsAt
+	}
+	if err := validateCodeWindow(startsAt, endsAt); err != nil {
+		return nil, err
+	}
+
+	params := db.UpdateAccessCodeParams{
+		AccessCodeID: accessCodeID,
+		StartsAt:     timestamptz(req.StartsAt),
+		EndsAt:       timestamptz(req.EndsAt),
+	}
+	if req.Code != nil {
+		params.Code = pgtype.Text{String: *req.Code, Valid: true}
+	}
+	if req.MaxTickets != nil {
+		params.MaxTickets = pgtype.Int4{Int32: *req.MaxTickets, Valid: true}
+	}
+	params.TicketTypeIds, err = eventTicketTypes(ctx, query, current.EventID, req.TicketTypeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := query.UpdateAccessCode(ctx, params)
+	if isUniqueViolation(err) {
+		return nil, eb.Code(errs.AlreadyExists).Msgf("The event already has an access code %q", *req.Code).Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while updating access code", "UpdateAccessCode:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating access code").Err()
+	}
+
+	return &BaseResponse[AccessCode]{
+		Data:    toAccessCode(code),
+		Message: "Access code updated successfully",
+	}, nil
+}
+
+// DeleteAccessCode Delete an access code, tickets bought with it stay sold
+//
+//encore:api auth method=DELETE path=/v1/access-codes/:id
+func DeleteAccessCode(ctx context.Context, id uuid.UUID) (*BaseResponse[DeletesResponse], error) {
+	eb := errs.B()
+
+	deleted, err := query.DeleteAccessCode(ctx, pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while deleting access code", "DeleteAccessCode:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while deleting access code").Err()
+	}
+	if deleted == 0 {
+		return nil, eb.Code(errs.NotFound).Msg("Access code not found").Err()
+	}
+
+	return &BaseResponse[DeletesResponse]{
+		Data: DeletesResponse{
+			Deleted: int(deleted),
+		},
+		Message: "Access code deleted successfully",
+	}, nil
+}
@@ -1,17 +1,24 @@
 package events
 
-import "github.com/jackc/pgx/v5/pgtype"
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/money"
+)
 
 type Event struct {
-	ID             pgtype.UUID         `json:"id"`
-	Name           string              `json:"name"`
-	Description    string              `json:"description"`
-	Location       string              `json:"location"`
-	EventStartDate pgtype.Timestamptz  `json:"event_start_date"`
-	EventEndDate   pgtype.Timestamptz  `json:"event_end_date"`
-	CreatedAt      pgtype.Timestamptz  `json:"created_at"`
-	UpdatedAt      pgtype.Timestamptz  `json:"updated_at"`
-	TicketInputs   []*EventTicketInput `json:"inputs"`
+	ID                     pgtype.UUID         `json:"id"`
+	Name                   string              `json:"name"`
+	Description            string              `json:"description"`
+	Location               string              `json:"location"`
+	EventStartDate         pgtype.Timestamptz  `json:"event_start_date"`
+	EventEndDate           pgtype.Timestamptz  `json:"event_end_date"`
+	CreatedAt              pgtype.Timestamptz  `json:"created_at"`
+	UpdatedAt              pgtype.Timestamptz  `json:"updated_at"`
+	PaymentProvider        string              `json:"payment_provider"`
+	Fee                    FeeRule             `json:"fee"`
+	Tax                    TaxRule             `json:"tax"`
+	MaxFreeTicketsPerEmail int32               `json:"max_free_tickets_per_email"`
+	TicketInputs           []*EventTicketInput `json:"inputs"`
 }
 
 type EventTicketInput struct {
@@ -30,7 +37,8 @@ type Ticket struct {
 	ID           string             `json:"id"`
 	Name         string             `json:"name"`
 	Description  string             `json:"description"`
-	Price        string             `json:"price"`
+	Price        int64              `json:"price"`
+	Currency     string             `json:"currency"`
 	Benefits     []byte             `json:"benefits"`
 	Status       string             `json:"status"`
 	TicketInputs []byte             `json:"ticket_inputs"`
@@ -48,13 +56,14 @@ type Attendee struct {
 }
 
 type Payment struct {
-	ID         string             `json:"id"`
-	EventID    string             `json:"event_id"`
-	TicketID   string             `json:"ticket_id"`
-	Data       []byte             `json:"data"`
-	Name       string             `json:"name"`
-	Email      string             `json:"email"`
-	BillLinkID int                `json:"bill_link_id"`
-	CreatedAt  pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	ID            pgtype.UUID        `json:"id"`
+	EventID       pgtype.UUID        `json:"event_id"`
+	OrderID       pgtype.UUID        `json:"order_id"`
+	Name          string             `json:"name"`
+	Email         string             `json:"email"`
+	BillLinkID    int32              `json:"bill_link_id"`
+	TransactionID string             `json:"transaction_id"`
+	Amount        money.Money        `json:"amount"`
+	Status        string             `json:"status"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
 }
@@ -0,0 +1,4 @@
+ALTER TABLE event
+    ADD COLUMN payment_provider VARCHAR(32) NOT NULL DEFAULT 'flip';
+ALTER TABLE reservation
+    ADD COLUMN payment_provider VARCHAR(32) NOT NULL DEFAULT 'flip';
@@ -0,0 +1,39 @@
+CREATE TYPE order_status AS ENUM ('created', 'awaiting_payment', 'paid', 'expired', 'cancelled', 'refunded');
+CREATE TABLE orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES event (id) ON DELETE CASCADE,
+    buyer_name VARCHAR(128) NOT NULL DEFAULT '',
+    buyer_email VARCHAR(128) NOT NULL DEFAULT '',
+    amount INT NOT NULL,
+    payment_provider VARCHAR(32) NOT NULL,
+    provider_reference VARCHAR(64),
+    status order_status NOT NULL DEFAULT 'created',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX orders_event_id_index ON orders (event_id);
+
+CREATE TABLE order_item (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    ticket_name VARCHAR(128) NOT NULL,
+    quantity INT NOT NULL,
+    unit_price INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX order_item_order_id_index ON order_item (order_id);
+
+CREATE TABLE order_transition (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    from_status order_status,
+    to_status order_status NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX order_transition_order_id_index ON order_transition (order_id);
+
+ALTER TABLE reservation
+    ADD COLUMN order_id UUID REFERENCES orders (id) ON DELETE SET NULL;
+ALTER TABLE payment
+    ADD COLUMN order_id UUID REFERENCES orders (id) ON DELETE SET NULL;
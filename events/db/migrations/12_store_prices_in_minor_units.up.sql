@@ -0,0 +1,19 @@
+-- Prices were free-form text holding whole rupiah, sometimes with separators such as "299.000".
+-- Keep the digits only; an empty result fails the cast instead of silently making a ticket free.
+ALTER TABLE ticket
+    ALTER COLUMN price TYPE BIGINT USING NULLIF(regexp_replace(price, '[^0-9]', '', 'g'), '')::BIGINT;
+ALTER TABLE ticket
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE reservation
+    ALTER COLUMN amount TYPE BIGINT;
+ALTER TABLE reservation
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE orders
+    ALTER COLUMN amount TYPE BIGINT;
+ALTER TABLE orders
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE order_item
+    ALTER COLUMN unit_price TYPE BIGINT;
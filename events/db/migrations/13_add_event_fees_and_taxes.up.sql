@@ -0,0 +1,30 @@
+CREATE TYPE fee_type AS ENUM ('flat', 'percentage');
+CREATE TYPE fee_per AS ENUM ('ticket', 'order');
+
+-- fee_value is in minor units for flat fees and in basis points for percentage fees.
+-- The defaults keep the previous behaviour: 1000 per ticket, paid by the buyer, no tax.
+ALTER TABLE event
+    ADD COLUMN fee_type fee_type NOT NULL DEFAULT 'flat',
+    ADD COLUMN fee_value BIGINT NOT NULL DEFAULT 1000,
+    ADD COLUMN fee_per fee_per NOT NULL DEFAULT 'ticket',
+    ADD COLUMN fee_absorbed BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN tax_name VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN tax_rate INT NOT NULL DEFAULT 0;
+
+ALTER TABLE orders
+    ADD COLUMN subtotal BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN fee BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN fee_absorbed BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN tax BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax_name VARCHAR(32) NOT NULL DEFAULT '';
+
+-- Orders placed so far were charged the hard-coded per ticket fee on top of their items.
+UPDATE orders o
+SET subtotal = items.subtotal,
+    fee = o.amount - items.subtotal
+FROM (
+    SELECT order_id, SUM(quantity * unit_price) AS subtotal
+    FROM order_item
+    GROUP BY order_id
+) items
+WHERE items.order_id = o.id;
@@ -0,0 +1,21 @@
+ALTER TYPE ticket_status ADD VALUE 'refunded';
+ALTER TYPE attendee_status ADD VALUE 'refunded';
+
+-- status holds the provider's refund status, or MANUAL when the provider cannot refund through its API
+-- and the money is returned by hand.
+CREATE TABLE refund (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id UUID NOT NULL REFERENCES payment (id) ON DELETE CASCADE,
+    order_id UUID REFERENCES orders (id) ON DELETE SET NULL,
+    event_id UUID REFERENCES event (id) ON DELETE SET NULL,
+    ticket_ids UUID[] NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(32) NOT NULL,
+    provider_reference VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX refund_payment_id_index ON refund (payment_id);
+CREATE INDEX refund_order_id_index ON refund (order_id);
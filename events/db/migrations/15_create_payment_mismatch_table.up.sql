@@ -0,0 +1,13 @@
+-- reconciled_at is set once a reservation that is no longer pending has been checked against its provider.
+ALTER TABLE reservation
+    ADD COLUMN reconciled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE payment_mismatch (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    reservation_id UUID REFERENCES reservation (id) ON DELETE CASCADE,
+    bill_link_id INT NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    detail VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (bill_link_id, kind)
+);
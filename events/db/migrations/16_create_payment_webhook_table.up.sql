@@ -0,0 +1,18 @@
+CREATE TYPE webhook_status AS ENUM ('received', 'processed', 'duplicate', 'rejected', 'failed');
+CREATE TABLE payment_webhook (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider VARCHAR(32) NOT NULL,
+    content_type VARCHAR(128) NOT NULL DEFAULT '',
+    payload TEXT NOT NULL,
+    signature_valid BOOLEAN,
+    transaction_id VARCHAR(64),
+    bill_link_id INT,
+    status webhook_status NOT NULL DEFAULT 'received',
+    error TEXT NOT NULL DEFAULT '',
+    attempts INT NOT NULL DEFAULT 0,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX payment_webhook_transaction_index ON payment_webhook (provider, transaction_id);
+CREATE INDEX payment_webhook_status_index ON payment_webhook (status, created_at);
@@ -0,0 +1,4 @@
+-- Free tickets are confirmed without a payment, so each email may only claim this many per event.
+ALTER TABLE event
+    ADD COLUMN max_free_tickets_per_email INT NOT NULL DEFAULT 4;
+CREATE INDEX orders_event_id_buyer_email_index ON orders (event_id, lower(buyer_email));
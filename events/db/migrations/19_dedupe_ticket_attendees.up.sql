@@ -0,0 +1,49 @@
+-- Purchases used to link every attendee of an order to every ticket of it. The tickets of such a purchase all carry
+-- the same set of attendee rows, so the k-th ticket of the purchase keeps the k-th attendee and the rest are removed.
+-- A ticket keeps its attended status if any of its rows was scanned. Refunded rows are left alone.
+WITH ticket_attendees AS (
+    SELECT
+        ticket_id,
+        COUNT(*) AS attendee_count,
+        jsonb_agg(data ORDER BY data::TEXT) AS purchase,
+        bool_or(status = 'attended') AS attended
+    FROM attendee
+    WHERE ticket_id IS NOT NULL AND status != 'refunded'
+    GROUP BY ticket_id
+    HAVING COUNT(*) > 1
+), ranked_tickets AS (
+    SELECT
+        ta.ticket_id,
+        ta.attendee_count,
+        ta.attended,
+        ROW_NUMBER() OVER (PARTITION BY t.event_id, ta.purchase ORDER BY t.created_at, t.id) AS ticket_rank
+    FROM ticket_attendees ta
+    JOIN ticket t ON t.id = ta.ticket_id
+), ranked_attendees AS (
+    SELECT
+        a.id,
+        a.ticket_id,
+        ROW_NUMBER() OVER (PARTITION BY a.ticket_id ORDER BY a.data::TEXT, a.created_at, a.id) AS attendee_rank
+    FROM attendee a
+    JOIN ranked_tickets rt ON rt.ticket_id = a.ticket_id
+    WHERE a.status != 'refunded'
+), attendee_keep AS (
+    SELECT
+        ra.id,
+        ra.ticket_id,
+        rt.attended
+    FROM ranked_attendees ra
+    JOIN ranked_tickets rt ON rt.ticket_id = ra.ticket_id
+    WHERE ra.attendee_rank = (rt.ticket_rank - 1) % rt.attendee_count + 1
+), promoted AS (
+    UPDATE attendee a
+    SET
+        status = 'attended',
+        updated_at = now()
+    FROM attendee_keep k
+    WHERE a.id = k.id AND k.attended
+    RETURNING a.id
+)
+DELETE FROM attendee a
+USING attendee_keep k
+WHERE a.ticket_id = k.ticket_id AND a.id != k.id AND a.status != 'refunded';
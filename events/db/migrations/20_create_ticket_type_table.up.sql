@@ -0,0 +1,45 @@
+-- Ticket types used to exist only as ticket rows sharing a name. The type now holds what those rows have in common,
+-- while each ticket keeps its own copy of the price it was put on sale with.
+CREATE TABLE ticket_type (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES event (id) ON DELETE CASCADE,
+    name VARCHAR(128) NOT NULL,
+    description VARCHAR(2048) NOT NULL,
+    price BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    benefits JSONB NOT NULL DEFAULT '[]',
+    min INT NOT NULL DEFAULT 0,
+    max INT NOT NULL DEFAULT 0,
+    quantity INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (event_id, name)
+);
+
+-- The newest ticket of each name describes the type best, as it was created or updated last
+INSERT INTO ticket_type (event_id, name, description, price, currency, benefits, min, max, quantity, created_at)
+SELECT DISTINCT ON (t.event_id, t.name)
+    t.event_id,
+    t.name,
+    t.description,
+    t.price,
+    t.currency,
+    t.benefits,
+    COALESCE(t.min, 0),
+    COALESCE(t.max, 0),
+    COUNT(*) OVER (PARTITION BY t.event_id, t.name),
+    MIN(t.created_at) OVER (PARTITION BY t.event_id, t.name)
+FROM ticket t
+ORDER BY t.event_id, t.name, t.created_at DESC;
+
+ALTER TABLE ticket
+    ADD COLUMN ticket_type_id UUID REFERENCES ticket_type (id) ON DELETE CASCADE;
+
+UPDATE ticket t
+SET ticket_type_id = tt.id
+FROM ticket_type tt
+WHERE tt.event_id = t.event_id AND tt.name = t.name;
+
+ALTER TABLE ticket
+    ALTER COLUMN ticket_type_id SET NOT NULL;
+CREATE INDEX ticket_ticket_type_id_status_index ON ticket (ticket_type_id, status);
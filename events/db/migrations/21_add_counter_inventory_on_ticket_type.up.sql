@@ -0,0 +1,9 @@
+-- Counter types do not keep a row per unsold ticket: quantity is the capacity, and ticket rows with their hashes
+-- are only created when a purchase reserves them.
+CREATE TYPE inventory_mode AS ENUM ('rows', 'counter');
+
+ALTER TABLE ticket_type
+    ADD COLUMN inventory_mode inventory_mode NOT NULL DEFAULT 'rows',
+    ADD COLUMN reserved_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN sold_count INT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT ticket_type_capacity_check CHECK (reserved_count >= 0 AND sold_count >= 0 AND reserved_count + sold_count <= quantity);
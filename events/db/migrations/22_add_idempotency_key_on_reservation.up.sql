@@ -0,0 +1,7 @@
+ALTER TABLE reservation
+    ADD COLUMN idempotency_key TEXT,
+    ADD COLUMN request_hash TEXT,
+    ADD COLUMN response JSONB;
+
+CREATE UNIQUE INDEX reservation_event_id_idempotency_key_idx ON reservation (event_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
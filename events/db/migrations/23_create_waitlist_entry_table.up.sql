@@ -0,0 +1,22 @@
+CREATE TYPE waitlist_state AS ENUM ('waiting', 'offered', 'claimed', 'expired');
+CREATE TABLE waitlist_entry (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES event (id) ON DELETE CASCADE,
+    ticket_type_id UUID NOT NULL REFERENCES ticket_type (id) ON DELETE CASCADE,
+    name VARCHAR(128) NOT NULL,
+    email VARCHAR(320) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    state waitlist_state NOT NULL DEFAULT 'waiting',
+    -- set once tickets are held for the entry; the token in the claim link is the only way to buy them
+    claim_token TEXT UNIQUE,
+    ticket_ids UUID[] NOT NULL DEFAULT '{}',
+    offer_expires_at TIMESTAMP WITH TIME ZONE,
+    notified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX waitlist_entry_ticket_type_id_state_created_at_index ON waitlist_entry (ticket_type_id, state, created_at);
+CREATE INDEX waitlist_entry_state_offer_expires_at_index ON waitlist_entry (state, offer_expires_at);
+-- one open entry per email and type, so a buyer cannot take several places in the line
+CREATE UNIQUE INDEX waitlist_entry_ticket_type_id_email_index ON waitlist_entry (ticket_type_id, lower(email))
+    WHERE state IN ('waiting', 'offered');
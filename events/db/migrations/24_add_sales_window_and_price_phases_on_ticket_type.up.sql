@@ -0,0 +1,18 @@
+ALTER TABLE ticket_type
+    ADD COLUMN sales_start TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN sales_end TIMESTAMP WITH TIME ZONE,
+    ADD CONSTRAINT ticket_type_sales_window_check CHECK (sales_start IS NULL OR sales_end IS NULL OR sales_start < sales_end);
+
+-- Phases are tried in position order, the first one that has not ended sets the price of the type
+CREATE TABLE ticket_price_phase (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    ticket_type_id UUID NOT NULL REFERENCES ticket_type (id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    price BIGINT NOT NULL CHECK (price >= 0),
+    ends_at TIMESTAMP WITH TIME ZONE,
+    sold_limit INT NOT NULL DEFAULT 0 CHECK (sold_limit >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (ticket_type_id, position)
+);
@@ -0,0 +1,37 @@
+CREATE TYPE discount_type AS ENUM ('flat', 'percentage');
+CREATE TABLE promo_code (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES event (id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL,
+    discount_type discount_type NOT NULL,
+    -- minor units of currency for flat discounts, basis points of the ticket price for percentage discounts
+    value BIGINT NOT NULL CHECK (value >= 0),
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    -- empty means every ticket type of the event
+    ticket_type_ids UUID[] NOT NULL DEFAULT '{}',
+    -- 0 means unlimited
+    max_uses INT NOT NULL DEFAULT 0 CHECK (max_uses >= 0),
+    used_count INT NOT NULL DEFAULT 0 CHECK (used_count >= 0),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+-- codes are matched case insensitively
+CREATE UNIQUE INDEX promo_code_event_id_code_index ON promo_code (event_id, lower(code));
+
+ALTER TABLE orders
+    ADD COLUMN discount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN promo_code VARCHAR(64);
+
+-- A redemption holds one use of a code until its order is paid or released; released uses no longer count
+CREATE TABLE promo_code_redemption (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    promo_code_id UUID REFERENCES promo_code (id) ON DELETE SET NULL,
+    order_id UUID UNIQUE NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    discount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    released_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
@@ -0,0 +1,33 @@
+CREATE TYPE ticket_visibility AS ENUM ('public', 'hidden');
+-- hidden types, such as comp, press and presale tickets, are only listed and sold with an access code
+ALTER TABLE ticket_type
+    ADD COLUMN visibility ticket_visibility NOT NULL DEFAULT 'public';
+
+CREATE TABLE access_code (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES event (id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL,
+    -- the hidden types the code unlocks, empty means every hidden type of the event
+    ticket_type_ids UUID[] NOT NULL DEFAULT '{}',
+    -- tickets that can be bought with the code, 0 means unlimited
+    max_tickets INT NOT NULL DEFAULT 0 CHECK (max_tickets >= 0),
+    used_tickets INT NOT NULL DEFAULT 0 CHECK (used_tickets >= 0),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+-- codes are matched case insensitively
+CREATE UNIQUE INDEX access_code_event_id_code_index ON access_code (event_id, lower(code));
+
+-- A redemption holds the tickets an order bought with a code until the order is paid or released; released
+-- tickets no longer count
+CREATE TABLE access_code_redemption (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    access_code_id UUID REFERENCES access_code (id) ON DELETE SET NULL,
+    order_id UUID UNIQUE NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    tickets INT NOT NULL,
+    released_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
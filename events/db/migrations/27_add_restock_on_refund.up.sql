@@ -0,0 +1,25 @@
+-- A refund is recorded as PENDING before the provider is asked to pay it out and completed afterwards, so a
+-- payout is never left unrecorded. FAILED refunds were turned down by the provider and no longer count.
+ALTER TABLE refund
+    ADD COLUMN restock BOOLEAN NOT NULL DEFAULT false;
+
+CREATE OR REPLACE VIEW payment_summary AS
+SELECT
+    p.id,
+    p.event_id,
+    p.order_id,
+    p.name,
+    p.email,
+    p.bill_link_id,
+    p.data,
+    COALESCE(o.amount, (p.data ->> 'amount')::BIGINT, 0)::BIGINT AS amount,
+    COALESCE(o.currency, 'IDR')::CHAR(3) AS currency,
+    (CASE
+        WHEN o.status = 'refunded' THEN 'refunded'
+        WHEN EXISTS (SELECT 1 FROM refund r WHERE r.payment_id = p.id AND r.status != 'FAILED') THEN 'partially_refunded'
+        ELSE 'paid'
+    END)::VARCHAR(32) AS status,
+    p.created_at,
+    p.updated_at
+FROM payment p
+LEFT JOIN orders o ON o.id = p.order_id;
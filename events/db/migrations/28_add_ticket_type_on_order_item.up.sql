@@ -0,0 +1,3 @@
+-- Refunds price each ticket at the unit price of its own line, older items are matched by ticket name instead
+ALTER TABLE order_item
+    ADD COLUMN ticket_type_id UUID REFERENCES ticket_type (id) ON DELETE SET NULL;
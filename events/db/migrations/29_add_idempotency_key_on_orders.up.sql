@@ -0,0 +1,8 @@
+-- Free orders have no reservation, they keep the Idempotency-Key of their purchase themselves
+ALTER TABLE orders
+    ADD COLUMN idempotency_key TEXT,
+    ADD COLUMN request_hash TEXT,
+    ADD COLUMN response JSONB;
+
+CREATE UNIQUE INDEX orders_event_id_idempotency_key_idx ON orders (event_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
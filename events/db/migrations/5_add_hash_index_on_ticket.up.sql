@@ -0,0 +1 @@
+CREATE INDEX ticket_hash_index ON ticket (hash);
@@ -0,0 +1,14 @@
+CREATE TYPE reservation_state AS ENUM ('pending', 'paid', 'expired', 'cancelled');
+CREATE TABLE reservation (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bill_link_id INT UNIQUE NOT NULL,
+    event_id UUID NOT NULL REFERENCES event (id) ON DELETE CASCADE,
+    ticket_ids UUID[] NOT NULL,
+    ticket_hashes TEXT[] NOT NULL,
+    attendees JSONB NOT NULL,
+    state reservation_state NOT NULL DEFAULT 'pending',
+    expired_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX reservation_state_expired_at_index ON reservation (state, expired_at);
@@ -0,0 +1,2 @@
+ALTER TABLE reservation
+    ADD COLUMN release_reason VARCHAR(255);
@@ -0,0 +1 @@
+CREATE INDEX ticket_event_name_status_index ON ticket (event_id, name, status);
@@ -0,0 +1,3 @@
+-- 0 marks reservations made before the amount was stored, their callbacks are not checked against it
+ALTER TABLE reservation
+    ADD COLUMN amount INT NOT NULL DEFAULT 0;
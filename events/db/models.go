@@ -16,6 +16,7 @@ type AttendeeStatus string
 const (
 	AttendeeStatusWaiting  AttendeeStatus = "waiting"
 	AttendeeStatusAttended AttendeeStatus = "attended"
+	AttendeeStatusRefunded AttendeeStatus = "refunded"
 )
 
 func (e *AttendeeStatus) Scan(src interface{}) error {
@@ -53,12 +54,271 @@ func (ns NullAttendeeStatus) Value() (driver.Value, error) {
 	return string(ns.AttendeeStatus), nil
 }
 
+type DiscountType string
+
+const (
+	DiscountTypeFlat       DiscountType = "flat"
+	DiscountTypePercentage DiscountType = "percentage"
+)
+
+func (e *DiscountType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = DiscountType(s)
+	case string:
+		*e = DiscountType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for DiscountType: %T", src)
+	}
+	return nil
+}
+
+type NullDiscountType struct {
+	DiscountType DiscountType
+	Valid        bool // Valid is true if DiscountType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullDiscountType) Scan(value interface{}) error {
+	if value == nil {
+		ns.DiscountType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.DiscountType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullDiscountType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.DiscountType), nil
+}
+
+type FeePer string
+
+const (
+	FeePerTicket FeePer = "ticket"
+	FeePerOrder  FeePer = "order"
+)
+
+func (e *FeePer) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = FeePer(s)
+	case string:
+		*e = FeePer(s)
+	default:
+		return fmt.Errorf("unsupported scan type for FeePer: %T", src)
+	}
+	return nil
+}
+
+type NullFeePer struct {
+	FeePer FeePer
+	Valid  bool // Valid is true if FeePer is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullFeePer) Scan(value interface{}) error {
+	if value == nil {
+		ns.FeePer, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.FeePer.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullFeePer) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.FeePer), nil
+}
+
+type FeeType string
+
+const (
+	FeeTypeFlat       FeeType = "flat"
+	FeeTypePercentage FeeType = "percentage"
+)
+
+func (e *FeeType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = FeeType(s)
+	case string:
+		*e = FeeType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for FeeType: %T", src)
+	}
+	return nil
+}
+
+type NullFeeType struct {
+	FeeType FeeType
+	Valid   bool // Valid is true if FeeType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullFeeType) Scan(value interface{}) error {
+	if value == nil {
+		ns.FeeType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.FeeType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullFeeType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.FeeType), nil
+}
+
+type InventoryMode string
+
+const (
+	InventoryModeRows    InventoryMode = "rows"
+	InventoryModeCounter InventoryMode = "counter"
+)
+
+func (e *InventoryMode) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = InventoryMode(s)
+	case string:
+		*e = InventoryMode(s)
+	default:
+		return fmt.Errorf("unsupported scan type for InventoryMode: %T", src)
+	}
+	return nil
+}
+
+type NullInventoryMode struct {
+	InventoryMode InventoryMode
+	Valid         bool // Valid is true if InventoryMode is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullInventoryMode) Scan(value interface{}) error {
+	if value == nil {
+		ns.InventoryMode, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.InventoryMode.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullInventoryMode) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.InventoryMode), nil
+}
+
+type OrderStatus string
+
+const (
+	OrderStatusCreated         OrderStatus = "created"
+	OrderStatusAwaitingPayment OrderStatus = "awaiting_payment"
+	OrderStatusPaid            OrderStatus = "paid"
+	OrderStatusExpired         OrderStatus = "expired"
+	OrderStatusCancelled       OrderStatus = "cancelled"
+	OrderStatusRefunded        OrderStatus = "refunded"
+)
+
+func (e *OrderStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = OrderStatus(s)
+	case string:
+		*e = OrderStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for OrderStatus: %T", src)
+	}
+	return nil
+}
+
+type NullOrderStatus struct {
+	OrderStatus OrderStatus
+	Valid       bool // Valid is true if OrderStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullOrderStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.OrderStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.OrderStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullOrderStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.OrderStatus), nil
+}
+
+type ReservationState string
+
+const (
+	ReservationStatePending   ReservationState = "pending"
+	ReservationStatePaid      ReservationState = "paid"
+	ReservationStateExpired   ReservationState = "expired"
+	ReservationStateCancelled ReservationState = "cancelled"
+)
+
+func (e *ReservationState) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReservationState(s)
+	case string:
+		*e = ReservationState(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReservationState: %T", src)
+	}
+	return nil
+}
+
+type NullReservationState struct {
+	ReservationState ReservationState
+	Valid            bool // Valid is true if ReservationState is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullReservationState) Scan(value interface{}) error {
+	if value == nil {
+		ns.ReservationState, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ReservationState.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullReservationState) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ReservationState), nil
+}
+
 type TicketStatus string
 
 const (
 	TicketStatusAvailable TicketStatus = "available"
 	TicketStatusPending   TicketStatus = "pending"
 	TicketStatusSold      TicketStatus = "sold"
+	TicketStatusRefunded  TicketStatus = "refunded"
 )
 
 func (e *TicketStatus) Scan(src interface{}) error {
@@ -96,6 +356,159 @@ func (ns NullTicketStatus) Value() (driver.Value, error) {
 	return string(ns.TicketStatus), nil
 }
 
+type TicketVisibility string
+
+const (
+	TicketVisibilityPublic TicketVisibility = "public"
+	TicketVisibilityHidden TicketVisibility = "hidden"
+)
+
+func (e *TicketVisibility) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TicketVisibility(s)
+	case string:
+		*e = TicketVisibility(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TicketVisibility: %T", src)
+	}
+	return nil
+}
+
+type NullTicketVisibility struct {
+	TicketVisibility TicketVisibility
+	Valid            bool // Valid is true if TicketVisibility is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTicketVisibility) Scan(value interface{}) error {
+	if value == nil {
+		ns.TicketVisibility, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TicketVisibility.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTicketVisibility) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TicketVisibility), nil
+}
+
+type WaitlistState string
+
+const (
+	WaitlistStateWaiting WaitlistState = "waiting"
+	WaitlistStateOffered WaitlistState = "offered"
+	WaitlistStateClaimed WaitlistState = "claimed"
+	WaitlistStateExpired WaitlistState = "expired"
+)
+
+func (e *WaitlistState) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WaitlistState(s)
+	case string:
+		*e = WaitlistState(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WaitlistState: %T", src)
+	}
+	return nil
+}
+
+type NullWaitlistState struct {
+	WaitlistState WaitlistState
+	Valid         bool // Valid is true if WaitlistState is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWaitlistState) Scan(value interface{}) error {
+	if value == nil {
+		ns.WaitlistState, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WaitlistState.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWaitlistState) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WaitlistState), nil
+}
+
+type WebhookStatus string
+
+const (
+	WebhookStatusReceived  WebhookStatus = "received"
+	WebhookStatusProcessed WebhookStatus = "processed"
+	WebhookStatusDuplicate WebhookStatus = "duplicate"
+	WebhookStatusRejected  WebhookStatus = "rejected"
+	WebhookStatusFailed    WebhookStatus = "failed"
+)
+
+func (e *WebhookStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookStatus(s)
+	case string:
+		*e = WebhookStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookStatus struct {
+	WebhookStatus WebhookStatus
+	Valid         bool // Valid is true if WebhookStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookStatus), nil
+}
+
+type AccessCode struct {
+	ID            pgtype.UUID
+	EventID       pgtype.UUID
+	Code          string
+	TicketTypeIds []pgtype.UUID
+	MaxTickets    int32
+	UsedTickets   int32
+	StartsAt      pgtype.Timestamptz
+	EndsAt        pgtype.Timestamptz
+	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+}
+
+type AccessCodeRedemption struct {
+	ID           pgtype.UUID
+	AccessCodeID pgtype.UUID
+	OrderID      pgtype.UUID
+	Tickets      int32
+	ReleasedAt   pgtype.Timestamptz
+	CreatedAt    pgtype.Timestamptz
+}
+
 type Attendee struct {
 	ID        pgtype.UUID
 	EventID   pgtype.UUID
@@ -107,14 +520,65 @@ type Attendee struct {
 }
 
 type Event struct {
-	ID             pgtype.UUID
-	Name           string
-	Description    string
-	Location       string
-	EventStartDate pgtype.Timestamptz
-	EventEndDate   pgtype.Timestamptz
-	CreatedAt      pgtype.Timestamptz
-	UpdatedAt      pgtype.Timestamptz
+	ID                     pgtype.UUID
+	Name                   string
+	Description            string
+	Location               string
+	EventStartDate         pgtype.Timestamptz
+	EventEndDate           pgtype.Timestamptz
+	CreatedAt              pgtype.Timestamptz
+	UpdatedAt              pgtype.Timestamptz
+	PaymentProvider        string
+	FeeType                FeeType
+	FeeValue               int64
+	FeePer                 FeePer
+	FeeAbsorbed            bool
+	TaxName                string
+	TaxRate                int32
+	MaxFreeTicketsPerEmail int32
+}
+
+type Order struct {
+	ID                pgtype.UUID
+	EventID           pgtype.UUID
+	BuyerName         string
+	BuyerEmail        string
+	Amount            int64
+	PaymentProvider   string
+	ProviderReference pgtype.Text
+	Status            OrderStatus
+	CreatedAt         pgtype.Timestamptz
+	UpdatedAt         pgtype.Timestamptz
+	Currency          string
+	Subtotal          int64
+	Fee               int64
+	FeeAbsorbed       bool
+	Tax               int64
+	TaxName           string
+	Discount          int64
+	PromoCode         pgtype.Text
+	IdempotencyKey    pgtype.Text
+	RequestHash       pgtype.Text
+	Response          []byte
+}
+
+type OrderItem struct {
+	ID           pgtype.UUID
+	OrderID      pgtype.UUID
+	TicketName   string
+	Quantity     int32
+	UnitPrice    int64
+	CreatedAt    pgtype.Timestamptz
+	TicketTypeID pgtype.UUID
+}
+
+type OrderTransition struct {
+	ID         pgtype.UUID
+	OrderID    pgtype.UUID
+	FromStatus NullOrderStatus
+	ToStatus   OrderStatus
+	Reason     string
+	CreatedAt  pgtype.Timestamptz
 }
 
 type Payment struct {
@@ -126,21 +590,128 @@ type Payment struct {
 	BillLinkID int32
 	CreatedAt  pgtype.Timestamptz
 	UpdatedAt  pgtype.Timestamptz
+	OrderID    pgtype.UUID
 }
 
-type Ticket struct {
+type PaymentMismatch struct {
+	ID            pgtype.UUID
+	ReservationID pgtype.UUID
+	BillLinkID    int32
+	Kind          string
+	Detail        string
+	CreatedAt     pgtype.Timestamptz
+}
+
+type PaymentSummary struct {
+	ID         pgtype.UUID
+	EventID    pgtype.UUID
+	OrderID    pgtype.UUID
+	Name       string
+	Email      string
+	BillLinkID int32
+	Data       []byte
+	Amount     int64
+	Currency   string
+	Status     string
+	CreatedAt  pgtype.Timestamptz
+	UpdatedAt  pgtype.Timestamptz
+}
+
+type PaymentWebhook struct {
+	ID             pgtype.UUID
+	Provider       string
+	ContentType    string
+	Payload        string
+	SignatureValid pgtype.Bool
+	TransactionID  pgtype.Text
+	BillLinkID     pgtype.Int4
+	Status         WebhookStatus
+	Error          string
+	Attempts       int32
+	ProcessedAt    pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+}
+
+type PromoCode struct {
+	ID            pgtype.UUID
+	EventID       pgtype.UUID
+	Code          string
+	DiscountType  DiscountType
+	Value         int64
+	Currency      string
+	TicketTypeIds []pgtype.UUID
+	MaxUses       int32
+	UsedCount     int32
+	StartsAt      pgtype.Timestamptz
+	EndsAt        pgtype.Timestamptz
+	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+}
+
+type PromoCodeRedemption struct {
 	ID          pgtype.UUID
-	EventID     pgtype.UUID
-	Name        string
-	Description string
-	Price       string
-	Benefits    []byte
-	Status      TicketStatus
+	PromoCodeID pgtype.UUID
+	OrderID     pgtype.UUID
+	Discount    int64
+	Currency    string
+	ReleasedAt  pgtype.Timestamptz
 	CreatedAt   pgtype.Timestamptz
-	UpdatedAt   pgtype.Timestamptz
-	Hash        pgtype.Text
-	Min         pgtype.Int4
-	Max         pgtype.Int4
+}
+
+type Refund struct {
+	ID                pgtype.UUID
+	PaymentID         pgtype.UUID
+	OrderID           pgtype.UUID
+	EventID           pgtype.UUID
+	TicketIds         []pgtype.UUID
+	Amount            int64
+	Currency          string
+	Reason            string
+	Status            string
+	ProviderReference pgtype.Text
+	CreatedAt         pgtype.Timestamptz
+	UpdatedAt         pgtype.Timestamptz
+	Restock           bool
+}
+
+type Reservation struct {
+	ID              pgtype.UUID
+	BillLinkID      int32
+	EventID         pgtype.UUID
+	TicketIds       []pgtype.UUID
+	TicketHashes    []string
+	Attendees       []byte
+	State           ReservationState
+	ExpiredAt       pgtype.Timestamptz
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+	ReleaseReason   pgtype.Text
+	Amount          int64
+	PaymentProvider string
+	OrderID         pgtype.UUID
+	Currency        string
+	ReconciledAt    pgtype.Timestamptz
+	IdempotencyKey  pgtype.Text
+	RequestHash     pgtype.Text
+	Response        []byte
+}
+
+type Ticket struct {
+	ID           pgtype.UUID
+	EventID      pgtype.UUID
+	Name         string
+	Description  string
+	Price        int64
+	Benefits     []byte
+	Status       TicketStatus
+	CreatedAt    pgtype.Timestamptz
+	UpdatedAt    pgtype.Timestamptz
+	Hash         pgtype.Text
+	Min          pgtype.Int4
+	Max          pgtype.Int4
+	Currency     string
+	TicketTypeID pgtype.UUID
 }
 
 type TicketInput struct {
@@ -150,3 +721,52 @@ type TicketInput struct {
 	CreatedAt pgtype.Timestamptz
 	UpdatedAt pgtype.Timestamptz
 }
+
+type TicketPricePhase struct {
+	ID           pgtype.UUID
+	TicketTypeID pgtype.UUID
+	Position     int32
+	Name         string
+	Price        int64
+	EndsAt       pgtype.Timestamptz
+	SoldLimit    int32
+	CreatedAt    pgtype.Timestamptz
+	UpdatedAt    pgtype.Timestamptz
+}
+
+type TicketType struct {
+	ID            pgtype.UUID
+	EventID       pgtype.UUID
+	Name          string
+	Description   string
+	Price         int64
+	Currency      string
+	Benefits      []byte
+	Min           int32
+	Max           int32
+	Quantity      int32
+	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+	InventoryMode InventoryMode
+	ReservedCount int32
+	SoldCount     int32
+	SalesStart    pgtype.Timestamptz
+	SalesEnd      pgtype.Timestamptz
+	Visibility    TicketVisibility
+}
+
+type WaitlistEntry struct {
+	ID             pgtype.UUID
+	EventID        pgtype.UUID
+	TicketTypeID   pgtype.UUID
+	Name           string
+	Email          string
+	Quantity       int32
+	State          WaitlistState
+	ClaimToken     pgtype.Text
+	TicketIds      []pgtype.UUID
+	OfferExpiresAt pgtype.Timestamptz
+	NotifiedAt     pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+}
@@ -4,9 +4,11 @@
 
 -- name: InsertEvent :one
 INSERT INTO event
-    (name, description, location, event_start_date, event_end_date)
+    (name, description, location, event_start_date, event_end_date, payment_provider,
+     fee_type, fee_value, fee_per, fee_absorbed, tax_name, tax_rate, max_free_tickets_per_email)
 VALUES
-    (@name, @description, @location, @event_start_date, @event_end_date)
+    (@name, @description, @location, @event_start_date, @event_end_date, @payment_provider,
+     @fee_type, @fee_value, @fee_per, @fee_absorbed, @tax_name, @tax_rate, @max_free_tickets_per_email)
 RETURNING id;
 
 -- name: UpdateEvent :exec
@@ -16,7 +18,15 @@ SET
     description = @description,
     location = @location,
     event_start_date = @event_start_date,
-    event_end_date = @event_end_date
+    event_end_date = @event_end_date,
+    payment_provider = COALESCE(sqlc.narg(payment_provider), payment_provider),
+    fee_type = COALESCE(sqlc.narg(fee_type)::TEXT, fee_type::TEXT)::fee_type,
+    fee_value = COALESCE(sqlc.narg(fee_value), fee_value),
+    fee_per = COALESCE(sqlc.narg(fee_per)::TEXT, fee_per::TEXT)::fee_per,
+    fee_absorbed = COALESCE(sqlc.narg(fee_absorbed), fee_absorbed),
+    tax_name = COALESCE(sqlc.narg(tax_name), tax_name),
+    tax_rate = COALESCE(sqlc.narg(tax_rate), tax_rate),
+    max_free_tickets_per_email = COALESCE(sqlc.narg(max_free_tickets_per_email), max_free_tickets_per_email)
 WHERE id = @event_id;
 
 -- name: DeleteEvent :exec
@@ -33,11 +43,34 @@ SELECT
     e.event_end_date,
     e.created_at,
     e.updated_at,
+    e.payment_provider,
+    e.fee_type,
+    e.fee_value,
+    e.fee_per,
+    e.fee_absorbed,
+    e.tax_name,
+    e.tax_rate,
+    e.max_free_tickets_per_email,
     eti.inputs as ticket_inputs
 FROM event e
 LEFT JOIN ticket_inputs eti ON e.id = eti.event_id
 WHERE e.id = $1;
 
+-- name: GetEventPaymentProvider :one
+SELECT payment_provider
+FROM event
+WHERE id = $1;
+
+-- name: GetEventPricing :one
+SELECT fee_type, fee_value, fee_per, fee_absorbed, tax_name, tax_rate
+FROM event
+WHERE id = $1;
+
+-- name: GetEventFreeTicketLimit :one
+SELECT max_free_tickets_per_email
+FROM event
+WHERE id = $1;
+
 -- name: ListEvent :many
 SELECT
     event.id,
@@ -48,6 +81,14 @@ SELECT
     event.event_end_date,
     event.created_at,
     event.updated_at,
+    event.payment_provider,
+    event.fee_type,
+    event.fee_value,
+    event.fee_per,
+    event.fee_absorbed,
+    event.tax_name,
+    event.tax_rate,
+    event.max_free_tickets_per_email,
     ticket_inputs.inputs as ticket_inputs
 FROM event
 LEFT JOIN ticket_inputs ticket_inputs ON event.id = ticket_inputs.event_id
@@ -72,35 +113,241 @@ VALUES
     (@event_id, @inputs)
 RETURNING id;
 
+-- name: GetEventTicketInputs :one
+SELECT inputs
+FROM ticket_inputs
+WHERE event_id = $1;
+
 -- ###############################################################
--- Ticket
+-- TicketType
 -- ###############################################################
 
--- name: InsertTicket :one
-INSERT INTO ticket
-    (event_id, name, description, price, benefits, hash, min, max)
+-- name: InsertTicketType :one
+INSERT INTO ticket_type
+    (event_id, name, description, price, currency, benefits, min, max, inventory_mode, sales_start, sales_end, visibility)
 VALUES
-    (@event_id, @name, @description, @price, @benefits, @hash, @min, @max)
+    (@event_id, @name, @description, @price, @currency, @benefits, @min, @max, @inventory_mode, @sales_start, @sales_end, @visibility)
 RETURNING id;
 
--- name: UpdateTicket :exec
-UPDATE ticket
+-- name: UpdateTicketType :exec
+UPDATE ticket_type
 SET
-    name = @name,
-    description = @description,
-    price = @price,
-    benefits = @benefits
-WHERE event_id = @event_id;
-
--- name: DeleteTicket :exec
-WITH rows_to_delete AS (
-    SELECT *
-    FROM ticket
-    WHERE ticket.event_id = @ev_id AND ticket.name = @ticket_name
-    LIMIT @limits
+    name = COALESCE(sqlc.narg(name), name),
+    description = COALESCE(sqlc.narg(description), description),
+    price = COALESCE(sqlc.narg(price), price),
+    currency = COALESCE(sqlc.narg(currency), currency),
+    benefits = COALESCE(sqlc.narg(benefits), benefits),
+    min = COALESCE(sqlc.narg(min), min),
+    max = COALESCE(sqlc.narg(max), max),
+    sales_start = CASE WHEN @clear_sales_start::BOOLEAN THEN NULL ELSE COALESCE(sqlc.narg(sales_start), sales_start) END,
+    sales_end = CASE WHEN @clear_sales_end::BOOLEAN THEN NULL ELSE COALESCE(sqlc.narg(sales_end), sales_end) END,
+    visibility = COALESCE(sqlc.narg(visibility), visibility),
+    updated_at = now()
+WHERE id = @ticket_type_id;
+
+-- name: SyncTicketTypeQuantity :exec
+UPDATE ticket_type
+SET
+    quantity = (SELECT COUNT(*) FROM ticket t WHERE t.ticket_type_id = ticket_type.id),
+    updated_at = now()
+WHERE ticket_type.id = @ticket_type_id AND inventory_mode = 'rows';
+
+-- name: SetTicketTypeCapacity :exec
+UPDATE ticket_type
+SET
+    quantity = @quantity,
+    updated_at = now()
+WHERE id = @ticket_type_id AND inventory_mode = 'counter';
+
+-- name: ReserveTicketTypeCapacity :execrows
+UPDATE ticket_type
+SET
+    reserved_count = reserved_count + @amount::INT,
+    updated_at = now()
+WHERE id = @ticket_type_id AND inventory_mode = 'counter' AND quantity - reserved_count - sold_count >= @amount::INT;
+
+-- name: SellIssuedTickets :exec
+UPDATE ticket_type tt
+SET
+    reserved_count = tt.reserved_count - issued.n,
+    sold_count = tt.sold_count + issued.n,
+    updated_at = now()
+FROM (
+    SELECT t.ticket_type_id, COUNT(*)::INT AS n
+    FROM ticket t
+    WHERE t.id = ANY(@ticket_ids::UUID[])
+    GROUP BY t.ticket_type_id
+) issued
+WHERE tt.id = issued.ticket_type_id AND tt.inventory_mode = 'counter';
+
+-- name: ReleaseIssuedTickets :exec
+WITH released AS (
+    DELETE FROM ticket t
+    USING ticket_type tt
+    WHERE t.id = ANY(@ticket_ids::UUID[]) AND tt.id = t.ticket_type_id AND tt.inventory_mode = 'counter'
+    RETURNING t.ticket_type_id
+)
+UPDATE ticket_type tt
+SET
+    reserved_count = tt.reserved_count - r.n,
+    updated_at = now()
+FROM (
+    SELECT ticket_type_id, COUNT(*)::INT AS n
+    FROM released
+    GROUP BY ticket_type_id
+) r
+WHERE tt.id = r.ticket_type_id;
+
+-- name: ReturnIssuedTicket :execrows
+WITH returned AS (
+    UPDATE ticket t
+    SET status = 'refunded'
+    FROM ticket_type tt
+    WHERE t.id = @ticket_id AND tt.id = t.ticket_type_id AND tt.inventory_mode = 'counter'
+    RETURNING t.ticket_type_id
 )
+UPDATE ticket_type tt
+SET
+    sold_count = tt.sold_count - 1,
+    updated_at = now()
+FROM returned
+WHERE tt.id = returned.ticket_type_id;
+
+-- name: DeleteTicketType :exec
+DELETE FROM ticket_type
+WHERE id = $1;
+
+-- name: GetTicketTypeForUpdate :one
+SELECT
+    *
+FROM ticket_type
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetTicketTypeByName :one
+SELECT
+    *
+FROM ticket_type
+WHERE event_id = @event_id AND name = @name;
+
+-- name: GetTicketTypeByNameForUpdate :one
+SELECT
+    *
+FROM ticket_type
+WHERE event_id = @event_id AND name = @name
+FOR UPDATE;
+
+-- name: GetTicketType :one
+SELECT
+    tt.*,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.quantity - tt.reserved_count - tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status = 'available')
+    END::BIGINT AS available,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status = 'sold')
+    END::BIGINT AS sold,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.reserved_count + tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status != 'available')
+    END::BIGINT AS claimed
+FROM ticket_type tt
+LEFT JOIN ticket t ON t.ticket_type_id = tt.id AND tt.inventory_mode = 'rows'
+WHERE tt.id = $1
+GROUP BY tt.id;
+
+-- name: ListTicketType :many
+SELECT
+    tt.*,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.quantity - tt.reserved_count - tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status = 'available')
+    END::BIGINT AS available,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status = 'sold')
+    END::BIGINT AS sold,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.reserved_count + tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status != 'available')
+    END::BIGINT AS claimed
+FROM ticket_type tt
+LEFT JOIN ticket t ON t.ticket_type_id = tt.id AND tt.inventory_mode = 'rows'
+WHERE tt.event_id = $1
+GROUP BY tt.id
+ORDER BY tt.created_at;
+
+-- name: InsertTicketPricePhase :exec
+INSERT INTO ticket_price_phase
+    (ticket_type_id, position, name, price, ends_at, sold_limit)
+VALUES
+    (@ticket_type_id, @position, @name, @price, @ends_at, @sold_limit);
+
+-- name: DeleteTicketPricePhases :exec
+DELETE FROM ticket_price_phase
+WHERE ticket_type_id = @ticket_type_id;
+
+-- name: ListTicketPricePhase :many
+SELECT
+    *
+FROM ticket_price_phase
+WHERE ticket_type_id = @ticket_type_id
+ORDER BY position;
+
+-- name: ListEventTicketPricePhase :many
+SELECT
+    p.*
+FROM ticket_price_phase p
+JOIN ticket_type tt ON tt.id = p.ticket_type_id
+WHERE tt.event_id = @event_id
+ORDER BY p.ticket_type_id, p.position;
+
+-- ###############################################################
+-- Ticket
+-- ###############################################################
+
+-- name: IssueReservedTickets :many
+INSERT INTO ticket
+    (event_id, ticket_type_id, name, description, price, currency, benefits, min, max, hash, status)
+SELECT
+    tt.event_id, tt.id, tt.name, tt.description, tt.price, tt.currency, tt.benefits, tt.min, tt.max, h.hash, 'pending'
+FROM ticket_type tt, unnest(@hashes::TEXT[]) AS h(hash)
+WHERE tt.id = @ticket_type_id
+RETURNING id, name, price, currency, hash;
+
+-- name: InsertTypeTickets :execrows
+INSERT INTO ticket
+    (event_id, ticket_type_id, name, description, price, currency, benefits, min, max, hash)
+SELECT
+    tt.event_id, tt.id, tt.name, tt.description, tt.price, tt.currency, tt.benefits, tt.min, tt.max, h.hash
+FROM ticket_type tt, unnest(@hashes::TEXT[]) AS h(hash)
+WHERE tt.id = @ticket_type_id;
+
+-- name: UpdateAvailableTicketsOfType :execrows
+UPDATE ticket t
+SET
+    name = tt.name,
+    description = tt.description,
+    price = tt.price,
+    currency = tt.currency,
+    benefits = tt.benefits,
+    min = tt.min,
+    max = tt.max,
+    updated_at = now()
+FROM ticket_type tt
+WHERE tt.id = @ticket_type_id AND t.ticket_type_id = tt.id AND t.status = 'available';
+
+-- name: DeleteAvailableTicketsOfType :execrows
 DELETE FROM ticket
-WHERE id IN (SELECT id FROM rows_to_delete);
+WHERE id IN (
+    SELECT t.id
+    FROM ticket t
+    WHERE t.ticket_type_id = @ticket_type_id AND t.status = 'available'
+    ORDER BY t.created_at DESC
+    LIMIT @limits
+    FOR UPDATE SKIP LOCKED
+);
 
 -- name: GetTicket :one
 SELECT
@@ -109,39 +356,101 @@ FROM ticket
 WHERE id = $1;
 
 -- name: ListDistinctTicket :many
-SELECT DISTINCT ON (name)
-    event_id,
-    name,
-    description,
-    price,
-    benefits,
-    status,
-    min,
-    max,
-    created_at,
-    updated_at,
-    COUNT(*) OVER (PARTITION BY name)
+SELECT
+    tt.id,
+    tt.event_id,
+    tt.name,
+    tt.description,
+    tt.price,
+    tt.currency,
+    tt.benefits,
+    tt.min,
+    tt.max,
+    tt.sales_start,
+    tt.sales_end,
+    tt.visibility,
+    tt.created_at,
+    tt.updated_at,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.quantity - tt.reserved_count - tt.sold_count
+        ELSE COUNT(t.id)
+    END::BIGINT AS count,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.sold_count
+        ELSE (SELECT COUNT(*) FROM ticket c WHERE c.ticket_type_id = tt.id AND c.status = 'sold')
+    END::BIGINT AS sold
+FROM ticket_type tt
+LEFT JOIN ticket t ON t.ticket_type_id = tt.id AND tt.inventory_mode = 'rows' AND t.status = 'available'
+WHERE tt.event_id = @event_id AND (tt.visibility = 'public' OR tt.id = ANY(@unlocked_ticket_type_ids::UUID[]))
+GROUP BY tt.id
+HAVING CASE tt.inventory_mode
+    WHEN 'counter' THEN tt.quantity - tt.reserved_count - tt.sold_count
+    ELSE COUNT(t.id)
+END > 0
+ORDER BY tt.name;
+
+-- name: ListTicketsByID :many
+SELECT
+    *
 FROM ticket
-WHERE event_id = $1 AND status = 'available'
-ORDER BY name, created_at DESC;
+WHERE id = ANY(@ticket_ids::UUID[])
+ORDER BY created_at;
 
 -- name: GetAvailableTickets :many
 SELECT
     id,
     name,
     price,
-    hash,
-    COUNT(name) AS count
+    currency,
+    hash
 FROM ticket
-WHERE status = 'available' AND name = @name
-GROUP BY id
-LIMIT @limits;
+WHERE status = 'available' AND ticket_type_id = @ticket_type_id
+ORDER BY created_at
+LIMIT @limits
+FOR UPDATE SKIP LOCKED;
+
+-- name: GetTicketByHash :one
+SELECT
+    *
+FROM ticket
+WHERE hash = @hash;
 
 -- name: ChangeTicketsStatus :exec
 UPDATE ticket
     SET status = @status
 WHERE id = @ticket_id;
 
+-- name: RestockTicket :exec
+UPDATE ticket t
+SET
+    status = 'available',
+    hash = @hash,
+    name = tt.name,
+    description = tt.description,
+    price = tt.price,
+    currency = tt.currency,
+    benefits = tt.benefits,
+    min = tt.min,
+    max = tt.max,
+    updated_at = now()
+FROM ticket_type tt
+WHERE t.id = @ticket_id AND tt.id = t.ticket_type_id;
+
+-- name: MakeTicketsAvailable :exec
+UPDATE ticket t
+SET
+    status = 'available',
+    name = tt.name,
+    description = tt.description,
+    price = tt.price,
+    currency = tt.currency,
+    benefits = tt.benefits,
+    min = tt.min,
+    max = tt.max,
+    updated_at = now()
+FROM ticket_type tt
+WHERE t.id = ANY(@ticket_ids::UUID[]) AND tt.id = t.ticket_type_id;
+
 -- ###############################################################
 -- Attendee
 -- ###############################################################
@@ -159,10 +468,37 @@ SET
     status = @status
 WHERE id = @attendee_id;
 
+-- name: UpdateTicketAttendeesStatus :exec
+UPDATE attendee
+SET
+    status = @status,
+    updated_at = now()
+WHERE ticket_id = @ticket_id;
+
+-- name: GetTicketAttendeeForUpdate :one
+SELECT
+    id,
+    event_id,
+    ticket_id,
+    data,
+    status
+FROM attendee
+WHERE ticket_id = @ticket_id AND status != 'refunded'
+ORDER BY created_at
+LIMIT 1
+FOR UPDATE;
+
 -- name: DeleteAttendee :exec
 DELETE FROM attendee
 WHERE id = $1;
 
+-- name: ListTicketAttendees :many
+SELECT
+    *
+FROM attendee
+WHERE ticket_id = ANY(@ticket_ids::UUID[])
+ORDER BY created_at;
+
 -- name: ListAttendee :many
 SELECT
     e.id,
@@ -183,9 +519,9 @@ LIMIT @limits;
 
 -- name: InsertPayment :one
 INSERT INTO payment
-    (event_id, data, name, email, bill_link_id)
+    (event_id, order_id, data, name, email, bill_link_id)
 VALUES
-    (@event_id, @data, @name, @email, @bill_link_id)
+    (@event_id, @order_id, @data, @name, @email, @bill_link_id)
 RETURNING id;
 
 -- name: UpdatePayment :exec
@@ -203,31 +539,589 @@ WHERE id = $1;
 
 -- name: GetPayment :one
 SELECT
-    e.id,
-    e.event_id,
-    e.data,
-    e.name,
-    e.email,
-    e.bill_link_id,
-    e.created_at,
-    e.updated_at
-FROM payment e
-WHERE e.id = $1;
+    *
+FROM payment_summary
+WHERE id = $1;
 
 -- name: CheckPaymentExists :one
 SELECT EXISTS(SELECT 1 FROM payment WHERE bill_link_id = $1) AS payment_exists;
 
+-- name: GetOrderPayment :one
+SELECT
+    *
+FROM payment
+WHERE order_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
 -- name: ListPayment :many
 SELECT
-    e.id,
-    e.event_id,
-    e.data,
-    e.name,
-    e.email,
-    e.bill_link_id,
-    e.created_at,
-    e.updated_at
-FROM payment e
+    *
+FROM payment_summary
+WHERE event_id = @event_id
+  AND (sqlc.narg(created_from)::TIMESTAMPTZ IS NULL OR created_at >= sqlc.narg(created_from)::TIMESTAMPTZ)
+  AND (sqlc.narg(created_to)::TIMESTAMPTZ IS NULL OR created_at < sqlc.narg(created_to)::TIMESTAMPTZ)
+  AND (sqlc.narg(email)::TEXT IS NULL OR email ILIKE sqlc.narg(email)::TEXT)
+  AND (sqlc.narg(status)::TEXT IS NULL OR status = sqlc.narg(status)::TEXT)
+ORDER BY @order_by, created_at DESC, id
+OFFSET @offsets
+LIMIT @limits;
+
+-- ###############################################################
+-- Reservation
+-- ###############################################################
+
+-- name: InsertReservation :one
+INSERT INTO reservation
+    (bill_link_id, event_id, order_id, ticket_ids, ticket_hashes, attendees, amount, currency, payment_provider, expired_at, idempotency_key, request_hash, response)
+VALUES
+    (@bill_link_id, @event_id, @order_id, @ticket_ids::uuid[], @ticket_hashes::text[], @attendees, @amount, @currency, @payment_provider, @expired_at, sqlc.narg('idempotency_key'), sqlc.narg('request_hash'), @response)
+RETURNING id;
+
+-- name: LockIdempotencyKey :exec
+SELECT pg_advisory_xact_lock(hashtextextended('idempotency:' || CAST(@event_id::UUID AS TEXT) || @idempotency_key::TEXT, 0));
+
+-- name: GetReservationByIdempotencyKey :one
+SELECT
+    *
+FROM reservation
+WHERE event_id = @event_id AND idempotency_key = @idempotency_key::TEXT;
+
+-- name: ClearReservationIdempotencyKey :exec
+UPDATE reservation
+SET
+    idempotency_key = NULL,
+    updated_at = now()
+WHERE id = @reservation_id;
+
+-- name: GetOrderByIdempotencyKey :one
+SELECT
+    *
+FROM orders
+WHERE event_id = @event_id AND idempotency_key = @idempotency_key::TEXT;
+
+-- name: SetOrderIdempotencyKey :exec
+UPDATE orders
+SET
+    idempotency_key = @idempotency_key::TEXT,
+    request_hash = @request_hash::TEXT,
+    response = @response,
+    updated_at = now()
+WHERE id = @order_id;
+
+-- name: ClearOrderIdempotencyKey :exec
+UPDATE orders
+SET
+    idempotency_key = NULL,
+    updated_at = now()
+WHERE id = @order_id;
+
+-- name: GetReservation :one
+SELECT
+    *
+FROM reservation
+WHERE bill_link_id = @bill_link_id;
+
+-- name: GetReservationForUpdate :one
+SELECT
+    *
+FROM reservation
+WHERE bill_link_id = @bill_link_id
+FOR UPDATE;
+
+-- name: GetPaidReservationByOrder :one
+SELECT
+    *
+FROM reservation
+WHERE order_id = $1 AND state = 'paid';
+
+-- name: ListExpiredReservationsForUpdate :many
+SELECT
+    *
+FROM reservation
+WHERE state = 'pending' AND expired_at < now()
+ORDER BY expired_at
+LIMIT @limits
+FOR UPDATE SKIP LOCKED;
+
+-- name: ListReservationsToReconcile :many
+SELECT
+    *
+FROM reservation
+WHERE (state = 'pending' AND created_at < @pending_before)
+   OR (state != 'pending' AND reconciled_at IS NULL AND updated_at > @settled_after)
+ORDER BY created_at
+LIMIT @limits;
+
+-- name: MarkReservationReconciled :exec
+UPDATE reservation
+SET
+    reconciled_at = now()
+WHERE id = $1;
+
+-- name: UpdateReservationState :exec
+UPDATE reservation
+SET
+    state = @state,
+    release_reason = @release_reason,
+    updated_at = now()
+WHERE id = @reservation_id;
+
+-- ###############################################################
+-- Order
+-- ###############################################################
+
+-- name: InsertOrder :one
+INSERT INTO orders
+    (event_id, buyer_name, buyer_email, subtotal, discount, promo_code, fee, fee_absorbed, tax, tax_name, amount, currency, payment_provider)
+VALUES
+    (@event_id, @buyer_name, @buyer_email, @subtotal, @discount, sqlc.narg('promo_code'), @fee, @fee_absorbed, @tax, @tax_name, @amount, @currency, @payment_provider)
+RETURNING id;
+
+-- name: InsertOrderItem :one
+INSERT INTO order_item
+    (order_id, ticket_type_id, ticket_name, quantity, unit_price)
+VALUES
+    (@order_id, @ticket_type_id, @ticket_name, @quantity, @unit_price)
+RETURNING id;
+
+-- name: GetOrder :one
+SELECT
+    *
+FROM orders
+WHERE id = $1;
+
+-- name: GetOrderForUpdate :one
+SELECT
+    *
+FROM orders
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListOrder :many
+SELECT
+    *
+FROM orders
+WHERE event_id = @event_id
+ORDER BY @order_by
+OFFSET @offsets
+LIMIT @limits;
+
+-- name: GetEventEndDate :one
+SELECT event_end_date
+FROM event
+WHERE id = @event_id;
+
+-- name: LockBuyerEmail :exec
+SELECT pg_advisory_xact_lock(hashtextextended(CAST(@event_id::UUID AS TEXT) || lower(@buyer_email::TEXT), 0));
+
+-- name: CountFreeTicketsByEmail :one
+SELECT COALESCE(SUM(oi.quantity), 0)::INT AS claimed
+FROM orders o
+JOIN order_item oi ON oi.order_id = o.id
+WHERE o.event_id = @event_id
+  AND lower(o.buyer_email) = lower(@buyer_email::TEXT)
+  AND o.amount = 0
+  AND o.status = 'paid';
+
+-- name: ListOrderItem :many
+SELECT
+    *
+FROM order_item
+WHERE order_id = $1
+ORDER BY created_at;
+
+-- name: UpdateOrderStatus :exec
+UPDATE orders
+SET
+    status = @status,
+    updated_at = now()
+WHERE id = @order_id;
+
+-- name: UpdateOrderProviderReference :exec
+UPDATE orders
+SET
+    provider_reference = @provider_reference,
+    updated_at = now()
+WHERE id = @order_id;
+
+-- name: UpdateOrderBuyer :exec
+UPDATE orders
+SET
+    buyer_name = @buyer_name,
+    buyer_email = @buyer_email,
+    updated_at = now()
+WHERE id = @order_id;
+
+-- name: InsertOrderTransition :exec
+INSERT INTO order_transition
+    (order_id, from_status, to_status, reason)
+VALUES
+    (@order_id, @from_status, @to_status, @reason);
+
+-- name: ListOrderTransition :many
+SELECT
+    *
+FROM order_transition
+WHERE order_id = $1
+ORDER BY created_at;
+
+-- ###############################################################
+-- Refund
+-- ###############################################################
+
+-- name: InsertRefund :one
+INSERT INTO refund
+    (payment_id, order_id, event_id, ticket_ids, amount, currency, reason, status, provider_reference, restock)
+VALUES
+    (@payment_id, @order_id, @event_id, @ticket_ids, @amount, @currency, @reason, @status, @provider_reference, @restock)
+RETURNING *;
+
+-- name: GetRefundForUpdate :one
+SELECT
+    *
+FROM refund
+WHERE id = @refund_id
+FOR UPDATE;
+
+-- name: UpdateRefundStatus :one
+UPDATE refund
+SET
+    status = @status,
+    provider_reference = COALESCE(sqlc.narg(provider_reference), provider_reference),
+    updated_at = now()
+WHERE id = @refund_id
+RETURNING *;
+
+-- name: ListOrderRefund :many
+SELECT
+    *
+FROM refund
+WHERE order_id = $1
+ORDER BY created_at;
+
+-- ###############################################################
+-- Payment mismatch
+-- ###############################################################
+
+-- name: InsertPaymentMismatch :execrows
+INSERT INTO payment_mismatch
+    (reservation_id, bill_link_id, kind, detail)
+VALUES
+    (@reservation_id, @bill_link_id, @kind, @detail)
+ON CONFLICT (bill_link_id, kind) DO NOTHING;
+
+-- name: ListPaymentMismatch :many
+SELECT
+    *
+FROM payment_mismatch
+ORDER BY @order_by
+OFFSET @offsets
+LIMIT @limits;
+
+-- ###############################################################
+-- Payment webhook
+-- ###############################################################
+
+-- name: InsertPaymentWebhook :one
+INSERT INTO payment_webhook
+    (provider, content_type, payload)
+VALUES
+    (@provider, @content_type, @payload)
+RETURNING id;
+
+-- name: GetPaymentWebhook :one
+SELECT
+    *
+FROM payment_webhook
+WHERE id = $1;
+
+-- name: UpdatePaymentWebhook :exec
+UPDATE payment_webhook
+SET
+    signature_valid = @signature_valid,
+    transaction_id = @transaction_id,
+    bill_link_id = @bill_link_id,
+    status = @status,
+    error = @error,
+    attempts = attempts + 1,
+    processed_at = CASE WHEN @status IN ('processed', 'duplicate') THEN now() ELSE processed_at END,
+    updated_at = now()
+WHERE id = @webhook_id;
+
+-- name: HasProcessedPaymentWebhook :one
+SELECT EXISTS(
+    SELECT 1
+    FROM payment_webhook
+    WHERE provider = @provider
+      AND transaction_id = @transaction_id
+      AND status = 'processed'
+      AND id != @webhook_id
+) AS processed;
+
+-- name: ListPaymentWebhook :many
+SELECT
+    *
+FROM payment_webhook
+WHERE sqlc.narg(status)::TEXT IS NULL OR status::TEXT = sqlc.narg(status)::TEXT
 ORDER BY @order_by
 OFFSET @offsets
 LIMIT @limits;
+
+-- name: InsertWaitlistEntry :one
+INSERT INTO waitlist_entry
+    (event_id, ticket_type_id, name, email, quantity)
+VALUES
+    (@event_id, @ticket_type_id, @name, @email, @quantity)
+RETURNING *;
+
+-- name: ListWaitlistEntry :many
+SELECT
+    *
+FROM waitlist_entry
+WHERE ticket_type_id = @ticket_type_id
+ORDER BY created_at
+OFFSET @offsets
+LIMIT @limits;
+
+-- name: ListWaitlistedTicketTypes :many
+SELECT DISTINCT ticket_type_id
+FROM waitlist_entry
+WHERE state = 'waiting';
+
+-- name: GetNextWaitlistEntryForUpdate :one
+SELECT
+    *
+FROM waitlist_entry
+WHERE ticket_type_id = @ticket_type_id AND state = 'waiting'
+ORDER BY created_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: OfferWaitlistEntry :exec
+UPDATE waitlist_entry
+SET
+    state = 'offered',
+    claim_token = @claim_token::TEXT,
+    ticket_ids = @ticket_ids::UUID[],
+    offer_expires_at = @offer_expires_at,
+    updated_at = now()
+WHERE id = @entry_id;
+
+-- name: GetWaitlistOffer :one
+SELECT
+    we.*,
+    tt.name AS ticket_name
+FROM waitlist_entry we
+JOIN ticket_type tt ON tt.id = we.ticket_type_id
+WHERE we.claim_token = @claim_token::TEXT;
+
+-- name: GetWaitlistOfferForUpdate :one
+SELECT
+    *
+FROM waitlist_entry
+WHERE claim_token = @claim_token::TEXT
+FOR UPDATE;
+
+-- name: ListExpiredWaitlistOffersForUpdate :many
+SELECT
+    *
+FROM waitlist_entry
+WHERE state = 'offered' AND offer_expires_at < now()
+ORDER BY offer_expires_at
+LIMIT @limits
+FOR UPDATE SKIP LOCKED;
+
+-- name: ListUnnotifiedWaitlistOffersForUpdate :many
+SELECT
+    we.*,
+    tt.name AS ticket_name
+FROM waitlist_entry we
+JOIN ticket_type tt ON tt.id = we.ticket_type_id
+WHERE we.state = 'offered' AND we.notified_at IS NULL
+ORDER BY we.offer_expires_at
+LIMIT @limits
+FOR UPDATE OF we SKIP LOCKED;
+
+-- name: MarkWaitlistOfferNotified :exec
+UPDATE waitlist_entry
+SET
+    notified_at = now(),
+    updated_at = now()
+WHERE id = @entry_id;
+
+-- name: UpdateWaitlistEntryState :exec
+UPDATE waitlist_entry
+SET
+    state = @state,
+    updated_at = now()
+WHERE id = @entry_id;
+
+-- name: ListTicketTypeIDsOfTickets :many
+SELECT DISTINCT ticket_type_id
+FROM ticket
+WHERE id = ANY(@ticket_ids::UUID[]);
+
+-- name: GetTicketsByIDs :many
+SELECT
+    id,
+    name,
+    price,
+    currency,
+    hash
+FROM ticket
+WHERE id = ANY(@ticket_ids::UUID[])
+ORDER BY created_at;
+
+-- ###############################################################
+-- Promo code
+-- ###############################################################
+
+-- name: InsertPromoCode :one
+INSERT INTO promo_code
+    (event_id, code, discount_type, value, currency, ticket_type_ids, max_uses, starts_at, ends_at)
+VALUES
+    (@event_id, @code, @discount_type, @value, @currency, @ticket_type_ids::UUID[], @max_uses, @starts_at, @ends_at)
+RETURNING *;
+
+-- name: ListPromoCode :many
+SELECT
+    *
+FROM promo_code
+WHERE event_id = @event_id
+ORDER BY created_at DESC
+OFFSET @offsets
+LIMIT @limits;
+
+-- name: GetPromoCode :one
+SELECT
+    *
+FROM promo_code
+WHERE id = @promo_code_id;
+
+-- name: GetPromoCodeByCode :one
+SELECT
+    *
+FROM promo_code
+WHERE event_id = @event_id AND lower(code) = lower(@code::TEXT);
+
+-- name: UpdatePromoCode :one
+UPDATE promo_code
+SET
+    code = COALESCE(sqlc.narg(code), code),
+    discount_type = COALESCE(sqlc.narg(discount_type), discount_type),
+    value = COALESCE(sqlc.narg(value), value),
+    currency = COALESCE(sqlc.narg(currency), currency),
+    ticket_type_ids = COALESCE(sqlc.narg(ticket_type_ids)::UUID[], ticket_type_ids),
+    max_uses = COALESCE(sqlc.narg(max_uses), max_uses),
+    starts_at = COALESCE(sqlc.narg(starts_at), starts_at),
+    ends_at = COALESCE(sqlc.narg(ends_at), ends_at),
+    updated_at = now()
+WHERE id = @promo_code_id
+RETURNING *;
+
+-- name: DeletePromoCode :execrows
+DELETE FROM promo_code
+WHERE id = @promo_code_id;
+
+-- name: UsePromoCode :execrows
+UPDATE promo_code
+SET
+    used_count = used_count + 1,
+    updated_at = now()
+WHERE id = @promo_code_id AND (max_uses = 0 OR used_count < max_uses);
+
+-- name: InsertPromoCodeRedemption :exec
+INSERT INTO promo_code_redemption
+    (promo_code_id, order_id, discount, currency)
+VALUES
+    (@promo_code_id, @order_id, @discount, @currency);
+
+-- name: ReleasePromoCodeRedemption :exec
+WITH released AS (
+    UPDATE promo_code_redemption
+    SET released_at = now()
+    WHERE order_id = @order_id AND released_at IS NULL
+    RETURNING promo_code_id
+)
+UPDATE promo_code pc
+SET
+    used_count = pc.used_count - 1,
+    updated_at = now()
+FROM released
+WHERE pc.id = released.promo_code_id;
+
+-- ###############################################################
+-- Access code
+-- ###############################################################
+
+-- name: InsertAccessCode :one
+INSERT INTO access_code
+    (event_id, code, ticket_type_ids, max_tickets, starts_at, ends_at)
+VALUES
+    (@event_id, @code, @ticket_type_ids::UUID[], @max_tickets, @starts_at, @ends_at)
+RETURNING *;
+
+-- name: ListAccessCode :many
+SELECT
+    *
+FROM access_code
+WHERE event_id = @event_id
+ORDER BY created_at DESC
+OFFSET @offsets
+LIMIT @limits;
+
+-- name: GetAccessCode :one
+SELECT
+    *
+FROM access_code
+WHERE id = @access_code_id;
+
+-- name: GetAccessCodeByCode :one
+SELECT
+    *
+FROM access_code
+WHERE event_id = @event_id AND lower(code) = lower(@code::TEXT);
+
+-- name: UpdateAccessCode :one
+UPDATE access_code
+SET
+    code = COALESCE(sqlc.narg(code), code),
+    ticket_type_ids = COALESCE(sqlc.narg(ticket_type_ids)::UUID[], ticket_type_ids),
+    max_tickets = COALESCE(sqlc.narg(max_tickets), max_tickets),
+    starts_at = COALESCE(sqlc.narg(starts_at), starts_at),
+    ends_at = COALESCE(sqlc.narg(ends_at), ends_at),
+    updated_at = now()
+WHERE id = @access_code_id
+RETURNING *;
+
+-- name: DeleteAccessCode :execrows
+DELETE FROM access_code
+WHERE id = @access_code_id;
+
+-- name: UseAccessCode :execrows
+UPDATE access_code
+SET
+    used_tickets = used_tickets + @tickets::INT,
+    updated_at = now()
+WHERE id = @access_code_id AND (max_tickets = 0 OR used_tickets + @tickets::INT <= max_tickets);
+
+-- name: InsertAccessCodeRedemption :exec
+INSERT INTO access_code_redemption
+    (access_code_id, order_id, tickets)
+VALUES
+    (@access_code_id, @order_id, @tickets);
+
+-- name: ReleaseAccessCodeRedemption :exec
+WITH released AS (
+    UPDATE access_code_redemption
+    SET released_at = now()
+    WHERE order_id = @order_id AND released_at IS NULL
+    RETURNING access_code_id, tickets
+)
+UPDATE access_code ac
+SET
+    used_tickets = ac.used_tickets - released.tickets,
+    updated_at = now()
+FROM released
+WHERE ac.id = released.access_code_id;
@@ -38,6 +38,67 @@ func (q *Queries) CheckPaymentExists(ctx context.Context, billLinkID int32) (boo
 	return payment_exists, err
 }
 
+const clearOrderIdempotencyKey = `-- name: ClearOrderIdempotencyKey :exec
+UPDATE orders
+SET
+    idempotency_key = NULL,
+    updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) ClearOrderIdempotencyKey(ctx context.Context, orderID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, clearOrderIdempotencyKey, orderID)
+	return err
+}
+
+const clearReservationIdempotencyKey = `-- name: ClearReservationIdempotencyKey :exec
+UPDATE reservation
+SET
+    idempotency_key = NULL,
+    updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) ClearReservationIdempotencyKey(ctx context.Context, reservationID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, clearReservationIdempotencyKey, reservationID)
+	return err
+}
+
+const countFreeTicketsByEmail = `-- name: CountFreeTicketsByEmail :one
+SELECT COALESCE(SUM(oi.quantity), 0)::INT AS claimed
+FROM orders o
+JOIN order_item oi ON oi.order_id = o.id
+WHERE o.event_id = $1
+  AND lower(o.buyer_email) = lower($2::TEXT)
+  AND o.amount = 0
+  AND o.status = 'paid'
+`
+
+type CountFreeTicketsByEmailParams struct {
+	EventID    pgtype.UUID
+	BuyerEmail string
+}
+
+func (q *Queries) CountFreeTicketsByEmail(ctx context.Context, arg CountFreeTicketsByEmailParams) (int32, error) {
+	row := q.db.QueryRow(ctx, countFreeTicketsByEmail, arg.EventID, arg.BuyerEmail)
+	var claimed int32
+	err := row.Scan(&claimed)
+	return claimed, err
+}
+
+const deleteAccessCode = `-- name: DeleteAccessCode :execrows
+DELETE FROM access_code
+WHERE id = $1
+`
+
+func (q *Queries) DeleteAccessCode(ctx context.Context, accessCodeID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAccessCode, accessCodeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteAttendee = `-- name: DeleteAttendee :exec
 DELETE FROM attendee
 WHERE id = $1
@@ -48,6 +109,31 @@ func (q *Queries) DeleteAttendee(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteAvailableTicketsOfType = `-- name: DeleteAvailableTicketsOfType :execrows
+DELETE FROM ticket
+WHERE id IN (
+    SELECT t.id
+    FROM ticket t
+    WHERE t.ticket_type_id = $1 AND t.status = 'available'
+    ORDER BY t.created_at DESC
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+`
+
+type DeleteAvailableTicketsOfTypeParams struct {
+	TicketTypeID pgtype.UUID
+	Limits       int32
+}
+
+func (q *Queries) DeleteAvailableTicketsOfType(ctx context.Context, arg DeleteAvailableTicketsOfTypeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAvailableTicketsOfType, arg.TicketTypeID, arg.Limits)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteEvent = `-- name: DeleteEvent :exec
 DELETE FROM event
 WHERE id = $1
@@ -68,56 +154,123 @@ func (q *Queries) DeletePayment(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
-const deleteTicket = `-- name: DeleteTicket :exec
-WITH rows_to_delete AS (
-    SELECT id, event_id, name, description, price, benefits, status, created_at, updated_at, hash, min, max
-    FROM ticket
-    WHERE ticket.event_id = $1 AND ticket.name = $2
-    LIMIT $3
-)
-DELETE FROM ticket
-WHERE id IN (SELECT id FROM rows_to_delete)
+const deletePromoCode = `-- name: DeletePromoCode :execrows
+DELETE FROM promo_code
+WHERE id = $1
+`
+
+func (q *Queries) DeletePromoCode(ctx context.Context, promoCodeID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePromoCode, promoCodeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTicketPricePhases = `-- name: DeleteTicketPricePhases :exec
+DELETE FROM ticket_price_phase
+WHERE ticket_type_id = $1
 `
 
-type DeleteTicketParams struct {
-	EvID       pgtype.UUID
-	TicketName string
-	Limits     int32
+func (q *Queries) DeleteTicketPricePhases(ctx context.Context, ticketTypeID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteTicketPricePhases, ticketTypeID)
+	return err
 }
 
-func (q *Queries) DeleteTicket(ctx context.Context, arg DeleteTicketParams) error {
-	_, err := q.db.Exec(ctx, deleteTicket, arg.EvID, arg.TicketName, arg.Limits)
+const deleteTicketType = `-- name: DeleteTicketType :exec
+DELETE FROM ticket_type
+WHERE id = $1
+`
+
+func (q *Queries) DeleteTicketType(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteTicketType, id)
 	return err
 }
 
+const getAccessCode = `-- name: GetAccessCode :one
+SELECT
+    id, event_id, code, ticket_type_ids, max_tickets, used_tickets, starts_at, ends_at, created_at, updated_at
+FROM access_code
+WHERE id = $1
+`
+
+func (q *Queries) GetAccessCode(ctx context.Context, accessCodeID pgtype.UUID) (AccessCode, error) {
+	row := q.db.QueryRow(ctx, getAccessCode, accessCodeID)
+	var i AccessCode
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Code,
+		&i.TicketTypeIds,
+		&i.MaxTickets,
+		&i.UsedTickets,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getAccessCodeByCode = `-- name: GetAccessCodeByCode :one
+SELECT
+    id, event_id, code, ticket_type_ids, max_tickets, used_tickets, starts_at, ends_at, created_at, updated_at
+FROM access_code
+WHERE event_id = $1 AND lower(code) = lower($2::TEXT)
+`
+
+type GetAccessCodeByCodeParams struct {
+	EventID pgtype.UUID
+	Code    string
+}
+
+func (q *Queries) GetAccessCodeByCode(ctx context.Context, arg GetAccessCodeByCodeParams) (AccessCode, error) {
+	row := q.db.QueryRow(ctx, getAccessCodeByCode, arg.EventID, arg.Code)
+	var i AccessCode
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Code,
+		&i.TicketTypeIds,
+		&i.MaxTickets,
+		&i.UsedTickets,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getAvailableTickets = `-- name: GetAvailableTickets :many
 SELECT
     id,
     name,
     price,
-    hash,
-    COUNT(name) AS count
+    currency,
+    hash
 FROM ticket
-WHERE status = 'available' AND name = $1
-GROUP BY id
+WHERE status = 'available' AND ticket_type_id = $1
+ORDER BY created_at
 LIMIT $2
+FOR UPDATE SKIP LOCKED
 `
 
 type GetAvailableTicketsParams struct {
-	Name   string
-	Limits int32
+	TicketTypeID pgtype.UUID
+	Limits       int32
 }
 
 type GetAvailableTicketsRow struct {
-	ID    pgtype.UUID
-	Name  string
-	Price string
-	Hash  pgtype.Text
-	Count int64
+	ID       pgtype.UUID
+	Name     string
+	Price    int64
+	Currency string
+	Hash     pgtype.Text
 }
 
 func (q *Queries) GetAvailableTickets(ctx context.Context, arg GetAvailableTicketsParams) ([]GetAvailableTicketsRow, error) {
-	rows, err := q.db.Query(ctx, getAvailableTickets, arg.Name, arg.Limits)
+	rows, err := q.db.Query(ctx, getAvailableTickets, arg.TicketTypeID, arg.Limits)
 	if err != nil {
 		return nil, err
 	}
@@ -129,8 +282,8 @@ func (q *Queries) GetAvailableTickets(ctx context.Context, arg GetAvailableTicke
 			&i.ID,
 			&i.Name,
 			&i.Price,
+			&i.Currency,
 			&i.Hash,
-			&i.Count,
 		); err != nil {
 			return nil, err
 		}
@@ -152,6 +305,14 @@ SELECT
     e.event_end_date,
     e.created_at,
     e.updated_at,
+    e.payment_provider,
+    e.fee_type,
+    e.fee_value,
+    e.fee_per,
+    e.fee_absorbed,
+    e.tax_name,
+    e.tax_rate,
+    e.max_free_tickets_per_email,
     eti.inputs as ticket_inputs
 FROM event e
 LEFT JOIN ticket_inputs eti ON e.id = eti.event_id
@@ -159,15 +320,23 @@ WHERE e.id = $1
 `
 
 type GetEventRow struct {
-	ID             pgtype.UUID
-	Name           string
-	Description    string
-	Location       string
-	EventStartDate pgtype.Timestamptz
-	EventEndDate   pgtype.Timestamptz
-	CreatedAt      pgtype.Timestamptz
-	UpdatedAt      pgtype.Timestamptz
-	TicketInputs   []byte
+	ID                     pgtype.UUID
+	Name                   string
+	Description            string
+	Location               string
+	EventStartDate         pgtype.Timestamptz
+	EventEndDate           pgtype.Timestamptz
+	CreatedAt              pgtype.Timestamptz
+	UpdatedAt              pgtype.Timestamptz
+	PaymentProvider        string
+	FeeType                FeeType
+	FeeValue               int64
+	FeePer                 FeePer
+	FeeAbsorbed            bool
+	TaxName                string
+	TaxRate                int32
+	MaxFreeTicketsPerEmail int32
+	TicketInputs           []byte
 }
 
 func (q *Queries) GetEvent(ctx context.Context, id pgtype.UUID) (GetEventRow, error) {
@@ -182,274 +351,2747 @@ func (q *Queries) GetEvent(ctx context.Context, id pgtype.UUID) (GetEventRow, er
 		&i.EventEndDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.PaymentProvider,
+		&i.FeeType,
+		&i.FeeValue,
+		&i.FeePer,
+		&i.FeeAbsorbed,
+		&i.TaxName,
+		&i.TaxRate,
+		&i.MaxFreeTicketsPerEmail,
 		&i.TicketInputs,
 	)
 	return i, err
 }
 
-const getPayment = `-- name: GetPayment :one
+const getEventEndDate = `-- name: GetEventEndDate :one
+SELECT event_end_date
+FROM event
+WHERE id = $1
+`
+
+func (q *Queries) GetEventEndDate(ctx context.Context, eventID pgtype.UUID) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getEventEndDate, eventID)
+	var event_end_date pgtype.Timestamptz
+	err := row.Scan(&event_end_date)
+	return event_end_date, err
+}
+
+const getEventFreeTicketLimit = `-- name: GetEventFreeTicketLimit :one
+SELECT max_free_tickets_per_email
+FROM event
+WHERE id = $1
+`
+
+func (q *Queries) GetEventFreeTicketLimit(ctx context.Context, id pgtype.UUID) (int32, error) {
+	row := q.db.QueryRow(ctx, getEventFreeTicketLimit, id)
+	var max_free_tickets_per_email int32
+	err := row.Scan(&max_free_tickets_per_email)
+	return max_free_tickets_per_email, err
+}
+
+const getEventPaymentProvider = `-- name: GetEventPaymentProvider :one
+SELECT payment_provider
+FROM event
+WHERE id = $1
+`
+
+func (q *Queries) GetEventPaymentProvider(ctx context.Context, id pgtype.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, getEventPaymentProvider, id)
+	var payment_provider string
+	err := row.Scan(&payment_provider)
+	return payment_provider, err
+}
+
+const getEventPricing = `-- name: GetEventPricing :one
+SELECT fee_type, fee_value, fee_per, fee_absorbed, tax_name, tax_rate
+FROM event
+WHERE id = $1
+`
+
+type GetEventPricingRow struct {
+	FeeType     FeeType
+	FeeValue    int64
+	FeePer      FeePer
+	FeeAbsorbed bool
+	TaxName     string
+	TaxRate     int32
+}
+
+func (q *Queries) GetEventPricing(ctx context.Context, id pgtype.UUID) (GetEventPricingRow, error) {
+	row := q.db.QueryRow(ctx, getEventPricing, id)
+	var i GetEventPricingRow
+	err := row.Scan(
+		&i.FeeType,
+		&i.FeeValue,
+		&i.FeePer,
+		&i.FeeAbsorbed,
+		&i.TaxName,
+		&i.TaxRate,
+	)
+	return i, err
+}
+
+const getEventTicketInputs = `-- name: GetEventTicketInputs :one
+SELECT inputs
+FROM ticket_inputs
+WHERE event_id = $1
+`
+
+func (q *Queries) GetEventTicketInputs(ctx context.Context, eventID pgtype.UUID) ([]byte, error) {
+	row := q.db.QueryRow(ctx, getEventTicketInputs, eventID)
+	var inputs []byte
+	err := row.Scan(&inputs)
+	return inputs, err
+}
+
+const getNextWaitlistEntryForUpdate = `-- name: GetNextWaitlistEntryForUpdate :one
 SELECT
-    e.id,
-    e.event_id,
-    e.data,
-    e.name,
-    e.email,
-    e.bill_link_id,
-    e.created_at,
-    e.updated_at
-FROM payment e
-WHERE e.id = $1
+    id, event_id, ticket_type_id, name, email, quantity, state, claim_token, ticket_ids, offer_expires_at, notified_at, created_at, updated_at
+FROM waitlist_entry
+WHERE ticket_type_id = $1 AND state = 'waiting'
+ORDER BY created_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED
 `
 
-func (q *Queries) GetPayment(ctx context.Context, id pgtype.UUID) (Payment, error) {
-	row := q.db.QueryRow(ctx, getPayment, id)
-	var i Payment
+func (q *Queries) GetNextWaitlistEntryForUpdate(ctx context.Context, ticketTypeID pgtype.UUID) (WaitlistEntry, error) {
+	row := q.db.QueryRow(ctx, getNextWaitlistEntryForUpdate, ticketTypeID)
+	var i WaitlistEntry
 	err := row.Scan(
 		&i.ID,
 		&i.EventID,
-		&i.Data,
+		&i.TicketTypeID,
 		&i.Name,
 		&i.Email,
-		&i.BillLinkID,
+		&i.Quantity,
+		&i.State,
+		&i.ClaimToken,
+		&i.TicketIds,
+		&i.OfferExpiresAt,
+		&i.NotifiedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getTicket = `-- name: GetTicket :one
+const getOrder = `-- name: GetOrder :one
 SELECT
-    id, event_id, name, description, price, benefits, status, created_at, updated_at, hash, min, max
-FROM ticket
+    id, event_id, buyer_name, buyer_email, amount, payment_provider, provider_reference, status, created_at, updated_at, currency, subtotal, fee, fee_absorbed, tax, tax_name, discount, promo_code, idempotency_key, request_hash, response
+FROM orders
 WHERE id = $1
 `
 
-func (q *Queries) GetTicket(ctx context.Context, id pgtype.UUID) (Ticket, error) {
-	row := q.db.QueryRow(ctx, getTicket, id)
-	var i Ticket
+func (q *Queries) GetOrder(ctx context.Context, id pgtype.UUID) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrder, id)
+	var i Order
 	err := row.Scan(
 		&i.ID,
 		&i.EventID,
-		&i.Name,
-		&i.Description,
-		&i.Price,
-		&i.Benefits,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.Amount,
+		&i.PaymentProvider,
+		&i.ProviderReference,
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.Hash,
-		&i.Min,
-		&i.Max,
+		&i.Currency,
+		&i.Subtotal,
+		&i.Fee,
+		&i.FeeAbsorbed,
+		&i.Tax,
+		&i.TaxName,
+		&i.Discount,
+		&i.PromoCode,
+		&i.IdempotencyKey,
+		&i.RequestHash,
+		&i.Response,
 	)
 	return i, err
 }
 
-const insertAttendee = `-- name: InsertAttendee :one
-
-INSERT INTO attendee
-    (event_id, ticket_id, data)
-VALUES
-    ($1, $2, $3)
-RETURNING id
+const getOrderByIdempotencyKey = `-- name: GetOrderByIdempotencyKey :one
+SELECT
+    id, event_id, buyer_name, buyer_email, amount, payment_provider, provider_reference, status, created_at, updated_at, currency, subtotal, fee, fee_absorbed, tax, tax_name, discount, promo_code, idempotency_key, request_hash, response
+FROM orders
+WHERE event_id = $1 AND idempotency_key = $2::TEXT
 `
 
-type InsertAttendeeParams struct {
-	EventID  pgtype.UUID
-	TicketID pgtype.UUID
-	Data     []byte
+type GetOrderByIdempotencyKeyParams struct {
+	EventID        pgtype.UUID
+	IdempotencyKey string
 }
 
-// ###############################################################
-// Attendee
-// ###############################################################
-func (q *Queries) InsertAttendee(ctx context.Context, arg InsertAttendeeParams) (pgtype.UUID, error) {
-	row := q.db.QueryRow(ctx, insertAttendee, arg.EventID, arg.TicketID, arg.Data)
-	var id pgtype.UUID
-	err := row.Scan(&id)
-	return id, err
+func (q *Queries) GetOrderByIdempotencyKey(ctx context.Context, arg GetOrderByIdempotencyKeyParams) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrderByIdempotencyKey, arg.EventID, arg.IdempotencyKey)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.Amount,
+		&i.PaymentProvider,
+		&i.ProviderReference,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.Subtotal,
+		&i.Fee,
+		&i.FeeAbsorbed,
+		&i.Tax,
+		&i.TaxName,
+		&i.Discount,
+		&i.PromoCode,
+		&i.IdempotencyKey,
+		&i.RequestHash,
+		&i.Response,
+	)
+	return i, err
 }
 
-const insertEvent = `-- name: InsertEvent :one
-
-INSERT INTO event
-    (name, description, location, event_start_date, event_end_date)
-VALUES
-    ($1, $2, $3, $4, $5)
-RETURNING id
+const getOrderForUpdate = `-- name: GetOrderForUpdate :one
+SELECT
+    id, event_id, buyer_name, buyer_email, amount, payment_provider, provider_reference, status, created_at, updated_at, currency, subtotal, fee, fee_absorbed, tax, tax_name, discount, promo_code, idempotency_key, request_hash, response
+FROM orders
+WHERE id = $1
+FOR UPDATE
 `
 
-type InsertEventParams struct {
-	Name           string
-	Description    string
-	Location       string
-	EventStartDate pgtype.Timestamptz
-	EventEndDate   pgtype.Timestamptz
+func (q *Queries) GetOrderForUpdate(ctx context.Context, id pgtype.UUID) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrderForUpdate, id)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.BuyerName,
+		&i.BuyerEmail,
+		&i.Amount,
+		&i.PaymentProvider,
+		&i.ProviderReference,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.Subtotal,
+		&i.Fee,
+		&i.FeeAbsorbed,
+		&i.Tax,
+		&i.TaxName,
+		&i.Discount,
+		&i.PromoCode,
+		&i.IdempotencyKey,
+		&i.RequestHash,
+		&i.Response,
+	)
+	return i, err
 }
 
-// ###############################################################
-// Event
-// ###############################################################
-func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) (pgtype.UUID, error) {
-	row := q.db.QueryRow(ctx, insertEvent,
-		arg.Name,
-		arg.Description,
-		arg.Location,
-		arg.EventStartDate,
-		arg.EventEndDate,
+const getOrderPayment = `-- name: GetOrderPayment :one
+SELECT
+    id, event_id, data, name, email, bill_link_id, created_at, updated_at, order_id
+FROM payment
+WHERE order_id = $1
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetOrderPayment(ctx context.Context, orderID pgtype.UUID) (Payment, error) {
+	row := q.db.QueryRow(ctx, getOrderPayment, orderID)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Data,
+		&i.Name,
+		&i.Email,
+		&i.BillLinkID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OrderID,
 	)
-	var id pgtype.UUID
-	err := row.Scan(&id)
-	return id, err
+	return i, err
 }
 
-const insertEventTicketInput = `-- name: InsertEventTicketInput :one
-
-INSERT INTO ticket_inputs
-    (event_id, inputs)
-VALUES
-    ($1, $2)
-RETURNING id
+const getPaidReservationByOrder = `-- name: GetPaidReservationByOrder :one
+SELECT
+    id, bill_link_id, event_id, ticket_ids, ticket_hashes, attendees, state, expired_at, created_at, updated_at, release_reason, amount, payment_provider, order_id, currency, reconciled_at, idempotency_key, request_hash, response
+FROM reservation
+WHERE order_id = $1 AND state = 'paid'
 `
 
-type InsertEventTicketInputParams struct {
-	EventID pgtype.UUID
-	Inputs  []byte
+func (q *Queries) GetPaidReservationByOrder(ctx context.Context, orderID pgtype.UUID) (Reservation, error) {
+	row := q.db.QueryRow(ctx, getPaidReservationByOrder, orderID)
+	var i Reservation
+	err := row.Scan(
+		&i.ID,
+		&i.BillLinkID,
+		&i.EventID,
+		&i.TicketIds,
+		&i.TicketHashes,
+		&i.Attendees,
+		&i.State,
+		&i.ExpiredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReleaseReason,
+		&i.Amount,
+		&i.PaymentProvider,
+		&i.OrderID,
+		&i.Currency,
+		&i.ReconciledAt,
+		&i.IdempotencyKey,
+		&i.RequestHash,
+		&i.Response,
+	)
+	return i, err
 }
 
-// ###############################################################
-// TicketInputs
-// ###############################################################
-func (q *Queries) InsertEventTicketInput(ctx context.Context, arg InsertEventTicketInputParams) (pgtype.UUID, error) {
-	row := q.db.QueryRow(ctx, insertEventTicketInput, arg.EventID, arg.Inputs)
-	var id pgtype.UUID
-	err := row.Scan(&id)
-	return id, err
-}
+const getPayment = `-- name: GetPayment :one
+SELECT
+    id, event_id, order_id, name, email, bill_link_id, data, amount, currency, status, created_at, updated_at
+FROM payment_summary
+WHERE id = $1
+`
 
-const insertPayment = `-- name: InsertPayment :one
+func (q *Queries) GetPayment(ctx context.Context, id pgtype.UUID) (PaymentSummary, error) {
+	row := q.db.QueryRow(ctx, getPayment, id)
+	var i PaymentSummary
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.OrderID,
+		&i.Name,
+		&i.Email,
+		&i.BillLinkID,
+		&i.Data,
+		&i.Amount,
+		&i.Currency,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPaymentWebhook = `-- name: GetPaymentWebhook :one
+SELECT
+    id, provider, content_type, payload, signature_valid, transaction_id, bill_link_id, status, error, attempts, processed_at, created_at, updated_at
+FROM payment_webhook
+WHERE id = $1
+`
+
+func (q *Queries) GetPaymentWebhook(ctx context.Context, id pgtype.UUID) (PaymentWebhook, error) {
+	row := q.db.QueryRow(ctx, getPaymentWebhook, id)
+	var i PaymentWebhook
+	err := row.Scan(
+		&i.ID,
+		&i.Provider,
+		&i.ContentType,
+		&i.Payload,
+		&i.SignatureValid,
+		&i.TransactionID,
+		&i.BillLinkID,
+		&i.Status,
+		&i.Error,
+		&i.Attempts,
+		&i.ProcessedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPromoCode = `-- name: GetPromoCode :one
+SELECT
+    id, event_id, code, discount_type, value, currency, ticket_type_ids, max_uses, used_count, starts_at, ends_at, created_at, updated_at
+FROM promo_code
+WHERE id = $1
+`
+
+func (q *Queries) GetPromoCode(ctx context.Context, promoCodeID pgtype.UUID) (PromoCode, error) {
+	row := q.db.QueryRow(ctx, getPromoCode, promoCodeID)
+	var i PromoCode
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Code,
+		&i.DiscountType,
+		&i.Value,
+		&i.Currency,
+		&i.TicketTypeIds,
+		&i.MaxUses,
+		&i.UsedCount,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPromoCodeByCode = `-- name: GetPromoCodeByCode :one
+SELECT
+    id, event_id, code, discount_type, value, currency, ticket_type_ids, max_uses, used_count, starts_at, ends_at, created_at, updated_at
+FROM promo_code
+WHERE event_id = $1 AND lower(code) = lower($2::TEXT)
+`
+
+type GetPromoCodeByCodeParams struct {
+	EventID pgtype.UUID
+	Code    string
+}
+
+func (q *Queries) GetPromoCodeByCode(ctx context.Context, arg GetPromoCodeByCodeParams) (PromoCode, error) {
+	row := q.db.QueryRow(ctx, getPromoCodeByCode, arg.EventID, arg.Code)
+	var i PromoCode
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Code,
+		&i.DiscountType,
+		&i.Value,
+		&i.Currency,
+		&i.TicketTypeIds,
+		&i.MaxUses,
+		&i.UsedCount,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getRefundForUpdate = `-- name: GetRefundForUpdate :one
+SELECT
+    id, payment_id, order_id, event_id, ticket_ids, amount, currency, reason, status, provider_reference, created_at, updated_at, restock
+FROM refund
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetRefundForUpdate(ctx context.Context, refundID pgtype.UUID) (Refund, error) {
+	row := q.db.QueryRow(ctx, getRefundForUpdate, refundID)
+	var i Refund
+	err := row.Scan(
+		&i.ID,
+		&i.PaymentID,
+		&i.OrderID,
+		&i.EventID,
+		&i.TicketIds,
+		&i.Amount,
+		&i.Currency,
+		&i.Reason,
+		&i.Status,
+		&i.ProviderReference,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Restock,
+	)
+	return i, err
+}
+
+const getReservation = `-- name: GetReservation :one
+SELECT
+    id, bill_link_id, event_id, ticket_ids, ticket_hashes, attendees, state, expired_at, created_at, updated_at, release_reason, amount, payment_provider, order_id, currency, reconciled_at, idempotency_key, request_hash, response
+FROM reservation
+WHERE bill_link_id = $1
+`
+
+func (q *Queries) GetReservation(ctx context.Context, billLinkID int32) (Reservation, error) {
+	row := q.db.QueryRow(ctx, getReservation, billLinkID)
+	var i Reservation
+	err := row.Scan(
+		&i.ID,
+		&i.BillLinkID,
+		&i.EventID,
+		&i.TicketIds,
+		&i.TicketHashes,
+		&i.Attendees,
+		&i.State,
+		&i.ExpiredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReleaseReason,
+		&i.Amount,
+		&i.PaymentProvider,
+		&i.OrderID,
+		&i.Currency,
+		&i.ReconciledAt,
+		&i.IdempotencyKey,
+		&i.RequestHash,
+		&i.Response,
+	)
+	return i, err
+}
+
+const getReservationByIdempotencyKey = `-- name: GetReservationByIdempotencyKey :one
+SELECT
+    id, bill_link_id, event_id, ticket_ids, ticket_hashes, attendees, state, expired_at, created_at, updated_at, release_reason, amount, payment_provider, order_id, currency, reconciled_at, idempotency_key, request_hash, response
+FROM reservation
+WHERE event_id = $1 AND idempotency_key = $2::TEXT
+`
+
+type GetReservationByIdempotencyKeyParams struct {
+	EventID        pgtype.UUID
+	IdempotencyKey string
+}
+
+func (q *Queries) GetReservationByIdempotencyKey(ctx context.Context, arg GetReservationByIdempotencyKeyParams) (Reservation, error) {
+	row := q.db.QueryRow(ctx, getReservationByIdempotencyKey, arg.EventID, arg.IdempotencyKey)
+	var i Reservation
+	err := row.Scan(
+		&i.ID,
+		&i.BillLinkID,
+		&i.EventID,
+		&i.TicketIds,
+		&i.TicketHashes,
+		&i.Attendees,
+		&i.State,
+		&i.ExpiredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReleaseReason,
+		&i.Amount,
+		&i.PaymentProvider,
+		&i.OrderID,
+		&i.Currency,
+		&i.ReconciledAt,
+		&i.IdempotencyKey,
+		&i.RequestHash,
+		&i.Response,
+	)
+	return i, err
+}
+
+const getReservationForUpdate = `-- name: GetReservationForUpdate :one
+SELECT
+    id, bill_link_id, event_id, ticket_ids, ticket_hashes, attendees, state, expired_at, created_at, updated_at, release_reason, amount, payment_provider, order_id, currency, reconciled_at, idempotency_key, request_hash, response
+FROM reservation
+WHERE bill_link_id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetReservationForUpdate(ctx context.Context, billLinkID int32) (Reservation, error) {
+	row := q.db.QueryRow(ctx, getReservationForUpdate, billLinkID)
+	var i Reservation
+	err := row.Scan(
+		&i.ID,
+		&i.BillLinkID,
+		&i.EventID,
+		&i.TicketIds,
+		&i.TicketHashes,
+		&i.Attendees,
+		&i.State,
+		&i.ExpiredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReleaseReason,
+		&i.Amount,
+		&i.PaymentProvider,
+		&i.OrderID,
+		&i.Currency,
+		&i.ReconciledAt,
+		&i.IdempotencyKey,
+		&i.RequestHash,
+		&i.Response,
+	)
+	return i, err
+}
+
+const getTicket = `-- name: GetTicket :one
+SELECT
+    id, event_id, name, description, price, benefits, status, created_at, updated_at, hash, min, max, currency, ticket_type_id
+FROM ticket
+WHERE id = $1
+`
+
+func (q *Queries) GetTicket(ctx context.Context, id pgtype.UUID) (Ticket, error) {
+	row := q.db.QueryRow(ctx, getTicket, id)
+	var i Ticket
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.Benefits,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Hash,
+		&i.Min,
+		&i.Max,
+		&i.Currency,
+		&i.TicketTypeID,
+	)
+	return i, err
+}
+
+const getTicketAttendeeForUpdate = `-- name: GetTicketAttendeeForUpdate :one
+SELECT
+    id,
+    event_id,
+    ticket_id,
+    data,
+    status
+FROM attendee
+WHERE ticket_id = $1 AND status != 'refunded'
+ORDER BY created_at
+LIMIT 1
+FOR UPDATE
+`
+
+type GetTicketAttendeeForUpdateRow struct {
+	ID       pgtype.UUID
+	EventID  pgtype.UUID
+	TicketID pgtype.UUID
+	Data     []byte
+	Status   AttendeeStatus
+}
+
+func (q *Queries) GetTicketAttendeeForUpdate(ctx context.Context, ticketID pgtype.UUID) (GetTicketAttendeeForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, getTicketAttendeeForUpdate, ticketID)
+	var i GetTicketAttendeeForUpdateRow
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.TicketID,
+		&i.Data,
+		&i.Status,
+	)
+	return i, err
+}
+
+const getTicketByHash = `-- name: GetTicketByHash :one
+SELECT
+    id, event_id, name, description, price, benefits, status, created_at, updated_at, hash, min, max, currency, ticket_type_id
+FROM ticket
+WHERE hash = $1
+`
+
+func (q *Queries) GetTicketByHash(ctx context.Context, hash pgtype.Text) (Ticket, error) {
+	row := q.db.QueryRow(ctx, getTicketByHash, hash)
+	var i Ticket
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.Benefits,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Hash,
+		&i.Min,
+		&i.Max,
+		&i.Currency,
+		&i.TicketTypeID,
+	)
+	return i, err
+}
+
+const getTicketType = `-- name: GetTicketType :one
+SELECT
+    tt.id, tt.event_id, tt.name, tt.description, tt.price, tt.currency, tt.benefits, tt.min, tt.max, tt.quantity, tt.created_at, tt.updated_at, tt.inventory_mode, tt.reserved_count, tt.sold_count, tt.sales_start, tt.sales_end, tt.visibility,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.quantity - tt.reserved_count - tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status = 'available')
+    END::BIGINT AS available,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status = 'sold')
+    END::BIGINT AS sold,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.reserved_count + tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status != 'available')
+    END::BIGINT AS claimed
+FROM ticket_type tt
+LEFT JOIN ticket t ON t.ticket_type_id = tt.id AND tt.inventory_mode = 'rows'
+WHERE tt.id = $1
+GROUP BY tt.id
+`
+
+type GetTicketTypeRow struct {
+	ID            pgtype.UUID
+	EventID       pgtype.UUID
+	Name          string
+	Description   string
+	Price         int64
+	Currency      string
+	Benefits      []byte
+	Min           int32
+	Max           int32
+	Quantity      int32
+	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+	InventoryMode InventoryMode
+	ReservedCount int32
+	SoldCount     int32
+	SalesStart    pgtype.Timestamptz
+	SalesEnd      pgtype.Timestamptz
+	Visibility    TicketVisibility
+	Available     int64
+	Sold          int64
+	Claimed       int64
+}
+
+func (q *Queries) GetTicketType(ctx context.Context, id pgtype.UUID) (GetTicketTypeRow, error) {
+	row := q.db.QueryRow(ctx, getTicketType, id)
+	var i GetTicketTypeRow
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.Currency,
+		&i.Benefits,
+		&i.Min,
+		&i.Max,
+		&i.Quantity,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InventoryMode,
+		&i.ReservedCount,
+		&i.SoldCount,
+		&i.SalesStart,
+		&i.SalesEnd,
+		&i.Visibility,
+		&i.Available,
+		&i.Sold,
+		&i.Claimed,
+	)
+	return i, err
+}
+
+const getTicketTypeByName = `-- name: GetTicketTypeByName :one
+SELECT
+    id, event_id, name, description, price, currency, benefits, min, max, quantity, created_at, updated_at, inventory_mode, reserved_count, sold_count, sales_start, sales_end, visibility
+FROM ticket_type
+WHERE event_id = $1 AND name = $2
+`
+
+type GetTicketTypeByNameParams struct {
+	EventID pgtype.UUID
+	Name    string
+}
+
+func (q *Queries) GetTicketTypeByName(ctx context.Context, arg GetTicketTypeByNameParams) (TicketType, error) {
+	row := q.db.QueryRow(ctx, getTicketTypeByName, arg.EventID, arg.Name)
+	var i TicketType
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.Currency,
+		&i.Benefits,
+		&i.Min,
+		&i.Max,
+		&i.Quantity,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InventoryMode,
+		&i.ReservedCount,
+		&i.SoldCount,
+		&i.SalesStart,
+		&i.SalesEnd,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getTicketTypeByNameForUpdate = `-- name: GetTicketTypeByNameForUpdate :one
+SELECT
+    id, event_id, name, description, price, currency, benefits, min, max, quantity, created_at, updated_at, inventory_mode, reserved_count, sold_count, sales_start, sales_end, visibility
+FROM ticket_type
+WHERE event_id = $1 AND name = $2
+FOR UPDATE
+`
+
+type GetTicketTypeByNameForUpdateParams struct {
+	EventID pgtype.UUID
+	Name    string
+}
+
+func (q *Queries) GetTicketTypeByNameForUpdate(ctx context.Context, arg GetTicketTypeByNameForUpdateParams) (TicketType, error) {
+	row := q.db.QueryRow(ctx, getTicketTypeByNameForUpdate, arg.EventID, arg.Name)
+	var i TicketType
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.Currency,
+		&i.Benefits,
+		&i.Min,
+		&i.Max,
+		&i.Quantity,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InventoryMode,
+		&i.ReservedCount,
+		&i.SoldCount,
+		&i.SalesStart,
+		&i.SalesEnd,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getTicketTypeForUpdate = `-- name: GetTicketTypeForUpdate :one
+SELECT
+    id, event_id, name, description, price, currency, benefits, min, max, quantity, created_at, updated_at, inventory_mode, reserved_count, sold_count, sales_start, sales_end, visibility
+FROM ticket_type
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetTicketTypeForUpdate(ctx context.Context, id pgtype.UUID) (TicketType, error) {
+	row := q.db.QueryRow(ctx, getTicketTypeForUpdate, id)
+	var i TicketType
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.Currency,
+		&i.Benefits,
+		&i.Min,
+		&i.Max,
+		&i.Quantity,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InventoryMode,
+		&i.ReservedCount,
+		&i.SoldCount,
+		&i.SalesStart,
+		&i.SalesEnd,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getTicketsByIDs = `-- name: GetTicketsByIDs :many
+SELECT
+    id,
+    name,
+    price,
+    currency,
+    hash
+FROM ticket
+WHERE id = ANY($1::UUID[])
+ORDER BY created_at
+`
+
+type GetTicketsByIDsRow struct {
+	ID       pgtype.UUID
+	Name     string
+	Price    int64
+	Currency string
+	Hash     pgtype.Text
+}
+
+func (q *Queries) GetTicketsByIDs(ctx context.Context, ticketIds []pgtype.UUID) ([]GetTicketsByIDsRow, error) {
+	rows, err := q.db.Query(ctx, getTicketsByIDs, ticketIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTicketsByIDsRow
+	for rows.Next() {
+		var i GetTicketsByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Price,
+			&i.Currency,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWaitlistOffer = `-- name: GetWaitlistOffer :one
+SELECT
+    we.id, we.event_id, we.ticket_type_id, we.name, we.email, we.quantity, we.state, we.claim_token, we.ticket_ids, we.offer_expires_at, we.notified_at, we.created_at, we.updated_at,
+    tt.name AS ticket_name
+FROM waitlist_entry we
+JOIN ticket_type tt ON tt.id = we.ticket_type_id
+WHERE we.claim_token = $1::TEXT
+`
+
+type GetWaitlistOfferRow struct {
+	ID             pgtype.UUID
+	EventID        pgtype.UUID
+	TicketTypeID   pgtype.UUID
+	Name           string
+	Email          string
+	Quantity       int32
+	State          WaitlistState
+	ClaimToken     pgtype.Text
+	TicketIds      []pgtype.UUID
+	OfferExpiresAt pgtype.Timestamptz
+	NotifiedAt     pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	TicketName     string
+}
+
+func (q *Queries) GetWaitlistOffer(ctx context.Context, claimToken string) (GetWaitlistOfferRow, error) {
+	row := q.db.QueryRow(ctx, getWaitlistOffer, claimToken)
+	var i GetWaitlistOfferRow
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.TicketTypeID,
+		&i.Name,
+		&i.Email,
+		&i.Quantity,
+		&i.State,
+		&i.ClaimToken,
+		&i.TicketIds,
+		&i.OfferExpiresAt,
+		&i.NotifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TicketName,
+	)
+	return i, err
+}
+
+const getWaitlistOfferForUpdate = `-- name: GetWaitlistOfferForUpdate :one
+SELECT
+    id, event_id, ticket_type_id, name, email, quantity, state, claim_token, ticket_ids, offer_expires_at, notified_at, created_at, updated_at
+FROM waitlist_entry
+WHERE claim_token = $1::TEXT
+FOR UPDATE
+`
+
+func (q *Queries) GetWaitlistOfferForUpdate(ctx context.Context, claimToken string) (WaitlistEntry, error) {
+	row := q.db.QueryRow(ctx, getWaitlistOfferForUpdate, claimToken)
+	var i WaitlistEntry
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.TicketTypeID,
+		&i.Name,
+		&i.Email,
+		&i.Quantity,
+		&i.State,
+		&i.ClaimToken,
+		&i.TicketIds,
+		&i.OfferExpiresAt,
+		&i.NotifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const hasProcessedPaymentWebhook = `-- name: HasProcessedPaymentWebhook :one
+SELECT EXISTS(
+    SELECT 1
+    FROM payment_webhook
+    WHERE provider = $1
+      AND transaction_id = $2
+      AND status = 'processed'
+      AND id != $3
+) AS processed
+`
+
+type HasProcessedPaymentWebhookParams struct {
+	Provider      string
+	TransactionID pgtype.Text
+	WebhookID     pgtype.UUID
+}
+
+func (q *Queries) HasProcessedPaymentWebhook(ctx context.Context, arg HasProcessedPaymentWebhookParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hasProcessedPaymentWebhook, arg.Provider, arg.TransactionID, arg.WebhookID)
+	var processed bool
+	err := row.Scan(&processed)
+	return processed, err
+}
+
+const insertAccessCode = `-- name: InsertAccessCode :one
+
+INSERT INTO access_code
+    (event_id, code, ticket_type_ids, max_tickets, starts_at, ends_at)
+VALUES
+    ($1, $2, $3::UUID[], $4, $5, $6)
+RETURNING id, event_id, code, ticket_type_ids, max_tickets, used_tickets, starts_at, ends_at, created_at, updated_at
+`
+
+type InsertAccessCodeParams struct {
+	EventID       pgtype.UUID
+	Code          string
+	TicketTypeIds []pgtype.UUID
+	MaxTickets    int32
+	StartsAt      pgtype.Timestamptz
+	EndsAt        pgtype.Timestamptz
+}
+
+// ###############################################################
+// Access code
+// ###############################################################
+func (q *Queries) InsertAccessCode(ctx context.Context, arg InsertAccessCodeParams) (AccessCode, error) {
+	row := q.db.QueryRow(ctx, insertAccessCode,
+		arg.EventID,
+		arg.Code,
+		arg.TicketTypeIds,
+		arg.MaxTickets,
+		arg.StartsAt,
+		arg.EndsAt,
+	)
+	var i AccessCode
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Code,
+		&i.TicketTypeIds,
+		&i.MaxTickets,
+		&i.UsedTickets,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const insertAccessCodeRedemption = `-- name: InsertAccessCodeRedemption :exec
+INSERT INTO access_code_redemption
+    (access_code_id, order_id, tickets)
+VALUES
+    ($1, $2, $3)
+`
+
+type InsertAccessCodeRedemptionParams struct {
+	AccessCodeID pgtype.UUID
+	OrderID      pgtype.UUID
+	Tickets      int32
+}
+
+func (q *Queries) InsertAccessCodeRedemption(ctx context.Context, arg InsertAccessCodeRedemptionParams) error {
+	_, err := q.db.Exec(ctx, insertAccessCodeRedemption, arg.AccessCodeID, arg.OrderID, arg.Tickets)
+	return err
+}
+
+const insertAttendee = `-- name: InsertAttendee :one
+
+INSERT INTO attendee
+    (event_id, ticket_id, data)
+VALUES
+    ($1, $2, $3)
+RETURNING id
+`
+
+type InsertAttendeeParams struct {
+	EventID  pgtype.UUID
+	TicketID pgtype.UUID
+	Data     []byte
+}
+
+// ###############################################################
+// Attendee
+// ###############################################################
+func (q *Queries) InsertAttendee(ctx context.Context, arg InsertAttendeeParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, insertAttendee, arg.EventID, arg.TicketID, arg.Data)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertEvent = `-- name: InsertEvent :one
+
+INSERT INTO event
+    (name, description, location, event_start_date, event_end_date, payment_provider,
+     fee_type, fee_value, fee_per, fee_absorbed, tax_name, tax_rate, max_free_tickets_per_email)
+VALUES
+    ($1, $2, $3, $4, $5, $6,
+     $7, $8, $9, $10, $11, $12, $13)
+RETURNING id
+`
+
+type InsertEventParams struct {
+	Name                   string
+	Description            string
+	Location               string
+	EventStartDate         pgtype.Timestamptz
+	EventEndDate           pgtype.Timestamptz
+	PaymentProvider        string
+	FeeType                FeeType
+	FeeValue               int64
+	FeePer                 FeePer
+	FeeAbsorbed            bool
+	TaxName                string
+	TaxRate                int32
+	MaxFreeTicketsPerEmail int32
+}
+
+// ###############################################################
+// Event
+// ###############################################################
+func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, insertEvent,
+		arg.Name,
+		arg.Description,
+		arg.Location,
+		arg.EventStartDate,
+		arg.EventEndDate,
+		arg.PaymentProvider,
+		arg.FeeType,
+		arg.FeeValue,
+		arg.FeePer,
+		arg.FeeAbsorbed,
+		arg.TaxName,
+		arg.TaxRate,
+		arg.MaxFreeTicketsPerEmail,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertEventTicketInput = `-- name: InsertEventTicketInput :one
+
+INSERT INTO ticket_inputs
+    (event_id, inputs)
+VALUES
+    ($1, $2)
+RETURNING id
+`
+
+type InsertEventTicketInputParams struct {
+	EventID pgtype.UUID
+	Inputs  []byte
+}
+
+// ###############################################################
+// TicketInputs
+// ###############################################################
+func (q *Queries) InsertEventTicketInput(ctx context.Context, arg InsertEventTicketInputParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, insertEventTicketInput, arg.EventID, arg.Inputs)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertOrder = `-- name: InsertOrder :one
+
+INSERT INTO orders
+    (event_id, buyer_name, buyer_email, subtotal, discount, promo_code, fee, fee_absorbed, tax, tax_name, amount, currency, payment_provider)
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+RETURNING id
+`
+
+type InsertOrderParams struct {
+	EventID         pgtype.UUID
+	BuyerName       string
+	BuyerEmail      string
+	Subtotal        int64
+	Discount        int64
+	PromoCode       pgtype.Text
+	Fee             int64
+	FeeAbsorbed     bool
+	Tax             int64
+	TaxName         string
+	Amount          int64
+	Currency        string
+	PaymentProvider string
+}
+
+// ###############################################################
+// Order
+// ###############################################################
+func (q *Queries) InsertOrder(ctx context.Context, arg InsertOrderParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, insertOrder,
+		arg.EventID,
+		arg.BuyerName,
+		arg.BuyerEmail,
+		arg.Subtotal,
+		arg.Discount,
+		arg.PromoCode,
+		arg.Fee,
+		arg.FeeAbsorbed,
+		arg.Tax,
+		arg.TaxName,
+		arg.Amount,
+		arg.Currency,
+		arg.PaymentProvider,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertOrderItem = `-- name: InsertOrderItem :one
+INSERT INTO order_item
+    (order_id, ticket_type_id, ticket_name, quantity, unit_price)
+VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING id
+`
+
+type InsertOrderItemParams struct {
+	OrderID      pgtype.UUID
+	TicketTypeID pgtype.UUID
+	TicketName   string
+	Quantity     int32
+	UnitPrice    int64
+}
+
+func (q *Queries) InsertOrderItem(ctx context.Context, arg InsertOrderItemParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, insertOrderItem,
+		arg.OrderID,
+		arg.TicketTypeID,
+		arg.TicketName,
+		arg.Quantity,
+		arg.UnitPrice,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertOrderTransition = `-- name: InsertOrderTransition :exec
+INSERT INTO order_transition
+    (order_id, from_status, to_status, reason)
+VALUES
+    ($1, $2, $3, $4)
+`
+
+type InsertOrderTransitionParams struct {
+	OrderID    pgtype.UUID
+	FromStatus NullOrderStatus
+	ToStatus   OrderStatus
+	Reason     string
+}
+
+func (q *Queries) InsertOrderTransition(ctx context.Context, arg InsertOrderTransitionParams) error {
+	_, err := q.db.Exec(ctx, insertOrderTransition,
+		arg.OrderID,
+		arg.FromStatus,
+		arg.ToStatus,
+		arg.Reason,
+	)
+	return err
+}
+
+const insertPayment = `-- name: InsertPayment :one
+
+INSERT INTO payment
+    (event_id, order_id, data, name, email, bill_link_id)
+VALUES
+    ($1, $2, $3, $4, $5, $6)
+RETURNING id
+`
+
+type InsertPaymentParams struct {
+	EventID    pgtype.UUID
+	OrderID    pgtype.UUID
+	Data       []byte
+	Name       string
+	Email      string
+	BillLinkID int32
+}
+
+// ###############################################################
+// Payment
+// ###############################################################
+func (q *Queries) InsertPayment(ctx context.Context, arg InsertPaymentParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, insertPayment,
+		arg.EventID,
+		arg.OrderID,
+		arg.Data,
+		arg.Name,
+		arg.Email,
+		arg.BillLinkID,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertPaymentMismatch = `-- name: InsertPaymentMismatch :execrows
+
+INSERT INTO payment_mismatch
+    (reservation_id, bill_link_id, kind, detail)
+VALUES
+    ($1, $2, $3, $4)
+ON CONFLICT (bill_link_id, kind) DO NOTHING
+`
+
+type InsertPaymentMismatchParams struct {
+	ReservationID pgtype.UUID
+	BillLinkID    int32
+	Kind          string
+	Detail        string
+}
+
+// ###############################################################
+// Payment mismatch
+// ###############################################################
+func (q *Queries) InsertPaymentMismatch(ctx context.Context, arg InsertPaymentMismatchParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertPaymentMismatch,
+		arg.ReservationID,
+		arg.BillLinkID,
+		arg.Kind,
+		arg.Detail,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertPaymentWebhook = `-- name: InsertPaymentWebhook :one
+
+INSERT INTO payment_webhook
+    (provider, content_type, payload)
+VALUES
+    ($1, $2, $3)
+RETURNING id
+`
+
+type InsertPaymentWebhookParams struct {
+	Provider    string
+	ContentType string
+	Payload     string
+}
+
+// ###############################################################
+// Payment webhook
+// ###############################################################
+func (q *Queries) InsertPaymentWebhook(ctx context.Context, arg InsertPaymentWebhookParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, insertPaymentWebhook, arg.Provider, arg.ContentType, arg.Payload)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertPromoCode = `-- name: InsertPromoCode :one
+
+INSERT INTO promo_code
+    (event_id, code, discount_type, value, currency, ticket_type_ids, max_uses, starts_at, ends_at)
+VALUES
+    ($1, $2, $3, $4, $5, $6::UUID[], $7, $8, $9)
+RETURNING id, event_id, code, discount_type, value, currency, ticket_type_ids, max_uses, used_count, starts_at, ends_at, created_at, updated_at
+`
+
+type InsertPromoCodeParams struct {
+	EventID       pgtype.UUID
+	Code          string
+	DiscountType  DiscountType
+	Value         int64
+	Currency      string
+	TicketTypeIds []pgtype.UUID
+	MaxUses       int32
+	StartsAt      pgtype.Timestamptz
+	EndsAt        pgtype.Timestamptz
+}
+
+// ###############################################################
+// Promo code
+// ###############################################################
+func (q *Queries) InsertPromoCode(ctx context.Context, arg InsertPromoCodeParams) (PromoCode, error) {
+	row := q.db.QueryRow(ctx, insertPromoCode,
+		arg.EventID,
+		arg.Code,
+		arg.DiscountType,
+		arg.Value,
+		arg.Currency,
+		arg.TicketTypeIds,
+		arg.MaxUses,
+		arg.StartsAt,
+		arg.EndsAt,
+	)
+	var i PromoCode
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Code,
+		&i.DiscountType,
+		&i.Value,
+		&i.Currency,
+		&i.TicketTypeIds,
+		&i.MaxUses,
+		&i.UsedCount,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const insertPromoCodeRedemption = `-- name: InsertPromoCodeRedemption :exec
+INSERT INTO promo_code_redemption
+    (promo_code_id, order_id, discount, currency)
+VALUES
+    ($1, $2, $3, $4)
+`
+
+type InsertPromoCodeRedemptionParams struct {
+	PromoCodeID pgtype.UUID
+	OrderID     pgtype.UUID
+	Discount    int64
+	Currency    string
+}
+
+func (q *Queries) InsertPromoCodeRedemption(ctx context.Context, arg InsertPromoCodeRedemptionParams) error {
+	_, err := q.db.Exec(ctx, insertPromoCodeRedemption,
+		arg.PromoCodeID,
+		arg.OrderID,
+		arg.Discount,
+		arg.Currency,
+	)
+	return err
+}
+
+const insertRefund = `-- name: InsertRefund :one
+
+INSERT INTO refund
+    (payment_id, order_id, event_id, ticket_ids, amount, currency, reason, status, provider_reference, restock)
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, payment_id, order_id, event_id, ticket_ids, amount, currency, reason, status, provider_reference, created_at, updated_at, restock
+`
+
+type InsertRefundParams struct {
+	PaymentID         pgtype.UUID
+	OrderID           pgtype.UUID
+	EventID           pgtype.UUID
+	TicketIds         []pgtype.UUID
+	Amount            int64
+	Currency          string
+	Reason            string
+	Status            string
+	ProviderReference pgtype.Text
+	Restock           bool
+}
+
+// ###############################################################
+// Refund
+// ###############################################################
+func (q *Queries) InsertRefund(ctx context.Context, arg InsertRefundParams) (Refund, error) {
+	row := q.db.QueryRow(ctx, insertRefund,
+		arg.PaymentID,
+		arg.OrderID,
+		arg.EventID,
+		arg.TicketIds,
+		arg.Amount,
+		arg.Currency,
+		arg.Reason,
+		arg.Status,
+		arg.ProviderReference,
+		arg.Restock,
+	)
+	var i Refund
+	err := row.Scan(
+		&i.ID,
+		&i.PaymentID,
+		&i.OrderID,
+		&i.EventID,
+		&i.TicketIds,
+		&i.Amount,
+		&i.Currency,
+		&i.Reason,
+		&i.Status,
+		&i.ProviderReference,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Restock,
+	)
+	return i, err
+}
+
+const insertReservation = `-- name: InsertReservation :one
+
+INSERT INTO reservation
+    (bill_link_id, event_id, order_id, ticket_ids, ticket_hashes, attendees, amount, currency, payment_provider, expired_at, idempotency_key, request_hash, response)
+VALUES
+    ($1, $2, $3, $4::uuid[], $5::text[], $6, $7, $8, $9, $10, $11, $12, $13)
+RETURNING id
+`
+
+type InsertReservationParams struct {
+	BillLinkID      int32
+	EventID         pgtype.UUID
+	OrderID         pgtype.UUID
+	TicketIds       []pgtype.UUID
+	TicketHashes    []string
+	Attendees       []byte
+	Amount          int64
+	Currency        string
+	PaymentProvider string
+	ExpiredAt       pgtype.Timestamptz
+	IdempotencyKey  pgtype.Text
+	RequestHash     pgtype.Text
+	Response        []byte
+}
+
+// ###############################################################
+// Reservation
+// ###############################################################
+func (q *Queries) InsertReservation(ctx context.Context, arg InsertReservationParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, insertReservation,
+		arg.BillLinkID,
+		arg.EventID,
+		arg.OrderID,
+		arg.TicketIds,
+		arg.TicketHashes,
+		arg.Attendees,
+		arg.Amount,
+		arg.Currency,
+		arg.PaymentProvider,
+		arg.ExpiredAt,
+		arg.IdempotencyKey,
+		arg.RequestHash,
+		arg.Response,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertTicketPricePhase = `-- name: InsertTicketPricePhase :exec
+INSERT INTO ticket_price_phase
+    (ticket_type_id, position, name, price, ends_at, sold_limit)
+VALUES
+    ($1, $2, $3, $4, $5, $6)
+`
+
+type InsertTicketPricePhaseParams struct {
+	TicketTypeID pgtype.UUID
+	Position     int32
+	Name         string
+	Price        int64
+	EndsAt       pgtype.Timestamptz
+	SoldLimit    int32
+}
+
+func (q *Queries) InsertTicketPricePhase(ctx context.Context, arg InsertTicketPricePhaseParams) error {
+	_, err := q.db.Exec(ctx, insertTicketPricePhase,
+		arg.TicketTypeID,
+		arg.Position,
+		arg.Name,
+		arg.Price,
+		arg.EndsAt,
+		arg.SoldLimit,
+	)
+	return err
+}
+
+const insertTicketType = `-- name: InsertTicketType :one
+
+INSERT INTO ticket_type
+    (event_id, name, description, price, currency, benefits, min, max, inventory_mode, sales_start, sales_end, visibility)
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING id
+`
+
+type InsertTicketTypeParams struct {
+	EventID       pgtype.UUID
+	Name          string
+	Description   string
+	Price         int64
+	Currency      string
+	Benefits      []byte
+	Min           int32
+	Max           int32
+	InventoryMode InventoryMode
+	SalesStart    pgtype.Timestamptz
+	SalesEnd      pgtype.Timestamptz
+	Visibility    TicketVisibility
+}
+
+// ###############################################################
+// TicketType
+// ###############################################################
+func (q *Queries) InsertTicketType(ctx context.Context, arg InsertTicketTypeParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, insertTicketType,
+		arg.EventID,
+		arg.Name,
+		arg.Description,
+		arg.Price,
+		arg.Currency,
+		arg.Benefits,
+		arg.Min,
+		arg.Max,
+		arg.InventoryMode,
+		arg.SalesStart,
+		arg.SalesEnd,
+		arg.Visibility,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertTypeTickets = `-- name: InsertTypeTickets :execrows
+INSERT INTO ticket
+    (event_id, ticket_type_id, name, description, price, currency, benefits, min, max, hash)
+SELECT
+    tt.event_id, tt.id, tt.name, tt.description, tt.price, tt.currency, tt.benefits, tt.min, tt.max, h.hash
+FROM ticket_type tt, unnest($1::TEXT[]) AS h(hash)
+WHERE tt.id = $2
+`
+
+type InsertTypeTicketsParams struct {
+	Hashes       []string
+	TicketTypeID pgtype.UUID
+}
+
+func (q *Queries) InsertTypeTickets(ctx context.Context, arg InsertTypeTicketsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertTypeTickets, arg.Hashes, arg.TicketTypeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertWaitlistEntry = `-- name: InsertWaitlistEntry :one
+INSERT INTO waitlist_entry
+    (event_id, ticket_type_id, name, email, quantity)
+VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING id, event_id, ticket_type_id, name, email, quantity, state, claim_token, ticket_ids, offer_expires_at, notified_at, created_at, updated_at
+`
+
+type InsertWaitlistEntryParams struct {
+	EventID      pgtype.UUID
+	TicketTypeID pgtype.UUID
+	Name         string
+	Email        string
+	Quantity     int32
+}
+
+func (q *Queries) InsertWaitlistEntry(ctx context.Context, arg InsertWaitlistEntryParams) (WaitlistEntry, error) {
+	row := q.db.QueryRow(ctx, insertWaitlistEntry,
+		arg.EventID,
+		arg.TicketTypeID,
+		arg.Name,
+		arg.Email,
+		arg.Quantity,
+	)
+	var i WaitlistEntry
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.TicketTypeID,
+		&i.Name,
+		&i.Email,
+		&i.Quantity,
+		&i.State,
+		&i.ClaimToken,
+		&i.TicketIds,
+		&i.OfferExpiresAt,
+		&i.NotifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const issueReservedTickets = `-- name: IssueReservedTickets :many
+
+INSERT INTO ticket
+    (event_id, ticket_type_id, name, description, price, currency, benefits, min, max, hash, status)
+SELECT
+    tt.event_id, tt.id, tt.name, tt.description, tt.price, tt.currency, tt.benefits, tt.min, tt.max, h.hash, 'pending'
+FROM ticket_type tt, unnest($1::TEXT[]) AS h(hash)
+WHERE tt.id = $2
+RETURNING id, name, price, currency, hash
+`
+
+type IssueReservedTicketsParams struct {
+	Hashes       []string
+	TicketTypeID pgtype.UUID
+}
+
+type IssueReservedTicketsRow struct {
+	ID       pgtype.UUID
+	Name     string
+	Price    int64
+	Currency string
+	Hash     pgtype.Text
+}
+
+// ###############################################################
+// Ticket
+// ###############################################################
+func (q *Queries) IssueReservedTickets(ctx context.Context, arg IssueReservedTicketsParams) ([]IssueReservedTicketsRow, error) {
+	rows, err := q.db.Query(ctx, issueReservedTickets, arg.Hashes, arg.TicketTypeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IssueReservedTicketsRow
+	for rows.Next() {
+		var i IssueReservedTicketsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Price,
+			&i.Currency,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccessCode = `-- name: ListAccessCode :many
+SELECT
+    id, event_id, code, ticket_type_ids, max_tickets, used_tickets, starts_at, ends_at, created_at, updated_at
+FROM access_code
+WHERE event_id = $1
+ORDER BY created_at DESC
+OFFSET $2
+LIMIT $3
+`
+
+type ListAccessCodeParams struct {
+	EventID pgtype.UUID
+	Offsets int32
+	Limits  int32
+}
+
+func (q *Queries) ListAccessCode(ctx context.Context, arg ListAccessCodeParams) ([]AccessCode, error) {
+	rows, err := q.db.Query(ctx, listAccessCode, arg.EventID, arg.Offsets, arg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AccessCode
+	for rows.Next() {
+		var i AccessCode
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.Code,
+			&i.TicketTypeIds,
+			&i.MaxTickets,
+			&i.UsedTickets,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAttendee = `-- name: ListAttendee :many
+SELECT
+    e.id,
+    e.event_id,
+    e.ticket_id,
+    e.data,
+    e.created_at,
+    e.updated_at
+FROM attendee e
+WHERE e.event_id = $1
+ORDER BY $2
+OFFSET $3
+LIMIT $4
+`
+
+type ListAttendeeParams struct {
+	EventID pgtype.UUID
+	OrderBy interface{}
+	Offsets int32
+	Limits  int32
+}
+
+type ListAttendeeRow struct {
+	ID        pgtype.UUID
+	EventID   pgtype.UUID
+	TicketID  pgtype.UUID
+	Data      []byte
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) ListAttendee(ctx context.Context, arg ListAttendeeParams) ([]ListAttendeeRow, error) {
+	rows, err := q.db.Query(ctx, listAttendee,
+		arg.EventID,
+		arg.OrderBy,
+		arg.Offsets,
+		arg.Limits,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAttendeeRow
+	for rows.Next() {
+		var i ListAttendeeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.TicketID,
+			&i.Data,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDistinctTicket = `-- name: ListDistinctTicket :many
+SELECT
+    tt.id,
+    tt.event_id,
+    tt.name,
+    tt.description,
+    tt.price,
+    tt.currency,
+    tt.benefits,
+    tt.min,
+    tt.max,
+    tt.sales_start,
+    tt.sales_end,
+    tt.visibility,
+    tt.created_at,
+    tt.updated_at,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.quantity - tt.reserved_count - tt.sold_count
+        ELSE COUNT(t.id)
+    END::BIGINT AS count,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.sold_count
+        ELSE (SELECT COUNT(*) FROM ticket c WHERE c.ticket_type_id = tt.id AND c.status = 'sold')
+    END::BIGINT AS sold
+FROM ticket_type tt
+LEFT JOIN ticket t ON t.ticket_type_id = tt.id AND tt.inventory_mode = 'rows' AND t.status = 'available'
+WHERE tt.event_id = $1 AND (tt.visibility = 'public' OR tt.id = ANY($2::UUID[]))
+GROUP BY tt.id
+HAVING CASE tt.inventory_mode
+    WHEN 'counter' THEN tt.quantity - tt.reserved_count - tt.sold_count
+    ELSE COUNT(t.id)
+END > 0
+ORDER BY tt.name
+`
+
+type ListDistinctTicketParams struct {
+	EventID               pgtype.UUID
+	UnlockedTicketTypeIds []pgtype.UUID
+}
+
+type ListDistinctTicketRow struct {
+	ID          pgtype.UUID
+	EventID     pgtype.UUID
+	Name        string
+	Description string
+	Price       int64
+	Currency    string
+	Benefits    []byte
+	Min         int32
+	Max         int32
+	SalesStart  pgtype.Timestamptz
+	SalesEnd    pgtype.Timestamptz
+	Visibility  TicketVisibility
+	CreatedAt   pgtype.Timestamptz
+	UpdatedAt   pgtype.Timestamptz
+	Count       int64
+	Sold        int64
+}
+
+func (q *Queries) ListDistinctTicket(ctx context.Context, arg ListDistinctTicketParams) ([]ListDistinctTicketRow, error) {
+	rows, err := q.db.Query(ctx, listDistinctTicket, arg.EventID, arg.UnlockedTicketTypeIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDistinctTicketRow
+	for rows.Next() {
+		var i ListDistinctTicketRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.Currency,
+			&i.Benefits,
+			&i.Min,
+			&i.Max,
+			&i.SalesStart,
+			&i.SalesEnd,
+			&i.Visibility,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Count,
+			&i.Sold,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEvent = `-- name: ListEvent :many
+SELECT
+    event.id,
+    event.name,
+    event.description,
+    event.location,
+    event.event_start_date,
+    event.event_end_date,
+    event.created_at,
+    event.updated_at,
+    event.payment_provider,
+    event.fee_type,
+    event.fee_value,
+    event.fee_per,
+    event.fee_absorbed,
+    event.tax_name,
+    event.tax_rate,
+    event.max_free_tickets_per_email,
+    ticket_inputs.inputs as ticket_inputs
+FROM event
+LEFT JOIN ticket_inputs ticket_inputs ON event.id = ticket_inputs.event_id
+ORDER BY $1
+OFFSET $2
+LIMIT $3
+`
+
+type ListEventParams struct {
+	OrderBy interface{}
+	Offsets int32
+	Limits  int32
+}
+
+type ListEventRow struct {
+	ID                     pgtype.UUID
+	Name                   string
+	Description            string
+	Location               string
+	EventStartDate         pgtype.Timestamptz
+	EventEndDate           pgtype.Timestamptz
+	CreatedAt              pgtype.Timestamptz
+	UpdatedAt              pgtype.Timestamptz
+	PaymentProvider        string
+	FeeType                FeeType
+	FeeValue               int64
+	FeePer                 FeePer
+	FeeAbsorbed            bool
+	TaxName                string
+	TaxRate                int32
+	MaxFreeTicketsPerEmail int32
+	TicketInputs           []byte
+}
+
+func (q *Queries) ListEvent(ctx context.Context, arg ListEventParams) ([]ListEventRow, error) {
+	rows, err := q.db.Query(ctx, listEvent, arg.OrderBy, arg.Offsets, arg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEventRow
+	for rows.Next() {
+		var i ListEventRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Location,
+			&i.EventStartDate,
+			&i.EventEndDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PaymentProvider,
+			&i.FeeType,
+			&i.FeeValue,
+			&i.FeePer,
+			&i.FeeAbsorbed,
+			&i.TaxName,
+			&i.TaxRate,
+			&i.MaxFreeTicketsPerEmail,
+			&i.TicketInputs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEventTicketPricePhase = `-- name: ListEventTicketPricePhase :many
+SELECT
+    p.id, p.ticket_type_id, p.position, p.name, p.price, p.ends_at, p.sold_limit, p.created_at, p.updated_at
+FROM ticket_price_phase p
+JOIN ticket_type tt ON tt.id = p.ticket_type_id
+WHERE tt.event_id = $1
+ORDER BY p.ticket_type_id, p.position
+`
+
+func (q *Queries) ListEventTicketPricePhase(ctx context.Context, eventID pgtype.UUID) ([]TicketPricePhase, error) {
+	rows, err := q.db.Query(ctx, listEventTicketPricePhase, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TicketPricePhase
+	for rows.Next() {
+		var i TicketPricePhase
+		if err := rows.Scan(
+			&i.ID,
+			&i.TicketTypeID,
+			&i.Position,
+			&i.Name,
+			&i.Price,
+			&i.EndsAt,
+			&i.SoldLimit,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredReservationsForUpdate = `-- name: ListExpiredReservationsForUpdate :many
+SELECT
+    id, bill_link_id, event_id, ticket_ids, ticket_hashes, attendees, state, expired_at, created_at, updated_at, release_reason, amount, payment_provider, order_id, currency, reconciled_at, idempotency_key, request_hash, response
+FROM reservation
+WHERE state = 'pending' AND expired_at < now()
+ORDER BY expired_at
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) ListExpiredReservationsForUpdate(ctx context.Context, limits int32) ([]Reservation, error) {
+	rows, err := q.db.Query(ctx, listExpiredReservationsForUpdate, limits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Reservation
+	for rows.Next() {
+		var i Reservation
+		if err := rows.Scan(
+			&i.ID,
+			&i.BillLinkID,
+			&i.EventID,
+			&i.TicketIds,
+			&i.TicketHashes,
+			&i.Attendees,
+			&i.State,
+			&i.ExpiredAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReleaseReason,
+			&i.Amount,
+			&i.PaymentProvider,
+			&i.OrderID,
+			&i.Currency,
+			&i.ReconciledAt,
+			&i.IdempotencyKey,
+			&i.RequestHash,
+			&i.Response,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredWaitlistOffersForUpdate = `-- name: ListExpiredWaitlistOffersForUpdate :many
+SELECT
+    id, event_id, ticket_type_id, name, email, quantity, state, claim_token, ticket_ids, offer_expires_at, notified_at, created_at, updated_at
+FROM waitlist_entry
+WHERE state = 'offered' AND offer_expires_at < now()
+ORDER BY offer_expires_at
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) ListExpiredWaitlistOffersForUpdate(ctx context.Context, limits int32) ([]WaitlistEntry, error) {
+	rows, err := q.db.Query(ctx, listExpiredWaitlistOffersForUpdate, limits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WaitlistEntry
+	for rows.Next() {
+		var i WaitlistEntry
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.TicketTypeID,
+			&i.Name,
+			&i.Email,
+			&i.Quantity,
+			&i.State,
+			&i.ClaimToken,
+			&i.TicketIds,
+			&i.OfferExpiresAt,
+			&i.NotifiedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrder = `-- name: ListOrder :many
+SELECT
+    id, event_id, buyer_name, buyer_email, amount, payment_provider, provider_reference, status, created_at, updated_at, currency, subtotal, fee, fee_absorbed, tax, tax_name, discount, promo_code, idempotency_key, request_hash, response
+FROM orders
+WHERE event_id = $1
+ORDER BY $2
+OFFSET $3
+LIMIT $4
+`
+
+type ListOrderParams struct {
+	EventID pgtype.UUID
+	OrderBy interface{}
+	Offsets int32
+	Limits  int32
+}
+
+func (q *Queries) ListOrder(ctx context.Context, arg ListOrderParams) ([]Order, error) {
+	rows, err := q.db.Query(ctx, listOrder,
+		arg.EventID,
+		arg.OrderBy,
+		arg.Offsets,
+		arg.Limits,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Order
+	for rows.Next() {
+		var i Order
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.BuyerName,
+			&i.BuyerEmail,
+			&i.Amount,
+			&i.PaymentProvider,
+			&i.ProviderReference,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Currency,
+			&i.Subtotal,
+			&i.Fee,
+			&i.FeeAbsorbed,
+			&i.Tax,
+			&i.TaxName,
+			&i.Discount,
+			&i.PromoCode,
+			&i.IdempotencyKey,
+			&i.RequestHash,
+			&i.Response,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderItem = `-- name: ListOrderItem :many
+SELECT
+    id, order_id, ticket_name, quantity, unit_price, created_at, ticket_type_id
+FROM order_item
+WHERE order_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListOrderItem(ctx context.Context, orderID pgtype.UUID) ([]OrderItem, error) {
+	rows, err := q.db.Query(ctx, listOrderItem, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OrderItem
+	for rows.Next() {
+		var i OrderItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.TicketName,
+			&i.Quantity,
+			&i.UnitPrice,
+			&i.CreatedAt,
+			&i.TicketTypeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderRefund = `-- name: ListOrderRefund :many
+SELECT
+    id, payment_id, order_id, event_id, ticket_ids, amount, currency, reason, status, provider_reference, created_at, updated_at, restock
+FROM refund
+WHERE order_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListOrderRefund(ctx context.Context, orderID pgtype.UUID) ([]Refund, error) {
+	rows, err := q.db.Query(ctx, listOrderRefund, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Refund
+	for rows.Next() {
+		var i Refund
+		if err := rows.Scan(
+			&i.ID,
+			&i.PaymentID,
+			&i.OrderID,
+			&i.EventID,
+			&i.TicketIds,
+			&i.Amount,
+			&i.Currency,
+			&i.Reason,
+			&i.Status,
+			&i.ProviderReference,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Restock,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderTransition = `-- name: ListOrderTransition :many
+SELECT
+    id, order_id, from_status, to_status, reason, created_at
+FROM order_transition
+WHERE order_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListOrderTransition(ctx context.Context, orderID pgtype.UUID) ([]OrderTransition, error) {
+	rows, err := q.db.Query(ctx, listOrderTransition, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OrderTransition
+	for rows.Next() {
+		var i OrderTransition
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.FromStatus,
+			&i.ToStatus,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
 
-INSERT INTO payment
-    (event_id, data, name, email, bill_link_id)
-VALUES
-    ($1, $2, $3, $4, $5)
-RETURNING id
+const listPayment = `-- name: ListPayment :many
+SELECT
+    id, event_id, order_id, name, email, bill_link_id, data, amount, currency, status, created_at, updated_at
+FROM payment_summary
+WHERE event_id = $1
+  AND ($2::TIMESTAMPTZ IS NULL OR created_at >= $2::TIMESTAMPTZ)
+  AND ($3::TIMESTAMPTZ IS NULL OR created_at < $3::TIMESTAMPTZ)
+  AND ($4::TEXT IS NULL OR email ILIKE $4::TEXT)
+  AND ($5::TEXT IS NULL OR status = $5::TEXT)
+ORDER BY $6, created_at DESC, id
+OFFSET $7
+LIMIT $8
 `
 
-type InsertPaymentParams struct {
-	EventID    pgtype.UUID
-	Data       []byte
-	Name       string
-	Email      string
-	BillLinkID int32
+type ListPaymentParams struct {
+	EventID     pgtype.UUID
+	CreatedFrom pgtype.Timestamptz
+	CreatedTo   pgtype.Timestamptz
+	Email       pgtype.Text
+	Status      pgtype.Text
+	OrderBy     interface{}
+	Offsets     int32
+	Limits      int32
 }
 
-// ###############################################################
-// Payment
-// ###############################################################
-func (q *Queries) InsertPayment(ctx context.Context, arg InsertPaymentParams) (pgtype.UUID, error) {
-	row := q.db.QueryRow(ctx, insertPayment,
+func (q *Queries) ListPayment(ctx context.Context, arg ListPaymentParams) ([]PaymentSummary, error) {
+	rows, err := q.db.Query(ctx, listPayment,
 		arg.EventID,
-		arg.Data,
-		arg.Name,
+		arg.CreatedFrom,
+		arg.CreatedTo,
 		arg.Email,
-		arg.BillLinkID,
+		arg.Status,
+		arg.OrderBy,
+		arg.Offsets,
+		arg.Limits,
 	)
-	var id pgtype.UUID
-	err := row.Scan(&id)
-	return id, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaymentSummary
+	for rows.Next() {
+		var i PaymentSummary
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.OrderID,
+			&i.Name,
+			&i.Email,
+			&i.BillLinkID,
+			&i.Data,
+			&i.Amount,
+			&i.Currency,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const insertTicket = `-- name: InsertTicket :one
+const listPaymentMismatch = `-- name: ListPaymentMismatch :many
+SELECT
+    id, reservation_id, bill_link_id, kind, detail, created_at
+FROM payment_mismatch
+ORDER BY $1
+OFFSET $2
+LIMIT $3
+`
 
-INSERT INTO ticket
-    (event_id, name, description, price, benefits, hash, min, max)
-VALUES
-    ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id
+type ListPaymentMismatchParams struct {
+	OrderBy interface{}
+	Offsets int32
+	Limits  int32
+}
+
+func (q *Queries) ListPaymentMismatch(ctx context.Context, arg ListPaymentMismatchParams) ([]PaymentMismatch, error) {
+	rows, err := q.db.Query(ctx, listPaymentMismatch, arg.OrderBy, arg.Offsets, arg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaymentMismatch
+	for rows.Next() {
+		var i PaymentMismatch
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReservationID,
+			&i.BillLinkID,
+			&i.Kind,
+			&i.Detail,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentWebhook = `-- name: ListPaymentWebhook :many
+SELECT
+    id, provider, content_type, payload, signature_valid, transaction_id, bill_link_id, status, error, attempts, processed_at, created_at, updated_at
+FROM payment_webhook
+WHERE $1::TEXT IS NULL OR status::TEXT = $1::TEXT
+ORDER BY $2
+OFFSET $3
+LIMIT $4
 `
 
-type InsertTicketParams struct {
-	EventID     pgtype.UUID
-	Name        string
-	Description string
-	Price       string
-	Benefits    []byte
-	Hash        pgtype.Text
-	Min         pgtype.Int4
-	Max         pgtype.Int4
+type ListPaymentWebhookParams struct {
+	Status  pgtype.Text
+	OrderBy interface{}
+	Offsets int32
+	Limits  int32
 }
 
-// ###############################################################
-// Ticket
-// ###############################################################
-func (q *Queries) InsertTicket(ctx context.Context, arg InsertTicketParams) (pgtype.UUID, error) {
-	row := q.db.QueryRow(ctx, insertTicket,
-		arg.EventID,
-		arg.Name,
-		arg.Description,
-		arg.Price,
-		arg.Benefits,
-		arg.Hash,
-		arg.Min,
-		arg.Max,
+func (q *Queries) ListPaymentWebhook(ctx context.Context, arg ListPaymentWebhookParams) ([]PaymentWebhook, error) {
+	rows, err := q.db.Query(ctx, listPaymentWebhook,
+		arg.Status,
+		arg.OrderBy,
+		arg.Offsets,
+		arg.Limits,
 	)
-	var id pgtype.UUID
-	err := row.Scan(&id)
-	return id, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaymentWebhook
+	for rows.Next() {
+		var i PaymentWebhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.Provider,
+			&i.ContentType,
+			&i.Payload,
+			&i.SignatureValid,
+			&i.TransactionID,
+			&i.BillLinkID,
+			&i.Status,
+			&i.Error,
+			&i.Attempts,
+			&i.ProcessedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPromoCode = `-- name: ListPromoCode :many
+SELECT
+    id, event_id, code, discount_type, value, currency, ticket_type_ids, max_uses, used_count, starts_at, ends_at, created_at, updated_at
+FROM promo_code
+WHERE event_id = $1
+ORDER BY created_at DESC
+OFFSET $2
+LIMIT $3
+`
+
+type ListPromoCodeParams struct {
+	EventID pgtype.UUID
+	Offsets int32
+	Limits  int32
+}
+
+func (q *Queries) ListPromoCode(ctx context.Context, arg ListPromoCodeParams) ([]PromoCode, error) {
+	rows, err := q.db.Query(ctx, listPromoCode, arg.EventID, arg.Offsets, arg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PromoCode
+	for rows.Next() {
+		var i PromoCode
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.Code,
+			&i.DiscountType,
+			&i.Value,
+			&i.Currency,
+			&i.TicketTypeIds,
+			&i.MaxUses,
+			&i.UsedCount,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReservationsToReconcile = `-- name: ListReservationsToReconcile :many
+SELECT
+    id, bill_link_id, event_id, ticket_ids, ticket_hashes, attendees, state, expired_at, created_at, updated_at, release_reason, amount, payment_provider, order_id, currency, reconciled_at, idempotency_key, request_hash, response
+FROM reservation
+WHERE (state = 'pending' AND created_at < $1)
+   OR (state != 'pending' AND reconciled_at IS NULL AND updated_at > $2)
+ORDER BY created_at
+LIMIT $3
+`
+
+type ListReservationsToReconcileParams struct {
+	PendingBefore pgtype.Timestamptz
+	SettledAfter  pgtype.Timestamptz
+	Limits        int32
+}
+
+func (q *Queries) ListReservationsToReconcile(ctx context.Context, arg ListReservationsToReconcileParams) ([]Reservation, error) {
+	rows, err := q.db.Query(ctx, listReservationsToReconcile, arg.PendingBefore, arg.SettledAfter, arg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Reservation
+	for rows.Next() {
+		var i Reservation
+		if err := rows.Scan(
+			&i.ID,
+			&i.BillLinkID,
+			&i.EventID,
+			&i.TicketIds,
+			&i.TicketHashes,
+			&i.Attendees,
+			&i.State,
+			&i.ExpiredAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReleaseReason,
+			&i.Amount,
+			&i.PaymentProvider,
+			&i.OrderID,
+			&i.Currency,
+			&i.ReconciledAt,
+			&i.IdempotencyKey,
+			&i.RequestHash,
+			&i.Response,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketAttendees = `-- name: ListTicketAttendees :many
+SELECT
+    id, event_id, ticket_id, data, status, created_at, updated_at
+FROM attendee
+WHERE ticket_id = ANY($1::UUID[])
+ORDER BY created_at
+`
+
+func (q *Queries) ListTicketAttendees(ctx context.Context, ticketIds []pgtype.UUID) ([]Attendee, error) {
+	rows, err := q.db.Query(ctx, listTicketAttendees, ticketIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attendee
+	for rows.Next() {
+		var i Attendee
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.TicketID,
+			&i.Data,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTicketPricePhase = `-- name: ListTicketPricePhase :many
+SELECT
+    id, ticket_type_id, position, name, price, ends_at, sold_limit, created_at, updated_at
+FROM ticket_price_phase
+WHERE ticket_type_id = $1
+ORDER BY position
+`
+
+func (q *Queries) ListTicketPricePhase(ctx context.Context, ticketTypeID pgtype.UUID) ([]TicketPricePhase, error) {
+	rows, err := q.db.Query(ctx, listTicketPricePhase, ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TicketPricePhase
+	for rows.Next() {
+		var i TicketPricePhase
+		if err := rows.Scan(
+			&i.ID,
+			&i.TicketTypeID,
+			&i.Position,
+			&i.Name,
+			&i.Price,
+			&i.EndsAt,
+			&i.SoldLimit,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const listAttendee = `-- name: ListAttendee :many
+const listTicketType = `-- name: ListTicketType :many
 SELECT
-    e.id,
-    e.event_id,
-    e.ticket_id,
-    e.data,
-    e.created_at,
-    e.updated_at
-FROM attendee e
-WHERE e.event_id = $1
-ORDER BY $2
-OFFSET $3
-LIMIT $4
+    tt.id, tt.event_id, tt.name, tt.description, tt.price, tt.currency, tt.benefits, tt.min, tt.max, tt.quantity, tt.created_at, tt.updated_at, tt.inventory_mode, tt.reserved_count, tt.sold_count, tt.sales_start, tt.sales_end, tt.visibility,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.quantity - tt.reserved_count - tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status = 'available')
+    END::BIGINT AS available,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status = 'sold')
+    END::BIGINT AS sold,
+    CASE tt.inventory_mode
+        WHEN 'counter' THEN tt.reserved_count + tt.sold_count
+        ELSE COUNT(t.id) FILTER (WHERE t.status != 'available')
+    END::BIGINT AS claimed
+FROM ticket_type tt
+LEFT JOIN ticket t ON t.ticket_type_id = tt.id AND tt.inventory_mode = 'rows'
+WHERE tt.event_id = $1
+GROUP BY tt.id
+ORDER BY tt.created_at
 `
 
-type ListAttendeeParams struct {
-	EventID pgtype.UUID
-	OrderBy interface{}
-	Offsets int32
-	Limits  int32
-}
-
-type ListAttendeeRow struct {
-	ID        pgtype.UUID
-	EventID   pgtype.UUID
-	TicketID  pgtype.UUID
-	Data      []byte
-	CreatedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
+type ListTicketTypeRow struct {
+	ID            pgtype.UUID
+	EventID       pgtype.UUID
+	Name          string
+	Description   string
+	Price         int64
+	Currency      string
+	Benefits      []byte
+	Min           int32
+	Max           int32
+	Quantity      int32
+	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+	InventoryMode InventoryMode
+	ReservedCount int32
+	SoldCount     int32
+	SalesStart    pgtype.Timestamptz
+	SalesEnd      pgtype.Timestamptz
+	Visibility    TicketVisibility
+	Available     int64
+	Sold          int64
+	Claimed       int64
 }
 
-func (q *Queries) ListAttendee(ctx context.Context, arg ListAttendeeParams) ([]ListAttendeeRow, error) {
-	rows, err := q.db.Query(ctx, listAttendee,
-		arg.EventID,
-		arg.OrderBy,
-		arg.Offsets,
-		arg.Limits,
-	)
+func (q *Queries) ListTicketType(ctx context.Context, eventID pgtype.UUID) ([]ListTicketTypeRow, error) {
+	rows, err := q.db.Query(ctx, listTicketType, eventID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListAttendeeRow
+	var items []ListTicketTypeRow
 	for rows.Next() {
-		var i ListAttendeeRow
+		var i ListTicketTypeRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.EventID,
-			&i.TicketID,
-			&i.Data,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.Currency,
+			&i.Benefits,
+			&i.Min,
+			&i.Max,
+			&i.Quantity,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.InventoryMode,
+			&i.ReservedCount,
+			&i.SoldCount,
+			&i.SalesStart,
+			&i.SalesEnd,
+			&i.Visibility,
+			&i.Available,
+			&i.Sold,
+			&i.Claimed,
 		); err != nil {
 			return nil, err
 		}
@@ -461,59 +3103,64 @@ func (q *Queries) ListAttendee(ctx context.Context, arg ListAttendeeParams) ([]L
 	return items, nil
 }
 
-const listDistinctTicket = `-- name: ListDistinctTicket :many
-SELECT DISTINCT ON (name)
-    event_id,
-    name,
-    description,
-    price,
-    benefits,
-    status,
-    min,
-    max,
-    created_at,
-    updated_at,
-    COUNT(*) OVER (PARTITION BY name)
+const listTicketTypeIDsOfTickets = `-- name: ListTicketTypeIDsOfTickets :many
+SELECT DISTINCT ticket_type_id
 FROM ticket
-WHERE event_id = $1 AND status = 'available'
-ORDER BY name, created_at DESC
+WHERE id = ANY($1::UUID[])
 `
 
-type ListDistinctTicketRow struct {
-	EventID     pgtype.UUID
-	Name        string
-	Description string
-	Price       string
-	Benefits    []byte
-	Status      TicketStatus
-	Min         pgtype.Int4
-	Max         pgtype.Int4
-	CreatedAt   pgtype.Timestamptz
-	UpdatedAt   pgtype.Timestamptz
-	Count       int64
+func (q *Queries) ListTicketTypeIDsOfTickets(ctx context.Context, ticketIds []pgtype.UUID) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listTicketTypeIDsOfTickets, ticketIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var ticket_type_id pgtype.UUID
+		if err := rows.Scan(&ticket_type_id); err != nil {
+			return nil, err
+		}
+		items = append(items, ticket_type_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) ListDistinctTicket(ctx context.Context, eventID pgtype.UUID) ([]ListDistinctTicketRow, error) {
-	rows, err := q.db.Query(ctx, listDistinctTicket, eventID)
+const listTicketsByID = `-- name: ListTicketsByID :many
+SELECT
+    id, event_id, name, description, price, benefits, status, created_at, updated_at, hash, min, max, currency, ticket_type_id
+FROM ticket
+WHERE id = ANY($1::UUID[])
+ORDER BY created_at
+`
+
+func (q *Queries) ListTicketsByID(ctx context.Context, ticketIds []pgtype.UUID) ([]Ticket, error) {
+	rows, err := q.db.Query(ctx, listTicketsByID, ticketIds)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListDistinctTicketRow
+	var items []Ticket
 	for rows.Next() {
-		var i ListDistinctTicketRow
+		var i Ticket
 		if err := rows.Scan(
+			&i.ID,
 			&i.EventID,
 			&i.Name,
 			&i.Description,
 			&i.Price,
 			&i.Benefits,
 			&i.Status,
-			&i.Min,
-			&i.Max,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.Count,
+			&i.Hash,
+			&i.Min,
+			&i.Max,
+			&i.Currency,
+			&i.TicketTypeID,
 		); err != nil {
 			return nil, err
 		}
@@ -525,31 +3172,78 @@ func (q *Queries) ListDistinctTicket(ctx context.Context, eventID pgtype.UUID) (
 	return items, nil
 }
 
-const listEvent = `-- name: ListEvent :many
+const listUnnotifiedWaitlistOffersForUpdate = `-- name: ListUnnotifiedWaitlistOffersForUpdate :many
 SELECT
-    event.id,
-    event.name,
-    event.description,
-    event.location,
-    event.event_start_date,
-    event.event_end_date,
-    event.created_at,
-    event.updated_at,
-    ticket_inputs.inputs as ticket_inputs
-FROM event
-LEFT JOIN ticket_inputs ticket_inputs ON event.id = ticket_inputs.event_id
-ORDER BY $1
-OFFSET $2
-LIMIT $3
+    we.id, we.event_id, we.ticket_type_id, we.name, we.email, we.quantity, we.state, we.claim_token, we.ticket_ids, we.offer_expires_at, we.notified_at, we.created_at, we.updated_at,
+    tt.name AS ticket_name
+FROM waitlist_entry we
+JOIN ticket_type tt ON tt.id = we.ticket_type_id
+WHERE we.state = 'offered' AND we.notified_at IS NULL
+ORDER BY we.offer_expires_at
+LIMIT $1
+FOR UPDATE OF we SKIP LOCKED
 `
 
-type ListEventParams struct {
-	OrderBy interface{}
-	Offsets int32
-	Limits  int32
+type ListUnnotifiedWaitlistOffersForUpdateRow struct {
+	ID             pgtype.UUID
+	EventID        pgtype.UUID
+	TicketTypeID   pgtype.UUID
+	Name           string
+	Email          string
+	Quantity       int32
+	State          WaitlistState
+	ClaimToken     pgtype.Text
+	TicketIds      []pgtype.UUID
+	OfferExpiresAt pgtype.Timestamptz
+	NotifiedAt     pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	TicketName     string
 }
 
-type ListEventRow struct {
+func (q *Queries) ListUnnotifiedWaitlistOffersForUpdate(ctx context.Context, limits int32) ([]ListUnnotifiedWaitlistOffersForUpdateRow, error) {
+	rows, err := q.db.Query(ctx, listUnnotifiedWaitlistOffersForUpdate, limits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnnotifiedWaitlistOffersForUpdateRow
+	for rows.Next() {
+		var i ListUnnotifiedWaitlistOffersForUpdateRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.TicketTypeID,
+			&i.Name,
+			&i.Email,
+			&i.Quantity,
+			&i.State,
+			&i.ClaimToken,
+			&i.TicketIds,
+			&i.OfferExpiresAt,
+			&i.NotifiedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TicketName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUpcomingEvent = `-- name: ListUpcomingEvent :many
+SELECT id, name, description, location, event_start_date, event_end_date, created_at, updated_at
+FROM event
+WHERE NOW() < event_start_date::date
+ORDER BY event_start_date ASC
+`
+
+type ListUpcomingEventRow struct {
 	ID             pgtype.UUID
 	Name           string
 	Description    string
@@ -558,18 +3252,17 @@ type ListEventRow struct {
 	EventEndDate   pgtype.Timestamptz
 	CreatedAt      pgtype.Timestamptz
 	UpdatedAt      pgtype.Timestamptz
-	TicketInputs   []byte
 }
 
-func (q *Queries) ListEvent(ctx context.Context, arg ListEventParams) ([]ListEventRow, error) {
-	rows, err := q.db.Query(ctx, listEvent, arg.OrderBy, arg.Offsets, arg.Limits)
+func (q *Queries) ListUpcomingEvent(ctx context.Context) ([]ListUpcomingEventRow, error) {
+	rows, err := q.db.Query(ctx, listUpcomingEvent)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListEventRow
+	var items []ListUpcomingEventRow
 	for rows.Next() {
-		var i ListEventRow
+		var i ListUpcomingEventRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
@@ -579,7 +3272,6 @@ func (q *Queries) ListEvent(ctx context.Context, arg ListEventParams) ([]ListEve
 			&i.EventEndDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.TicketInputs,
 		); err != nil {
 			return nil, err
 		}
@@ -591,91 +3283,443 @@ func (q *Queries) ListEvent(ctx context.Context, arg ListEventParams) ([]ListEve
 	return items, nil
 }
 
-const listPayment = `-- name: ListPayment :many
-SELECT
-    e.id,
-    e.event_id,
-    e.data,
-    e.name,
-    e.email,
-    e.bill_link_id,
-    e.created_at,
-    e.updated_at
-FROM payment e
-ORDER BY $1
-OFFSET $2
-LIMIT $3
+const listWaitlistEntry = `-- name: ListWaitlistEntry :many
+SELECT
+    id, event_id, ticket_type_id, name, email, quantity, state, claim_token, ticket_ids, offer_expires_at, notified_at, created_at, updated_at
+FROM waitlist_entry
+WHERE ticket_type_id = $1
+ORDER BY created_at
+OFFSET $2
+LIMIT $3
+`
+
+type ListWaitlistEntryParams struct {
+	TicketTypeID pgtype.UUID
+	Offsets      int32
+	Limits       int32
+}
+
+func (q *Queries) ListWaitlistEntry(ctx context.Context, arg ListWaitlistEntryParams) ([]WaitlistEntry, error) {
+	rows, err := q.db.Query(ctx, listWaitlistEntry, arg.TicketTypeID, arg.Offsets, arg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WaitlistEntry
+	for rows.Next() {
+		var i WaitlistEntry
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.TicketTypeID,
+			&i.Name,
+			&i.Email,
+			&i.Quantity,
+			&i.State,
+			&i.ClaimToken,
+			&i.TicketIds,
+			&i.OfferExpiresAt,
+			&i.NotifiedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWaitlistedTicketTypes = `-- name: ListWaitlistedTicketTypes :many
+SELECT DISTINCT ticket_type_id
+FROM waitlist_entry
+WHERE state = 'waiting'
+`
+
+func (q *Queries) ListWaitlistedTicketTypes(ctx context.Context) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listWaitlistedTicketTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var ticket_type_id pgtype.UUID
+		if err := rows.Scan(&ticket_type_id); err != nil {
+			return nil, err
+		}
+		items = append(items, ticket_type_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockBuyerEmail = `-- name: LockBuyerEmail :exec
+SELECT pg_advisory_xact_lock(hashtextextended(CAST($1::UUID AS TEXT) || lower($2::TEXT), 0))
+`
+
+type LockBuyerEmailParams struct {
+	EventID    pgtype.UUID
+	BuyerEmail string
+}
+
+func (q *Queries) LockBuyerEmail(ctx context.Context, arg LockBuyerEmailParams) error {
+	_, err := q.db.Exec(ctx, lockBuyerEmail, arg.EventID, arg.BuyerEmail)
+	return err
+}
+
+const lockIdempotencyKey = `-- name: LockIdempotencyKey :exec
+SELECT pg_advisory_xact_lock(hashtextextended('idempotency:' || CAST($1::UUID AS TEXT) || $2::TEXT, 0))
+`
+
+type LockIdempotencyKeyParams struct {
+	EventID        pgtype.UUID
+	IdempotencyKey string
+}
+
+func (q *Queries) LockIdempotencyKey(ctx context.Context, arg LockIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, lockIdempotencyKey, arg.EventID, arg.IdempotencyKey)
+	return err
+}
+
+const makeTicketsAvailable = `-- name: MakeTicketsAvailable :exec
+UPDATE ticket t
+SET
+    status = 'available',
+    name = tt.name,
+    description = tt.description,
+    price = tt.price,
+    currency = tt.currency,
+    benefits = tt.benefits,
+    min = tt.min,
+    max = tt.max,
+    updated_at = now()
+FROM ticket_type tt
+WHERE t.id = ANY($1::UUID[]) AND tt.id = t.ticket_type_id
+`
+
+func (q *Queries) MakeTicketsAvailable(ctx context.Context, ticketIds []pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, makeTicketsAvailable, ticketIds)
+	return err
+}
+
+const markReservationReconciled = `-- name: MarkReservationReconciled :exec
+UPDATE reservation
+SET
+    reconciled_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkReservationReconciled(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markReservationReconciled, id)
+	return err
+}
+
+const markWaitlistOfferNotified = `-- name: MarkWaitlistOfferNotified :exec
+UPDATE waitlist_entry
+SET
+    notified_at = now(),
+    updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkWaitlistOfferNotified(ctx context.Context, entryID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markWaitlistOfferNotified, entryID)
+	return err
+}
+
+const offerWaitlistEntry = `-- name: OfferWaitlistEntry :exec
+UPDATE waitlist_entry
+SET
+    state = 'offered',
+    claim_token = $1::TEXT,
+    ticket_ids = $2::UUID[],
+    offer_expires_at = $3,
+    updated_at = now()
+WHERE id = $4
+`
+
+type OfferWaitlistEntryParams struct {
+	ClaimToken     string
+	TicketIds      []pgtype.UUID
+	OfferExpiresAt pgtype.Timestamptz
+	EntryID        pgtype.UUID
+}
+
+func (q *Queries) OfferWaitlistEntry(ctx context.Context, arg OfferWaitlistEntryParams) error {
+	_, err := q.db.Exec(ctx, offerWaitlistEntry,
+		arg.ClaimToken,
+		arg.TicketIds,
+		arg.OfferExpiresAt,
+		arg.EntryID,
+	)
+	return err
+}
+
+const releaseAccessCodeRedemption = `-- name: ReleaseAccessCodeRedemption :exec
+WITH released AS (
+    UPDATE access_code_redemption
+    SET released_at = now()
+    WHERE order_id = $1 AND released_at IS NULL
+    RETURNING access_code_id, tickets
+)
+UPDATE access_code ac
+SET
+    used_tickets = ac.used_tickets - released.tickets,
+    updated_at = now()
+FROM released
+WHERE ac.id = released.access_code_id
+`
+
+func (q *Queries) ReleaseAccessCodeRedemption(ctx context.Context, orderID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, releaseAccessCodeRedemption, orderID)
+	return err
+}
+
+const releaseIssuedTickets = `-- name: ReleaseIssuedTickets :exec
+WITH released AS (
+    DELETE FROM ticket t
+    USING ticket_type tt
+    WHERE t.id = ANY($1::UUID[]) AND tt.id = t.ticket_type_id AND tt.inventory_mode = 'counter'
+    RETURNING t.ticket_type_id
+)
+UPDATE ticket_type tt
+SET
+    reserved_count = tt.reserved_count - r.n,
+    updated_at = now()
+FROM (
+    SELECT ticket_type_id, COUNT(*)::INT AS n
+    FROM released
+    GROUP BY ticket_type_id
+) r
+WHERE tt.id = r.ticket_type_id
+`
+
+func (q *Queries) ReleaseIssuedTickets(ctx context.Context, ticketIds []pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, releaseIssuedTickets, ticketIds)
+	return err
+}
+
+const releasePromoCodeRedemption = `-- name: ReleasePromoCodeRedemption :exec
+WITH released AS (
+    UPDATE promo_code_redemption
+    SET released_at = now()
+    WHERE order_id = $1 AND released_at IS NULL
+    RETURNING promo_code_id
+)
+UPDATE promo_code pc
+SET
+    used_count = pc.used_count - 1,
+    updated_at = now()
+FROM released
+WHERE pc.id = released.promo_code_id
+`
+
+func (q *Queries) ReleasePromoCodeRedemption(ctx context.Context, orderID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, releasePromoCodeRedemption, orderID)
+	return err
+}
+
+const reserveTicketTypeCapacity = `-- name: ReserveTicketTypeCapacity :execrows
+UPDATE ticket_type
+SET
+    reserved_count = reserved_count + $1::INT,
+    updated_at = now()
+WHERE id = $2 AND inventory_mode = 'counter' AND quantity - reserved_count - sold_count >= $1::INT
 `
 
-type ListPaymentParams struct {
-	OrderBy interface{}
-	Offsets int32
-	Limits  int32
+type ReserveTicketTypeCapacityParams struct {
+	Amount       int32
+	TicketTypeID pgtype.UUID
 }
 
-func (q *Queries) ListPayment(ctx context.Context, arg ListPaymentParams) ([]Payment, error) {
-	rows, err := q.db.Query(ctx, listPayment, arg.OrderBy, arg.Offsets, arg.Limits)
+func (q *Queries) ReserveTicketTypeCapacity(ctx context.Context, arg ReserveTicketTypeCapacityParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reserveTicketTypeCapacity, arg.Amount, arg.TicketTypeID)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Payment
-	for rows.Next() {
-		var i Payment
-		if err := rows.Scan(
-			&i.ID,
-			&i.EventID,
-			&i.Data,
-			&i.Name,
-			&i.Email,
-			&i.BillLinkID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
-const listUpcomingEvent = `-- name: ListUpcomingEvent :many
-SELECT id, name, description, location, event_start_date, event_end_date, created_at, updated_at
-FROM event
-WHERE NOW() < event_start_date::date
-ORDER BY event_start_date ASC
+const restockTicket = `-- name: RestockTicket :exec
+UPDATE ticket t
+SET
+    status = 'available',
+    hash = $1,
+    name = tt.name,
+    description = tt.description,
+    price = tt.price,
+    currency = tt.currency,
+    benefits = tt.benefits,
+    min = tt.min,
+    max = tt.max,
+    updated_at = now()
+FROM ticket_type tt
+WHERE t.id = $2 AND tt.id = t.ticket_type_id
 `
 
-func (q *Queries) ListUpcomingEvent(ctx context.Context) ([]Event, error) {
-	rows, err := q.db.Query(ctx, listUpcomingEvent)
+type RestockTicketParams struct {
+	Hash     pgtype.Text
+	TicketID pgtype.UUID
+}
+
+func (q *Queries) RestockTicket(ctx context.Context, arg RestockTicketParams) error {
+	_, err := q.db.Exec(ctx, restockTicket, arg.Hash, arg.TicketID)
+	return err
+}
+
+const returnIssuedTicket = `-- name: ReturnIssuedTicket :execrows
+WITH returned AS (
+    UPDATE ticket t
+    SET status = 'refunded'
+    FROM ticket_type tt
+    WHERE t.id = $1 AND tt.id = t.ticket_type_id AND tt.inventory_mode = 'counter'
+    RETURNING t.ticket_type_id
+)
+UPDATE ticket_type tt
+SET
+    sold_count = tt.sold_count - 1,
+    updated_at = now()
+FROM returned
+WHERE tt.id = returned.ticket_type_id
+`
+
+func (q *Queries) ReturnIssuedTicket(ctx context.Context, ticketID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, returnIssuedTicket, ticketID)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Event
-	for rows.Next() {
-		var i Event
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.Description,
-			&i.Location,
-			&i.EventStartDate,
-			&i.EventEndDate,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected(), nil
+}
+
+const sellIssuedTickets = `-- name: SellIssuedTickets :exec
+UPDATE ticket_type tt
+SET
+    reserved_count = tt.reserved_count - issued.n,
+    sold_count = tt.sold_count + issued.n,
+    updated_at = now()
+FROM (
+    SELECT t.ticket_type_id, COUNT(*)::INT AS n
+    FROM ticket t
+    WHERE t.id = ANY($1::UUID[])
+    GROUP BY t.ticket_type_id
+) issued
+WHERE tt.id = issued.ticket_type_id AND tt.inventory_mode = 'counter'
+`
+
+func (q *Queries) SellIssuedTickets(ctx context.Context, ticketIds []pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, sellIssuedTickets, ticketIds)
+	return err
+}
+
+const setOrderIdempotencyKey = `-- name: SetOrderIdempotencyKey :exec
+UPDATE orders
+SET
+    idempotency_key = $1::TEXT,
+    request_hash = $2::TEXT,
+    response = $3,
+    updated_at = now()
+WHERE id = $4
+`
+
+type SetOrderIdempotencyKeyParams struct {
+	IdempotencyKey string
+	RequestHash    string
+	Response       []byte
+	OrderID        pgtype.UUID
+}
+
+func (q *Queries) SetOrderIdempotencyKey(ctx context.Context, arg SetOrderIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, setOrderIdempotencyKey,
+		arg.IdempotencyKey,
+		arg.RequestHash,
+		arg.Response,
+		arg.OrderID,
+	)
+	return err
+}
+
+const setTicketTypeCapacity = `-- name: SetTicketTypeCapacity :exec
+UPDATE ticket_type
+SET
+    quantity = $1,
+    updated_at = now()
+WHERE id = $2 AND inventory_mode = 'counter'
+`
+
+type SetTicketTypeCapacityParams struct {
+	Quantity     int32
+	TicketTypeID pgtype.UUID
+}
+
+func (q *Queries) SetTicketTypeCapacity(ctx context.Context, arg SetTicketTypeCapacityParams) error {
+	_, err := q.db.Exec(ctx, setTicketTypeCapacity, arg.Quantity, arg.TicketTypeID)
+	return err
+}
+
+const syncTicketTypeQuantity = `-- name: SyncTicketTypeQuantity :exec
+UPDATE ticket_type
+SET
+    quantity = (SELECT COUNT(*) FROM ticket t WHERE t.ticket_type_id = ticket_type.id),
+    updated_at = now()
+WHERE ticket_type.id = $1 AND inventory_mode = 'rows'
+`
+
+func (q *Queries) SyncTicketTypeQuantity(ctx context.Context, ticketTypeID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, syncTicketTypeQuantity, ticketTypeID)
+	return err
+}
+
+const updateAccessCode = `-- name: UpdateAccessCode :one
+UPDATE access_code
+SET
+    code = COALESCE($1, code),
+    ticket_type_ids = COALESCE($2::UUID[], ticket_type_ids),
+    max_tickets = COALESCE($3, max_tickets),
+    starts_at = COALESCE($4, starts_at),
+    ends_at = COALESCE($5, ends_at),
+    updated_at = now()
+WHERE id = $6
+RETURNING id, event_id, code, ticket_type_ids, max_tickets, used_tickets, starts_at, ends_at, created_at, updated_at
+`
+
+type UpdateAccessCodeParams struct {
+	Code          pgtype.Text
+	TicketTypeIds []pgtype.UUID
+	MaxTickets    pgtype.Int4
+	StartsAt      pgtype.Timestamptz
+	EndsAt        pgtype.Timestamptz
+	AccessCodeID  pgtype.UUID
+}
+
+func (q *Queries) UpdateAccessCode(ctx context.Context, arg UpdateAccessCodeParams) (AccessCode, error) {
+	row := q.db.QueryRow(ctx, updateAccessCode,
+		arg.Code,
+		arg.TicketTypeIds,
+		arg.MaxTickets,
+		arg.StartsAt,
+		arg.EndsAt,
+		arg.AccessCodeID,
+	)
+	var i AccessCode
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Code,
+		&i.TicketTypeIds,
+		&i.MaxTickets,
+		&i.UsedTickets,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
 }
 
 const updateAttendeeStatus = `-- name: UpdateAttendeeStatus :exec
@@ -695,6 +3739,29 @@ func (q *Queries) UpdateAttendeeStatus(ctx context.Context, arg UpdateAttendeeSt
 	return err
 }
 
+const updateAvailableTicketsOfType = `-- name: UpdateAvailableTicketsOfType :execrows
+UPDATE ticket t
+SET
+    name = tt.name,
+    description = tt.description,
+    price = tt.price,
+    currency = tt.currency,
+    benefits = tt.benefits,
+    min = tt.min,
+    max = tt.max,
+    updated_at = now()
+FROM ticket_type tt
+WHERE tt.id = $1 AND t.ticket_type_id = tt.id AND t.status = 'available'
+`
+
+func (q *Queries) UpdateAvailableTicketsOfType(ctx context.Context, ticketTypeID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, updateAvailableTicketsOfType, ticketTypeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateEvent = `-- name: UpdateEvent :exec
 UPDATE event
 SET
@@ -702,17 +3769,33 @@ SET
     description = $2,
     location = $3,
     event_start_date = $4,
-    event_end_date = $5
-WHERE id = $6
+    event_end_date = $5,
+    payment_provider = COALESCE($6, payment_provider),
+    fee_type = COALESCE($7::TEXT, fee_type::TEXT)::fee_type,
+    fee_value = COALESCE($8, fee_value),
+    fee_per = COALESCE($9::TEXT, fee_per::TEXT)::fee_per,
+    fee_absorbed = COALESCE($10, fee_absorbed),
+    tax_name = COALESCE($11, tax_name),
+    tax_rate = COALESCE($12, tax_rate),
+    max_free_tickets_per_email = COALESCE($13, max_free_tickets_per_email)
+WHERE id = $14
 `
 
 type UpdateEventParams struct {
-	Name           string
-	Description    string
-	Location       string
-	EventStartDate pgtype.Timestamptz
-	EventEndDate   pgtype.Timestamptz
-	EventID        pgtype.UUID
+	Name                   string
+	Description            string
+	Location               string
+	EventStartDate         pgtype.Timestamptz
+	EventEndDate           pgtype.Timestamptz
+	PaymentProvider        pgtype.Text
+	FeeType                pgtype.Text
+	FeeValue               pgtype.Int8
+	FeePer                 pgtype.Text
+	FeeAbsorbed            pgtype.Bool
+	TaxName                pgtype.Text
+	TaxRate                pgtype.Int4
+	MaxFreeTicketsPerEmail pgtype.Int4
+	EventID                pgtype.UUID
 }
 
 func (q *Queries) UpdateEvent(ctx context.Context, arg UpdateEventParams) error {
@@ -722,11 +3805,75 @@ func (q *Queries) UpdateEvent(ctx context.Context, arg UpdateEventParams) error
 		arg.Location,
 		arg.EventStartDate,
 		arg.EventEndDate,
+		arg.PaymentProvider,
+		arg.FeeType,
+		arg.FeeValue,
+		arg.FeePer,
+		arg.FeeAbsorbed,
+		arg.TaxName,
+		arg.TaxRate,
+		arg.MaxFreeTicketsPerEmail,
 		arg.EventID,
 	)
 	return err
 }
 
+const updateOrderBuyer = `-- name: UpdateOrderBuyer :exec
+UPDATE orders
+SET
+    buyer_name = $1,
+    buyer_email = $2,
+    updated_at = now()
+WHERE id = $3
+`
+
+type UpdateOrderBuyerParams struct {
+	BuyerName  string
+	BuyerEmail string
+	OrderID    pgtype.UUID
+}
+
+func (q *Queries) UpdateOrderBuyer(ctx context.Context, arg UpdateOrderBuyerParams) error {
+	_, err := q.db.Exec(ctx, updateOrderBuyer, arg.BuyerName, arg.BuyerEmail, arg.OrderID)
+	return err
+}
+
+const updateOrderProviderReference = `-- name: UpdateOrderProviderReference :exec
+UPDATE orders
+SET
+    provider_reference = $1,
+    updated_at = now()
+WHERE id = $2
+`
+
+type UpdateOrderProviderReferenceParams struct {
+	ProviderReference pgtype.Text
+	OrderID           pgtype.UUID
+}
+
+func (q *Queries) UpdateOrderProviderReference(ctx context.Context, arg UpdateOrderProviderReferenceParams) error {
+	_, err := q.db.Exec(ctx, updateOrderProviderReference, arg.ProviderReference, arg.OrderID)
+	return err
+}
+
+const updateOrderStatus = `-- name: UpdateOrderStatus :exec
+UPDATE orders
+SET
+    status = $1,
+    updated_at = now()
+WHERE id = $2
+`
+
+type UpdateOrderStatusParams struct {
+	Status  OrderStatus
+	OrderID pgtype.UUID
+}
+
+func (q *Queries) UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) error {
+	_, err := q.db.Exec(ctx, updateOrderStatus, arg.Status, arg.OrderID)
+	return err
+}
+
 const updatePayment = `-- name: UpdatePayment :exec
 UPDATE payment
 SET
@@ -756,31 +3903,278 @@ func (q *Queries) UpdatePayment(ctx context.Context, arg UpdatePaymentParams) er
 	return err
 }
 
-const updateTicket = `-- name: UpdateTicket :exec
-UPDATE ticket
+const updatePaymentWebhook = `-- name: UpdatePaymentWebhook :exec
+UPDATE payment_webhook
 SET
-    name = $1,
-    description = $2,
-    price = $3,
-    benefits = $4
-WHERE event_id = $5
+    signature_valid = $1,
+    transaction_id = $2,
+    bill_link_id = $3,
+    status = $4,
+    error = $5,
+    attempts = attempts + 1,
+    processed_at = CASE WHEN $4 IN ('processed', 'duplicate') THEN now() ELSE processed_at END,
+    updated_at = now()
+WHERE id = $6
 `
 
-type UpdateTicketParams struct {
-	Name        string
-	Description string
-	Price       string
-	Benefits    []byte
-	EventID     pgtype.UUID
+type UpdatePaymentWebhookParams struct {
+	SignatureValid pgtype.Bool
+	TransactionID  pgtype.Text
+	BillLinkID     pgtype.Int4
+	Status         WebhookStatus
+	Error          string
+	WebhookID      pgtype.UUID
+}
+
+func (q *Queries) UpdatePaymentWebhook(ctx context.Context, arg UpdatePaymentWebhookParams) error {
+	_, err := q.db.Exec(ctx, updatePaymentWebhook,
+		arg.SignatureValid,
+		arg.TransactionID,
+		arg.BillLinkID,
+		arg.Status,
+		arg.Error,
+		arg.WebhookID,
+	)
+	return err
+}
+
+const updatePromoCode = `-- name: UpdatePromoCode :one
+UPDATE promo_code
+SET
+    code = COALESCE($1, code),
+    discount_type = COALESCE($2, discount_type),
+    value = COALESCE($3, value),
+    currency = COALESCE($4, currency),
+    ticket_type_ids = COALESCE($5::UUID[], ticket_type_ids),
+    max_uses = COALESCE($6, max_uses),
+    starts_at = COALESCE($7, starts_at),
+    ends_at = COALESCE($8, ends_at),
+    updated_at = now()
+WHERE id = $9
+RETURNING id, event_id, code, discount_type, value, currency, ticket_type_ids, max_uses, used_count, starts_at, ends_at, created_at, updated_at
+`
+
+type UpdatePromoCodeParams struct {
+	Code          pgtype.Text
+	DiscountType  NullDiscountType
+	Value         pgtype.Int8
+	Currency      pgtype.Text
+	TicketTypeIds []pgtype.UUID
+	MaxUses       pgtype.Int4
+	StartsAt      pgtype.Timestamptz
+	EndsAt        pgtype.Timestamptz
+	PromoCodeID   pgtype.UUID
+}
+
+func (q *Queries) UpdatePromoCode(ctx context.Context, arg UpdatePromoCodeParams) (PromoCode, error) {
+	row := q.db.QueryRow(ctx, updatePromoCode,
+		arg.Code,
+		arg.DiscountType,
+		arg.Value,
+		arg.Currency,
+		arg.TicketTypeIds,
+		arg.MaxUses,
+		arg.StartsAt,
+		arg.EndsAt,
+		arg.PromoCodeID,
+	)
+	var i PromoCode
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Code,
+		&i.DiscountType,
+		&i.Value,
+		&i.Currency,
+		&i.TicketTypeIds,
+		&i.MaxUses,
+		&i.UsedCount,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateRefundStatus = `-- name: UpdateRefundStatus :one
+UPDATE refund
+SET
+    status = $1,
+    provider_reference = COALESCE($2, provider_reference),
+    updated_at = now()
+WHERE id = $3
+RETURNING id, payment_id, order_id, event_id, ticket_ids, amount, currency, reason, status, provider_reference, created_at, updated_at, restock
+`
+
+type UpdateRefundStatusParams struct {
+	Status            string
+	ProviderReference pgtype.Text
+	RefundID          pgtype.UUID
+}
+
+func (q *Queries) UpdateRefundStatus(ctx context.Context, arg UpdateRefundStatusParams) (Refund, error) {
+	row := q.db.QueryRow(ctx, updateRefundStatus, arg.Status, arg.ProviderReference, arg.RefundID)
+	var i Refund
+	err := row.Scan(
+		&i.ID,
+		&i.PaymentID,
+		&i.OrderID,
+		&i.EventID,
+		&i.TicketIds,
+		&i.Amount,
+		&i.Currency,
+		&i.Reason,
+		&i.Status,
+		&i.ProviderReference,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Restock,
+	)
+	return i, err
+}
+
+const updateReservationState = `-- name: UpdateReservationState :exec
+UPDATE reservation
+SET
+    state = $1,
+    release_reason = $2,
+    updated_at = now()
+WHERE id = $3
+`
+
+type UpdateReservationStateParams struct {
+	State         ReservationState
+	ReleaseReason pgtype.Text
+	ReservationID pgtype.UUID
+}
+
+func (q *Queries) UpdateReservationState(ctx context.Context, arg UpdateReservationStateParams) error {
+	_, err := q.db.Exec(ctx, updateReservationState, arg.State, arg.ReleaseReason, arg.ReservationID)
+	return err
+}
+
+const updateTicketAttendeesStatus = `-- name: UpdateTicketAttendeesStatus :exec
+UPDATE attendee
+SET
+    status = $1,
+    updated_at = now()
+WHERE ticket_id = $2
+`
+
+type UpdateTicketAttendeesStatusParams struct {
+	Status   AttendeeStatus
+	TicketID pgtype.UUID
+}
+
+func (q *Queries) UpdateTicketAttendeesStatus(ctx context.Context, arg UpdateTicketAttendeesStatusParams) error {
+	_, err := q.db.Exec(ctx, updateTicketAttendeesStatus, arg.Status, arg.TicketID)
+	return err
+}
+
+const updateTicketType = `-- name: UpdateTicketType :exec
+UPDATE ticket_type
+SET
+    name = COALESCE($1, name),
+    description = COALESCE($2, description),
+    price = COALESCE($3, price),
+    currency = COALESCE($4, currency),
+    benefits = COALESCE($5, benefits),
+    min = COALESCE($6, min),
+    max = COALESCE($7, max),
+    sales_start = CASE WHEN $8::BOOLEAN THEN NULL ELSE COALESCE($9, sales_start) END,
+    sales_end = CASE WHEN $10::BOOLEAN THEN NULL ELSE COALESCE($11, sales_end) END,
+    visibility = COALESCE($12, visibility),
+    updated_at = now()
+WHERE id = $13
+`
+
+type UpdateTicketTypeParams struct {
+	Name            pgtype.Text
+	Description     pgtype.Text
+	Price           pgtype.Int8
+	Currency        pgtype.Text
+	Benefits        []byte
+	Min             pgtype.Int4
+	Max             pgtype.Int4
+	ClearSalesStart bool
+	SalesStart      pgtype.Timestamptz
+	ClearSalesEnd   bool
+	SalesEnd        pgtype.Timestamptz
+	Visibility      NullTicketVisibility
+	TicketTypeID    pgtype.UUID
 }
 
-func (q *Queries) UpdateTicket(ctx context.Context, arg UpdateTicketParams) error {
-	_, err := q.db.Exec(ctx, updateTicket,
+func (q *Queries) UpdateTicketType(ctx context.Context, arg UpdateTicketTypeParams) error {
+	_, err := q.db.Exec(ctx, updateTicketType,
 		arg.Name,
 		arg.Description,
 		arg.Price,
+		arg.Currency,
 		arg.Benefits,
-		arg.EventID,
+		arg.Min,
+		arg.Max,
+		arg.ClearSalesStart,
+		arg.SalesStart,
+		arg.ClearSalesEnd,
+		arg.SalesEnd,
+		arg.Visibility,
+		arg.TicketTypeID,
 	)
 	return err
 }
+
+const updateWaitlistEntryState = `-- name: UpdateWaitlistEntryState :exec
+UPDATE waitlist_entry
+SET
+    state = $1,
+    updated_at = now()
+WHERE id = $2
+`
+
+type UpdateWaitlistEntryStateParams struct {
+	State   WaitlistState
+	EntryID pgtype.UUID
+}
+
+func (q *Queries) UpdateWaitlistEntryState(ctx context.Context, arg UpdateWaitlistEntryStateParams) error {
+	_, err := q.db.Exec(ctx, updateWaitlistEntryState, arg.State, arg.EntryID)
+	return err
+}
+
+const useAccessCode = `-- name: UseAccessCode :execrows
+UPDATE access_code
+SET
+    used_tickets = used_tickets + $1::INT,
+    updated_at = now()
+WHERE id = $2 AND (max_tickets = 0 OR used_tickets + $1::INT <= max_tickets)
+`
+
+type UseAccessCodeParams struct {
+	Tickets      int32
+	AccessCodeID pgtype.UUID
+}
+
+func (q *Queries) UseAccessCode(ctx context.Context, arg UseAccessCodeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useAccessCode, arg.Tickets, arg.AccessCodeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const usePromoCode = `-- name: UsePromoCode :execrows
+UPDATE promo_code
+SET
+    used_count = used_count + 1,
+    updated_at = now()
+WHERE id = $1 AND (max_uses = 0 OR used_count < max_uses)
+`
+
+func (q *Queries) UsePromoCode(ctx context.Context, promoCodeID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, usePromoCode, promoCodeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
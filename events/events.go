@@ -26,15 +26,25 @@ var secrets struct {
 	FlipApiBaseEndpoint string `json:"flip_api_base_endpoint"`
 	FlipValidationToken string `json:"flip_validation_token"`
 	FlipApiSecretKey    string `json:"flip_api_secret_key"`
+	// WaitlistClaimURL is the page waitlist offers link to, it gets the claim token as the token query parameter
+	WaitlistClaimURL string `json:"waitlist_claim_url"`
 }
 
+// defaultMaxFreeTicketsPerEmail is how many free tickets an email can claim per event unless the event says otherwise.
+const defaultMaxFreeTicketsPerEmail = 4
+
 type CreateEventRequest struct {
-	Name           string              `json:"name"`
-	Description    string              `json:"description"`
-	Location       string              `json:"location"`
-	EventStartDate time.Time           `json:"event_start_date"`
-	EventEndDate   time.Time           `json:"event_end_date"`
-	Inputs         []*EventTicketInput `json:"inputs"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	Location        string    `json:"location"`
+	EventStartDate  time.Time `json:"event_start_date"`
+	EventEndDate    time.Time `json:"event_end_date"`
+	PaymentProvider string    `json:"payment_provider"`
+	Fee             *FeeRule  `json:"fee"`
+	Tax             *TaxRule  `json:"tax"`
+	// MaxFreeTicketsPerEmail caps how many free tickets one email can claim, defaults to defaultMaxFreeTicketsPerEmail
+	MaxFreeTicketsPerEmail *int32              `json:"max_free_tickets_per_email"`
+	Inputs                 []*EventTicketInput `json:"inputs"`
 }
 
 // CreateEvent Create an event
@@ -43,6 +53,37 @@ type CreateEventRequest struct {
 func CreateEvent(ctx context.Context, req *CreateEventRequest) (*BaseResponse[InsertionResponse], error) {
 	eb := errs.B()
 
+	if req.PaymentProvider == "" {
+		req.PaymentProvider = PaymentProviderFlip
+	}
+	if _, err := paymentProvider(req.PaymentProvider); err != nil {
+		return nil, eb.Cause(err).Code(errs.InvalidArgument).Msg("Unknown payment provider").Err()
+	}
+
+	fee := defaultFeeRule()
+	if req.Fee != nil {
+		fee = *req.Fee
+	}
+	if err := validateFeeRule(fee); err != nil {
+		return nil, err
+	}
+
+	var tax TaxRule
+	if req.Tax != nil {
+		tax = *req.Tax
+	}
+	if err := validateTaxRate(tax.Rate); err != nil {
+		return nil, err
+	}
+
+	maxFreeTickets := int32(defaultMaxFreeTicketsPerEmail)
+	if req.MaxFreeTicketsPerEmail != nil {
+		maxFreeTickets = *req.MaxFreeTicketsPerEmail
+	}
+	if err := validateMaxFreeTickets(maxFreeTickets); err != nil {
+		return nil, err
+	}
+
 	eventId, err := query.InsertEvent(ctx, db.InsertEventParams{
 		Name:        req.Name,
 		Description: req.Description,
@@ -55,6 +96,14 @@ func CreateEvent(ctx context.Context, req *CreateEventRequest) (*BaseResponse[In
 			Time:  req.EventEndDate,
 			Valid: true,
 		},
+		PaymentProvider:        req.PaymentProvider,
+		FeeType:                fee.Type,
+		FeeValue:               fee.Value,
+		FeePer:                 fee.Per,
+		FeeAbsorbed:            fee.Absorbed,
+		TaxName:                tax.Name,
+		TaxRate:                tax.Rate,
+		MaxFreeTicketsPerEmail: maxFreeTickets,
 	})
 	if err != nil {
 		rlog.Error("An error occurred while creating event", "CreateEvent:err", err.Error())
@@ -90,12 +139,60 @@ func CreateEvent(ctx context.Context, req *CreateEventRequest) (*BaseResponse[In
 func UpdateEvent(ctx context.Context, id uuid.UUID, req *db.UpdateEventParams) error {
 	eb := errs.B()
 
+	if req.PaymentProvider.Valid {
+		if _, err := paymentProvider(req.PaymentProvider.String); err != nil {
+			return eb.Cause(err).Code(errs.InvalidArgument).Msg("Unknown payment provider").Err()
+		}
+	}
+	if req.FeeType.Valid || req.FeeValue.Valid || req.FeePer.Valid {
+		current, err := query.GetEventPricing(ctx, pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		})
+		if err != nil {
+			rlog.Error("An error occurred while updating event", "UpdateEvent:err", err.Error())
+			return eb.Code(errs.Internal).Msg("An error occurred while updating event").Err()
+		}
+
+		fee, _ := pricingRules(current)
+		if req.FeeType.Valid {
+			fee.Type = db.FeeType(req.FeeType.String)
+		}
+		if req.FeeValue.Valid {
+			fee.Value = req.FeeValue.Int64
+		}
+		if req.FeePer.Valid {
+			fee.Per = db.FeePer(req.FeePer.String)
+		}
+		if err := validateFeeRule(fee); err != nil {
+			return err
+		}
+	}
+	if req.TaxRate.Valid {
+		if err := validateTaxRate(req.TaxRate.Int32); err != nil {
+			return err
+		}
+	}
+	if req.MaxFreeTicketsPerEmail.Valid {
+		if err := validateMaxFreeTickets(req.MaxFreeTicketsPerEmail.Int32); err != nil {
+			return err
+		}
+	}
+
 	err := query.UpdateEvent(ctx, db.UpdateEventParams{
-		Name:           req.Name,
-		Description:    req.Description,
-		Location:       req.Location,
-		EventStartDate: req.EventStartDate,
-		EventEndDate:   req.EventEndDate,
+		Name:                   req.Name,
+		Description:            req.Description,
+		Location:               req.Location,
+		EventStartDate:         req.EventStartDate,
+		EventEndDate:           req.EventEndDate,
+		PaymentProvider:        req.PaymentProvider,
+		FeeType:                req.FeeType,
+		FeeValue:               req.FeeValue,
+		FeePer:                 req.FeePer,
+		FeeAbsorbed:            req.FeeAbsorbed,
+		TaxName:                req.TaxName,
+		TaxRate:                req.TaxRate,
+		MaxFreeTicketsPerEmail: req.MaxFreeTicketsPerEmail,
 		EventID: pgtype.UUID{
 			Bytes: id,
 			Valid: true,
@@ -109,6 +206,14 @@ func UpdateEvent(ctx context.Context, id uuid.UUID, req *db.UpdateEventParams) e
 	return nil
 }
 
+// validateMaxFreeTickets rejects a negative free ticket cap. Zero turns free checkout off for the event.
+func validateMaxFreeTickets(limit int32) error {
+	if limit < 0 {
+		return errs.B().Code(errs.InvalidArgument).Msg("max_free_tickets_per_email cannot be negative").Err()
+	}
+	return nil
+}
+
 // DeleteEvent Delete an event
 //
 //encore:api auth method=DELETE path=/v1/events/:id
@@ -150,15 +255,27 @@ func GetEvent(ctx context.Context, id uuid.UUID) (*BaseResponse[Event], error) {
 
 	return &BaseResponse[Event]{
 		Data: Event{
-			ID:             data.ID,
-			Name:           data.Name,
-			Description:    data.Description,
-			Location:       data.Location,
-			EventStartDate: data.EventStartDate,
-			EventEndDate:   data.EventEndDate,
-			CreatedAt:      data.CreatedAt,
-			UpdatedAt:      data.UpdatedAt,
-			TicketInputs:   ticketInputs,
+			ID:              data.ID,
+			Name:            data.Name,
+			Description:     data.Description,
+			Location:        data.Location,
+			EventStartDate:  data.EventStartDate,
+			EventEndDate:    data.EventEndDate,
+			CreatedAt:       data.CreatedAt,
+			UpdatedAt:       data.UpdatedAt,
+			PaymentProvider: data.PaymentProvider,
+			Fee: FeeRule{
+				Type:     data.FeeType,
+				Value:    data.FeeValue,
+				Per:      data.FeePer,
+				Absorbed: data.FeeAbsorbed,
+			},
+			Tax: TaxRule{
+				Name: data.TaxName,
+				Rate: data.TaxRate,
+			},
+			MaxFreeTicketsPerEmail: data.MaxFreeTicketsPerEmail,
+			TicketInputs:           ticketInputs,
 		},
 		Message: "Event retrieved successfully",
 	}, nil
@@ -192,15 +309,27 @@ func ListEvents(ctx context.Context, params *ListQuery) (*BaseResponse[[]Event],
 		}
 
 		events = append(events, Event{
-			ID:             data.ID,
-			Name:           data.Name,
-			Description:    data.Description,
-			Location:       data.Location,
-			EventStartDate: data.EventStartDate,
-			EventEndDate:   data.EventEndDate,
-			CreatedAt:      data.CreatedAt,
-			UpdatedAt:      data.UpdatedAt,
-			TicketInputs:   ticketInputs,
+			ID:              data.ID,
+			Name:            data.Name,
+			Description:     data.Description,
+			Location:        data.Location,
+			EventStartDate:  data.EventStartDate,
+			EventEndDate:    data.EventEndDate,
+			CreatedAt:       data.CreatedAt,
+			UpdatedAt:       data.UpdatedAt,
+			PaymentProvider: data.PaymentProvider,
+			Fee: FeeRule{
+				Type:     data.FeeType,
+				Value:    data.FeeValue,
+				Per:      data.FeePer,
+				Absorbed: data.FeeAbsorbed,
+			},
+			Tax: TaxRule{
+				Name: data.TaxName,
+				Rate: data.TaxRate,
+			},
+			MaxFreeTicketsPerEmail: data.MaxFreeTicketsPerEmail,
+			TicketInputs:           ticketInputs,
 		})
 	}
 
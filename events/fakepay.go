@@ -0,0 +1,140 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// fakeProvider is an in-memory payment provider for local runs and tests. Bills never leave the process;
+// a payment is simulated by posting to /payments/callback/fake, for example:
+//
+//	curl -X POST localhost:4000/payments/callback/fake \
+//	  -d '{"bill_link_id": 1729000000, "status": "SUCCESSFUL", "sender_name": "Jane", "sender_email": "jane@example.com"}'
+type fakeProvider struct {
+	mu     sync.Mutex
+	nextID int
+	bills  map[int]*fakeBill
+}
+
+type fakeBill struct {
+	bill     CreateBillResponse
+	payments []Transaction
+}
+
+// fakeCallbackRequest is the body accepted by the fake provider's callback.
+type fakeCallbackRequest struct {
+	BillLinkID  int    `json:"bill_link_id"`
+	Status      string `json:"status"`
+	SenderName  string `json:"sender_name"`
+	SenderEmail string `json:"sender_email"`
+}
+
+func newFakeProvider() *fakeProvider {
+	return &fakeProvider{
+		// seed from the clock so bill ids do not collide with reservations from earlier runs
+		nextID: int(time.Now().Unix()),
+		bills:  map[int]*fakeBill{},
+	}
+}
+
+func (f *fakeProvider) Name() string {
+	return PaymentProviderFake
+}
+
+// CreateBill records a bill in memory.
+func (f *fakeProvider) CreateBill(_ context.Context, req *CreateBillRequest) (*CreateBillResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	bill := CreateBillResponse{
+		LinkID:      f.nextID,
+		LinkURL:     fmt.Sprintf("fake://bills/%d", f.nextID),
+		Title:       req.Title,
+		Type:        req.Type,
+		Amount:      req.Amount,
+		RedirectURL: req.RedirectURL,
+		ExpiredDate: req.ExpiredDate,
+		CreatedFrom: "API",
+		Status:      "ACTIVE",
+		Step:        1,
+	}
+	f.bills[bill.LinkID] = &fakeBill{bill: bill}
+
+	return &bill, nil
+}
+
+// ParseCallback accepts a JSON fakeCallbackRequest. Only bills created by this process are accepted,
+// and the amount is always the full bill amount.
+func (f *fakeProvider) ParseCallback(req *http.Request) (*Transaction, error) {
+	var cb fakeCallbackRequest
+	if err := json.NewDecoder(req.Body).Decode(&cb); err != nil {
+		return nil, err
+	}
+	if cb.Status == "" {
+		cb.Status = TransactionSuccessful
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bills[cb.BillLinkID]
+	if !ok {
+		return nil, ErrInvalidCallback
+	}
+
+	tx := Transaction{
+		ID:          fmt.Sprintf("FAKE-%d-%d", cb.BillLinkID, len(b.payments)+1),
+		BillLink:    b.bill.LinkURL,
+		BillLinkID:  cb.BillLinkID,
+		BillTitle:   b.bill.Title,
+		SenderName:  cb.SenderName,
+		SenderBank:  "fake",
+		SenderEmail: cb.SenderEmail,
+		Amount:      b.bill.Amount,
+		Status:      cb.Status,
+		CreatedAt:   time.Now().Format("2006-01-02 15:04:05"),
+	}
+	b.payments = append(b.payments, tx)
+
+	return &tx, nil
+}
+
+// BillPayments returns the simulated payments of a bill.
+func (f *fakeProvider) BillPayments(_ context.Context, billLinkID int) ([]Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bills[billLinkID]
+	if !ok {
+		return nil, fmt.Errorf("unknown fake bill %d", billLinkID)
+	}
+
+	return append([]Transaction(nil), b.payments...), nil
+}
+
+// Refund succeeds for any bill with a successful payment. The same reference always gets the same refund.
+func (f *fakeProvider) Refund(_ context.Context, req *RefundRequest) (*RefundResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bills[req.BillLinkID]
+	if !ok {
+		return nil, fmt.Errorf("unknown fake bill %d", req.BillLinkID)
+	}
+
+	for _, payment := range b.payments {
+		if payment.Status == TransactionSuccessful {
+			return &RefundResponse{
+				ID:     fmt.Sprintf("FAKE-REFUND-%s", req.Reference),
+				Status: RefundSuccessful,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("fake bill %d has no successful payment", req.BillLinkID)
+}
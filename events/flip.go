@@ -0,0 +1,133 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"encore.dev/rlog"
+)
+
+// flipProvider bills through Flip's accept payment (PWF) API.
+type flipProvider struct{}
+
+// flipBillPaymentsResponse represents Flip's paginated list of payments made to a bill.
+type flipBillPaymentsResponse struct {
+	LinkID    int           `json:"link_id"`
+	TotalData int           `json:"total_data"`
+	Data      []Transaction `json:"data"`
+}
+
+func (f *flipProvider) Name() string {
+	return PaymentProviderFlip
+}
+
+// CreateBill creates a new Flip bill with the given request parameters.
+// Flip only bills in rupiah.
+func (f *flipProvider) CreateBill(ctx context.Context, req *CreateBillRequest) (*CreateBillResponse, error) {
+	if req.Currency != "" && req.Currency != "IDR" {
+		return nil, fmt.Errorf("flip cannot bill in %s", req.Currency)
+	}
+
+	data := url.Values{}
+	data.Set("title", req.Title)
+	data.Set("amount", fmt.Sprintf("%d", req.Amount))
+	data.Set("type", req.Type)
+	data.Set("expired_date", req.ExpiredDate)
+	data.Set("redirect_url", req.RedirectURL)
+	data.Set("is_address_required", fmt.Sprintf("%d", req.IsAddressRequired))
+	data.Set("is_phone_number_required", fmt.Sprintf("%d", req.IsPhoneNumberRequired))
+
+	body, err := f.do(ctx, http.MethodPost, "/pwf/bill", bytes.NewBufferString(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonResponse CreateBillResponse
+	if err := json.Unmarshal(body, &jsonResponse); err != nil {
+		return nil, err
+	}
+
+	return &jsonResponse, nil
+}
+
+// ParseCallback checks the callback token against the configured Flip validation token and decodes the
+// transaction sent in the `data` form field.
+func (f *flipProvider) ParseCallback(req *http.Request) (*Transaction, error) {
+	token := req.PostFormValue("token")
+	if secrets.FlipValidationToken == "" || token == "" ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(secrets.FlipValidationToken)) != 1 {
+		return nil, ErrInvalidCallback
+	}
+
+	var tx Transaction
+	if err := json.Unmarshal([]byte(req.PostFormValue("data")), &tx); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// BillPayments lists the payments Flip has recorded for a bill.
+func (f *flipProvider) BillPayments(ctx context.Context, billLinkID int) ([]Transaction, error) {
+	body, err := f.do(ctx, http.MethodGet, fmt.Sprintf("/pwf/%d/payment", billLinkID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonResponse flipBillPaymentsResponse
+	if err := json.Unmarshal(body, &jsonResponse); err != nil {
+		return nil, err
+	}
+
+	return jsonResponse.Data, nil
+}
+
+// Refund is not available for Flip bills; money has to be returned outside of the API.
+func (f *flipProvider) Refund(_ context.Context, _ *RefundRequest) (*RefundResponse, error) {
+	return nil, ErrRefundNotSupported
+}
+
+// do sends an authenticated request to the Flip API and returns the response body.
+func (f *flipProvider) do(ctx context.Context, method, path string, payload io.Reader) ([]byte, error) {
+	reqs, err := http.NewRequestWithContext(ctx, method, secrets.FlipApiBaseEndpoint+path, payload)
+	if err != nil {
+		rlog.Info("Error creating request:", "err", err)
+		return nil, err
+	}
+
+	encodedCredentials := base64.StdEncoding.EncodeToString([]byte(secrets.FlipApiSecretKey + ":"))
+	reqs.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	reqs.Header.Set("Authorization", "Basic "+encodedCredentials)
+
+	resp, err := http.DefaultClient.Do(reqs)
+	if err != nil {
+		rlog.Info("Error making request:", "err", err)
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			rlog.Error("Error closing response body", "err", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		rlog.Info("Error reading response:", "err", err)
+		return nil, err
+	}
+	rlog.Info("Response:", "path", path, "status", resp.StatusCode, "body", string(body))
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("flip responded with status %d", resp.StatusCode)
+	}
+
+	return body, nil
+}
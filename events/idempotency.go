@@ -0,0 +1,142 @@
+package events
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+)
+
+// IdempotencyKeyTTL is how long a purchase can be retried under the same Idempotency-Key. After that the key
+// starts a new purchase. A reservation that is not paid only replays until it expires, its bill link stops working
+// then.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength caps the Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
+// purchaseHash fingerprints a purchase request, so a retry under the same key can be told apart from another
+// purchase reusing it.
+func purchaseHash(req *BuyTicketRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// storedPurchase is a purchase kept under an Idempotency-Key: the reservation of a paid order, or a free order.
+// orderStatus is the current status of its order, which may have moved on since the response was stored.
+type storedPurchase struct {
+	expiresAt   time.Time
+	requestHash string
+	response    []byte
+	orderStatus db.OrderStatus
+	// clear frees the key once its window has passed
+	clear func() error
+}
+
+// findStoredPurchase looks up the purchase stored under key, or nil when there is none.
+func findStoredPurchase(ctx context.Context, q *db.Queries, eventID pgtype.UUID, key string) (*storedPurchase, error) {
+	reservation, err := q.GetReservationByIdempotencyKey(ctx, db.GetReservationByIdempotencyKeyParams{
+		EventID:        eventID,
+		IdempotencyKey: key,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil {
+		order, err := q.GetOrder(ctx, reservation.OrderID)
+		if err != nil {
+			return nil, err
+		}
+
+		// an unpaid reservation replays until its bill expires, a released one not at all
+		expiresAt := reservation.CreatedAt.Time.Add(IdempotencyKeyTTL)
+		switch reservation.State {
+		case db.ReservationStatePending:
+			if reservation.ExpiredAt.Time.Before(expiresAt) {
+				expiresAt = reservation.ExpiredAt.Time
+			}
+		case db.ReservationStateExpired, db.ReservationStateCancelled:
+			expiresAt = reservation.UpdatedAt.Time
+		}
+
+		return &storedPurchase{
+			expiresAt:   expiresAt,
+			requestHash: reservation.RequestHash.String,
+			response:    reservation.Response,
+			orderStatus: order.Status,
+			clear:       func() error { return q.ClearReservationIdempotencyKey(ctx, reservation.ID) },
+		}, nil
+	}
+
+	order, err := q.GetOrderByIdempotencyKey(ctx, db.GetOrderByIdempotencyKeyParams{
+		EventID:        eventID,
+		IdempotencyKey: key,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &storedPurchase{
+		expiresAt:   order.CreatedAt.Time.Add(IdempotencyKeyTTL),
+		requestHash: order.RequestHash.String,
+		response:    order.Response,
+		orderStatus: order.Status,
+		clear:       func() error { return q.ClearOrderIdempotencyKey(ctx, order.ID) },
+	}, nil
+}
+
+// replayPurchase returns the response of the purchase stored under key with the current status of its order, or
+// nil when the key is new or its window has passed. A key used for a different purchase is a conflict. The key
+// stays locked until the transaction ends, so a concurrent retry waits for the first request and then replays it.
+func replayPurchase(ctx context.Context, q *db.Queries, eventID pgtype.UUID, key, hash string) (*BuyTicketResponse, error) {
+	eb := errs.B()
+
+	err := q.LockIdempotencyKey(ctx, db.LockIdempotencyKeyParams{
+		EventID:        eventID,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while checking the idempotency key").Err()
+	}
+
+	stored, err := findStoredPurchase(ctx, q, eventID, key)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while checking the idempotency key").Err()
+	}
+	if stored == nil {
+		return nil, nil
+	}
+
+	if !time.Now().Before(stored.expiresAt) {
+		if err := stored.clear(); err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while checking the idempotency key").Err()
+		}
+		return nil, nil
+	}
+	if stored.requestHash != hash {
+		return nil, eb.Code(errs.AlreadyExists).Msg("Idempotency-Key was already used for a different purchase").Err()
+	}
+
+	var response BuyTicketResponse
+	if err := json.Unmarshal(stored.response, &response); err != nil {
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while reading the original purchase").Err()
+	}
+	response.OrderStatus = stored.orderStatus
+
+	return &response, nil
+}
@@ -0,0 +1,293 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+	"github.com/lichtlabs/ggrims-service/money"
+)
+
+// orderTransitions lists the states an order may move to from each state.
+//
+//	created → awaiting_payment → paid → refunded
+//	   ↘ paid (free)  ↘ expired / cancelled
+var orderTransitions = map[db.OrderStatus][]db.OrderStatus{
+	db.OrderStatusCreated:         {db.OrderStatusAwaitingPayment, db.OrderStatusPaid, db.OrderStatusCancelled},
+	db.OrderStatusAwaitingPayment: {db.OrderStatusPaid, db.OrderStatusExpired, db.OrderStatusCancelled},
+	db.OrderStatusPaid:            {db.OrderStatusRefunded},
+}
+
+// ErrInvalidOrderTransition is returned when an order is moved to a state its current state does not allow.
+var ErrInvalidOrderTransition = errors.New("invalid order transition")
+
+// createOrder inserts a new order with its line items and records its initial state.
+func createOrder(ctx context.Context, q *db.Queries, params db.InsertOrderParams, items []db.InsertOrderItemParams) (pgtype.UUID, error) {
+	orderID, err := q.InsertOrder(ctx, params)
+	if err != nil {
+		return orderID, err
+	}
+
+	for _, item := range items {
+		item.OrderID = orderID
+		if _, err := q.InsertOrderItem(ctx, item); err != nil {
+			return orderID, err
+		}
+	}
+
+	err = q.InsertOrderTransition(ctx, db.InsertOrderTransitionParams{
+		OrderID:  orderID,
+		ToStatus: db.OrderStatusCreated,
+		Reason:   "order placed",
+	})
+
+	return orderID, err
+}
+
+// transitionOrder moves an order to the given state and records the transition in its history.
+// It locks the order row, so it should run inside the transaction that caused the change.
+func transitionOrder(ctx context.Context, q *db.Queries, orderID pgtype.UUID, to db.OrderStatus, reason string) error {
+	order, err := q.GetOrderForUpdate(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	allowed := false
+	for _, next := range orderTransitions[order.Status] {
+		if next == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s → %s", ErrInvalidOrderTransition, order.Status, to)
+	}
+
+	err = q.UpdateOrderStatus(ctx, db.UpdateOrderStatusParams{
+		Status:  to,
+		OrderID: orderID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.InsertOrderTransition(ctx, db.InsertOrderTransitionParams{
+		OrderID: orderID,
+		FromStatus: db.NullOrderStatus{
+			OrderStatus: order.Status,
+			Valid:       true,
+		},
+		ToStatus: to,
+		Reason:   reason,
+	})
+}
+
+// settleOrder marks an order as paid and fills in buyer details the buyer did not give at checkout
+// from the payment itself.
+func settleOrder(ctx context.Context, q *db.Queries, orderID pgtype.UUID, tx *Transaction) error {
+	order, err := q.GetOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	if order.BuyerName == "" || order.BuyerEmail == "" {
+		buyerName, buyerEmail := order.BuyerName, order.BuyerEmail
+		if buyerName == "" {
+			buyerName = tx.SenderName
+		}
+		if buyerEmail == "" {
+			buyerEmail = tx.SenderEmail
+		}
+
+		err := q.UpdateOrderBuyer(ctx, db.UpdateOrderBuyerParams{
+			BuyerName:  buyerName,
+			BuyerEmail: buyerEmail,
+			OrderID:    orderID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return transitionOrder(ctx, q, orderID, db.OrderStatusPaid, fmt.Sprintf("payment %s received", tx.ID))
+}
+
+// orderStatusForReservation maps the final state of a reservation to the state of its order.
+func orderStatusForReservation(state db.ReservationState) db.OrderStatus {
+	switch state {
+	case db.ReservationStatePaid:
+		return db.OrderStatusPaid
+	case db.ReservationStateExpired:
+		return db.OrderStatusExpired
+	default:
+		return db.OrderStatusCancelled
+	}
+}
+
+// Order represents an order as shown to admins.
+type Order struct {
+	ID                pgtype.UUID        `json:"id"`
+	EventID           pgtype.UUID        `json:"event_id"`
+	BuyerName         string             `json:"buyer_name"`
+	BuyerEmail        string             `json:"buyer_email"`
+	Amount            money.Money        `json:"amount"`
+	Breakdown         PriceBreakdown     `json:"breakdown"`
+	PromoCode         string             `json:"promo_code,omitempty"`
+	PaymentProvider   string             `json:"payment_provider"`
+	ProviderReference string             `json:"provider_reference"`
+	Status            db.OrderStatus     `json:"status"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+// OrderItem represents a line of an order.
+type OrderItem struct {
+	TicketName string      `json:"ticket_name"`
+	Quantity   int32       `json:"quantity"`
+	UnitPrice  money.Money `json:"unit_price"`
+}
+
+// OrderTransition represents one state change in the history of an order.
+type OrderTransition struct {
+	FromStatus *db.OrderStatus    `json:"from_status"`
+	ToStatus   db.OrderStatus     `json:"to_status"`
+	Reason     string             `json:"reason"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+// OrderDetail combines an order with its line items and state history.
+type OrderDetail struct {
+	Order
+	Items       []OrderItem       `json:"items"`
+	Transitions []OrderTransition `json:"transitions"`
+	Refunds     []Refund          `json:"refunds"`
+}
+
+func toOrder(order db.Order) Order {
+	return Order{
+		ID:                order.ID,
+		EventID:           order.EventID,
+		BuyerName:         order.BuyerName,
+		BuyerEmail:        order.BuyerEmail,
+		Amount:            money.New(order.Amount, order.Currency),
+		Breakdown:         orderBreakdown(order),
+		PromoCode:         order.PromoCode.String,
+		PaymentProvider:   order.PaymentProvider,
+		ProviderReference: order.ProviderReference.String,
+		Status:            order.Status,
+		CreatedAt:         order.CreatedAt,
+		UpdatedAt:         order.UpdatedAt,
+	}
+}
+
+// ListEventOrders List orders placed for an event
+//
+//encore:api auth method=GET path=/v1/events/:id/orders
+func ListEventOrders(ctx context.Context, id uuid.UUID, params *ListQuery) (*BaseResponse[[]Order], error) {
+	eb := errs.B()
+
+	extractedParam := extractQuery(params)
+	data, err := query.ListOrder(ctx, db.ListOrderParams{
+		OrderBy: extractedParam.OrderBy,
+		Limits:  extractedParam.Limit,
+		Offsets: extractedParam.Page,
+		EventID: pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		},
+	})
+	if err != nil {
+		rlog.Error("An error occurred while retrieving orders", "ListEventOrders:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving orders").Err()
+	}
+
+	orders := make([]Order, 0, len(data))
+	for _, order := range data {
+		orders = append(orders, toOrder(order))
+	}
+
+	return &BaseResponse[[]Order]{
+		Data:    orders,
+		Message: "Orders retrieved successfully",
+	}, nil
+}
+
+// GetOrder Get an order including its line items, state history and refunds
+//
+//encore:api auth method=GET path=/v1/orders/:id
+func GetOrder(ctx context.Context, id uuid.UUID) (*BaseResponse[OrderDetail], error) {
+	eb := errs.B()
+
+	orderID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	order, err := query.GetOrder(ctx, orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Order not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving order", "GetOrder:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving order").Err()
+	}
+
+	items, err := query.ListOrderItem(ctx, orderID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving order items", "GetOrder:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving order items").Err()
+	}
+
+	transitions, err := query.ListOrderTransition(ctx, orderID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving order transitions", "GetOrder:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving order transitions").Err()
+	}
+
+	refunds, err := query.ListOrderRefund(ctx, orderID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving order refunds", "GetOrder:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving order refunds").Err()
+	}
+
+	detail := OrderDetail{
+		Order:       toOrder(order),
+		Items:       make([]OrderItem, 0, len(items)),
+		Transitions: make([]OrderTransition, 0, len(transitions)),
+		Refunds:     make([]Refund, 0, len(refunds)),
+	}
+	for _, item := range items {
+		detail.Items = append(detail.Items, OrderItem{
+			TicketName: item.TicketName,
+			Quantity:   item.Quantity,
+			UnitPrice:  money.New(item.UnitPrice, order.Currency),
+		})
+	}
+	for _, transition := range transitions {
+		var from *db.OrderStatus
+		if transition.FromStatus.Valid {
+			from = &transition.FromStatus.OrderStatus
+		}
+		detail.Transitions = append(detail.Transitions, OrderTransition{
+			FromStatus: from,
+			ToStatus:   transition.ToStatus,
+			Reason:     transition.Reason,
+			CreatedAt:  transition.CreatedAt,
+		})
+	}
+
+	for _, refund := range refunds {
+		detail.Refunds = append(detail.Refunds, toRefund(refund))
+	}
+
+	return &BaseResponse[OrderDetail]{
+		Data:    detail,
+		Message: "Order retrieved successfully",
+	}, nil
+}
@@ -3,33 +3,34 @@ package events
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"net/url"
-	"strconv"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/lichtlabs/ggrims-service/mail"
 	mailtempl "github.com/lichtlabs/ggrims-service/mail/template"
 
-	"encore.dev/beta/errs"
+	"encore.dev"
 	"encore.dev/rlog"
 	"github.com/lichtlabs/ggrims-service/events/db"
+	"github.com/lichtlabs/ggrims-service/money"
 )
 
 // CreateBillRequest represents the request parameters required to create a new bill.
 type CreateBillRequest struct {
-	Title                 string    `json:"title"`
-	Amount                int       `json:"amount"`
-	Type                  string    `json:"type"`
+	Title                 string `json:"title"`
+	Amount                int    `json:"amount"`
+	Currency              string `json:"currency"`
+	Type                  string `json:"type"`
 	ExpiredDate           string `json:"expired_date"`
-	RedirectURL           string    `json:"redirect_url"`
-	IsAddressRequired     int       `json:"is_address_required"`
-	IsPhoneNumberRequired int       `json:"is_phone_number_required"`
+	RedirectURL           string `json:"redirect_url"`
+	IsAddressRequired     int    `json:"is_address_required"`
+	IsPhoneNumberRequired int    `json:"is_phone_number_required"`
 }
 
 // CreateBillResponse represents the response structure for creating a bill.
@@ -48,74 +49,6 @@ type CreateBillResponse struct {
 	IsPhoneNumberRequired int    `json:"is_phone_number_required"`
 }
 
-// CreateBill creates a new bill with the given request parameters and returns the response or an error.
-//
-//encore:api private method=POST path=/payments
-func CreateBill(ctx context.Context, req *CreateBillRequest) (*CreateBillResponse, error) {
-	eb := errs.B()
-
-	createBillEndpoint := fmt.Sprintf("%s/pwf/bill", secrets.FlipApiBaseEndpoint)
-	data := url.Values{}
-	data.Set("title", req.Title)
-	data.Set("amount", fmt.Sprintf("%d", req.Amount))
-	data.Set("type", req.Type)
-	data.Set("expired_date", req.ExpiredDate)
-	data.Set("redirect_url", req.RedirectURL)
-	data.Set("is_address_required", fmt.Sprintf("%d", req.IsAddressRequired))
-	data.Set("is_phone_number_required", fmt.Sprintf("%d", req.IsPhoneNumberRequired))
-
-	encodedCredentials := base64.StdEncoding.EncodeToString([]byte(secrets.FlipApiSecretKey + ":"))
-	reqs, err := http.NewRequest(http.MethodPost, createBillEndpoint, bytes.NewBufferString(data.Encode()))
-	if err != nil {
-		rlog.Info("Error creating request:", "err", err)
-		return nil, eb.Code(errs.Internal).Msg("Error creating request").Err()
-	}
-
-	reqs.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	reqs.Header.Set("Authorization", "Basic "+encodedCredentials)
-
-	client := &http.Client{}
-	resp, err := client.Do(reqs)
-	if err != nil {
-		rlog.Info("Error making request:", "err", err)
-		return nil, eb.Code(errs.Internal).Msg("Error making request").Err()
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			rlog.Error("Error closing response body", "err", err)
-		}
-	}(resp.Body)
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		rlog.Info("Error reading response:", "err", err)
-		return nil, eb.Code(errs.Internal).Msg("Error reading response").Err()
-	}
-	rlog.Info("Response:", string(body))
-
-	var jsonResponse CreateBillResponse
-	err = json.Unmarshal(body, &jsonResponse)
-	if err != nil {
-		return nil, err
-	}
-
-	return &CreateBillResponse{
-		LinkID:                jsonResponse.LinkID,
-		LinkURL:               jsonResponse.LinkURL,
-		Title:                 jsonResponse.Title,
-		Type:                  jsonResponse.Type,
-		Amount:                jsonResponse.Amount,
-		RedirectURL:           jsonResponse.RedirectURL,
-		ExpiredDate:           jsonResponse.ExpiredDate,
-		CreatedFrom:           jsonResponse.CreatedFrom,
-		Status:                jsonResponse.Status,
-		Step:                  jsonResponse.Step,
-		IsAddressRequired:     jsonResponse.IsAddressRequired,
-		IsPhoneNumberRequired: jsonResponse.IsPhoneNumberRequired,
-	}, nil
-}
-
 type Transaction struct {
 	ID             string `json:"id"`
 	BillLink       string `json:"bill_link"`
@@ -139,19 +72,86 @@ type CallbackResponse struct {
 	Status string `json:"status"`
 }
 
-// Callback handles the HTTP request for processing a payment callback, updating the database, and changing ticket statuses.
+var (
+	errUnknownBill        = errors.New("callback for unknown bill")
+	errReservationSettled = errors.New("reservation is no longer pending")
+	errAmountMismatch     = errors.New("callback amount does not match reservation")
+	errProviderMismatch   = errors.New("callback provider does not match reservation")
+	errPaymentNotSettled  = errors.New("payment is not settled yet")
+	errMalformedCallback  = errors.New("callback could not be parsed")
+)
+
+// Callback handles the HTTP request for processing a Flip payment callback, updating the database, and changing ticket statuses.
 //
 //encore:api public raw method=POST path=/payments/callback
 func Callback(res http.ResponseWriter, req *http.Request) {
-	var tx Transaction
+	handleCallback(res, req, flip)
+}
+
+// ProviderCallback handles payment callbacks for the provider named in the path.
+//
+//encore:api public raw method=POST path=/payments/callback/:provider
+func ProviderCallback(res http.ResponseWriter, req *http.Request) {
+	provider, err := paymentProvider(encore.CurrentRequest().PathParams.Get("provider"))
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
 
+	handleCallback(res, req, provider)
+}
+
+// handleCallback stores a callback in the webhook inbox, processes it and maps the outcome to an HTTP status.
+func handleCallback(res http.ResponseWriter, req *http.Request, provider PaymentProvider) {
+	payload, err := io.ReadAll(req.Body)
+	if err != nil {
+		rlog.Error("Error reading callback", "provider", provider.Name(), "err", err)
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Settle in the background context so a provider hanging up does not abort a half-applied payment
 	ctx := context.Background()
+	contentType := req.Header.Get("Content-Type")
+
+	webhookID, err := query.InsertPaymentWebhook(ctx, db.InsertPaymentWebhookParams{
+		Provider:    provider.Name(),
+		ContentType: contentType,
+		Payload:     string(payload),
+	})
+	if err != nil {
+		rlog.Error("Error storing callback", "provider", provider.Name(), "err", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = processWebhook(ctx, provider, webhookID, contentType, payload)
+	switch {
+	case err == nil, errors.Is(err, errReservationSettled), errors.Is(err, errPaymentNotSettled):
+		res.WriteHeader(http.StatusOK)
+	case errors.Is(err, ErrInvalidCallback):
+		rlog.Error("Error: Invalid callback", "provider", provider.Name(), "remoteAddr", req.RemoteAddr)
+		res.WriteHeader(http.StatusUnauthorized)
+	case errors.Is(err, errMalformedCallback):
+		res.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, errUnknownBill):
+		res.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, errAmountMismatch), errors.Is(err, errProviderMismatch):
+		res.WriteHeader(http.StatusUnprocessableEntity)
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// settlePayment applies a provider transaction to the reservation of its bill: a successful payment sells the
+// reserved tickets, registers the attendees and mails the tickets, a failed, cancelled or expired one releases the
+// tickets. Pending and unknown statuses leave the reservation pending and return errPaymentNotSettled.
+func settlePayment(ctx context.Context, provider PaymentProvider, tx *Transaction) error {
 	// Start a database transaction
 	dbTX, err := pgxDB.Begin(ctx)
 	if err != nil {
 		rlog.Error("Failed to start transaction", "err", err)
-		res.WriteHeader(http.StatusInternalServerError)
-		return
+		return err
 	}
 
 	var committed bool
@@ -164,145 +164,277 @@ func Callback(res http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
-	dataFormValue := req.PostFormValue("data")
-	log.Println("dataFormValue", dataFormValue)
+	qtx := query.WithTx(dbTX)
 
-	err = json.Unmarshal([]byte(dataFormValue), &tx)
+	// The bill must belong to one of our reservations and be paid in full
+	reservation, err := qtx.GetReservationForUpdate(ctx, int32(tx.BillLinkID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		rlog.Error("Error: Callback for unknown bill", "billLinkID", tx.BillLinkID)
+		return errUnknownBill
+	}
 	if err != nil {
-		rlog.Error("Error unmarshalling JSON", "err", err)
-		return
+		rlog.Error("Error: Error retrieving reservation", "billLinkID", tx.BillLinkID, "err", err)
+		return err
+	}
+	if reservation.PaymentProvider != provider.Name() {
+		rlog.Error("Error: Callback from another provider", "billLinkID", tx.BillLinkID, "provider", provider.Name(), "expectedProvider", reservation.PaymentProvider)
+		return errProviderMismatch
+	}
+	if reservation.State != db.ReservationStatePending {
+		rlog.Error("Error: Reservation is no longer pending", "billLinkID", tx.BillLinkID, "state", reservation.State, "status", tx.Status)
+		return errReservationSettled
+	}
+	// reservations made before amounts were stored have 0 and cannot be checked, free orders never get a bill
+	if reservation.Amount != 0 && reservation.Amount != int64(tx.Amount) {
+		rlog.Error("Error: Callback does not match reservation", "billLinkID", tx.BillLinkID, "amount", tx.Amount, "expectedAmount", reservation.Amount)
+		return errAmountMismatch
 	}
 
-	rollbackTickets := func(status string) {
-		rlog.Error("Error: Payment failed", "status", status)
+	switch tx.Status {
+	case TransactionSuccessful:
+	case TransactionFailed, TransactionCancelled, TransactionExpired:
+		state := db.ReservationStateCancelled
+		if tx.Status == TransactionExpired {
+			state = db.ReservationStateExpired
+		}
+		rlog.Error("Error: Payment failed", "status", tx.Status)
 
-		for _, ticketID := range buyTicketData[fmt.Sprintf("reserve:%d", tx.BillLinkID)].TicketIDs {
-			err := query.ChangeTicketsStatus(ctx, db.ChangeTicketsStatusParams{
-				Status:   db.TicketStatusAvailable,
-				TicketID: ticketID,
-			})
-			if err != nil {
-				rlog.Error("Error: Error rolling back tickets status", status, err.Error())
-				return
-			}
+		err := releaseReservation(ctx, qtx, reservation, state, fmt.Sprintf("payment %s", strings.ToLower(tx.Status)))
+		if err != nil {
+			rlog.Error("Error: Error rolling back tickets status", tx.Status, err.Error())
+			return err
+		}
+		rlog.Error("Error: Sold Ticket IDs", "rolled back", reservation.TicketIds)
+
+		if err := dbTX.Commit(ctx); err != nil {
+			rlog.Error("Failed to commit transaction", "err", err)
+			return err
 		}
+		committed = true
 
-		rlog.Error("Error: Sold Ticket IDs", "rolled back", buyTicketData[fmt.Sprintf("reserve:%d", tx.BillLinkID)].TicketIDs)
-		delete(buyTicketData, fmt.Sprintf("reserve:%d", tx.BillLinkID))
+		return nil
+	default:
+		// the payment may still go through, the reconciler checks on it again
+		rlog.Warn("Payment not settled yet", "billLinkID", tx.BillLinkID, "status", tx.Status)
+		return errPaymentNotSettled
 	}
 
-	switch tx.Status {
-	case "SUCCESSFUL":
-		paymentData, err := json.Marshal(tx)
+	attendees, err := reservationAttendees(reservation)
+	if err != nil {
+		rlog.Error("Error: Error unmarshalling attendee data: ", err.Error())
+		return err
+	}
+
+	paymentData, err := json.Marshal(tx)
+	if err != nil {
+		rlog.Error("Error: Error marshalling payment data: ", err.Error())
+		return err
+	}
+
+	_, err = qtx.InsertPayment(ctx, db.InsertPaymentParams{
+		EventID:    reservation.EventID,
+		OrderID:    reservation.OrderID,
+		Data:       paymentData,
+		Name:       tx.SenderName,
+		Email:      tx.SenderEmail,
+		BillLinkID: int32(tx.BillLinkID),
+	})
+	if err != nil {
+		rlog.Error("Error: Error inserting payment: ", err.Error())
+		return err
+	}
+
+	ticket, err := qtx.GetTicket(ctx, reservation.TicketIds[0])
+	if err != nil {
+		rlog.Error("Error: Error getting ticket: ", err.Error())
+		return err
+	}
+	ticketPrice := money.New(ticket.Price, ticket.Currency)
+
+	var items []mailtempl.PurchasedItem
+	if reservation.OrderID.Valid {
+		orderItems, err := qtx.ListOrderItem(ctx, reservation.OrderID)
 		if err != nil {
-			rlog.Error("Error: Error marshalling payment data: ", err.Error())
-			return
+			rlog.Error("Error: Error getting order items: ", err.Error())
+			return err
+		}
+		purchased := make([]OrderItem, 0, len(orderItems))
+		for _, item := range orderItems {
+			purchased = append(purchased, OrderItem{
+				TicketName: item.TicketName,
+				Quantity:   item.Quantity,
+				UnitPrice:  money.New(item.UnitPrice, reservation.Currency),
+			})
 		}
+		items = purchasedItems(purchased)
+	}
 
-		_, err = query.InsertPayment(ctx, db.InsertPaymentParams{
-			EventID:    buyTicketData[fmt.Sprintf("reserve:%d", tx.BillLinkID)].EventID,
-			Data:       paymentData,
-			Name:       tx.SenderName,
-			Email:      tx.SenderEmail,
-			BillLinkID: int32(tx.BillLinkID),
-		})
+	if err := sellTickets(ctx, qtx, reservation.EventID, reservation.TicketIds, attendees); err != nil {
+		return err
+	}
+
+	err = qtx.UpdateReservationState(ctx, db.UpdateReservationStateParams{
+		State:         db.ReservationStatePaid,
+		ReservationID: reservation.ID,
+	})
+	if err != nil {
+		rlog.Error("Error: Error updating reservation state: ", err.Error())
+		return err
+	}
+
+	// reservations made before orders existed only know their total
+	total := money.New(reservation.Amount, reservation.Currency)
+	breakdown := PriceBreakdown{
+		Subtotal: total,
+		Discount: money.New(0, total.Currency),
+		Total:    total,
+	}
+	var promoCode string
+	if reservation.OrderID.Valid {
+		if err := settleOrder(ctx, qtx, reservation.OrderID, tx); err != nil {
+			rlog.Error("Error: Error updating order: ", err.Error())
+			return err
+		}
+
+		order, err := qtx.GetOrder(ctx, reservation.OrderID)
 		if err != nil {
-			rlog.Error("Error: Error inserting payment: ", err.Error())
-			return
+			rlog.Error("Error: Error getting order: ", err.Error())
+			return err
 		}
+		breakdown = orderBreakdown(order)
+		promoCode = order.PromoCode.String
+	}
 
-		ticketPrice := 0
-
-		for _, ticketID := range buyTicketData[fmt.Sprintf("reserve:%d", tx.BillLinkID)].TicketIDs {
-			if ticketPrice == 0 {
-				ticket, err := query.GetTicket(ctx, ticketID)
-				if err != nil {
-					rlog.Error("Error: Error getting ticket: ", err.Error())
-					return
-				}
-				log.Println(ticket.Price)
-				ticketPrice, err = strconv.Atoi(ticket.Price)
-				if err != nil {
-					rlog.Error("Error: Error converting ticket price to int: ", err.Error())
-					return
-				}
-			}
+	// Commit before mailing so a mail outage cannot undo a completed payment
+	if err := dbTX.Commit(ctx); err != nil {
+		rlog.Error("Failed to commit transaction", "err", err)
+		return err
+	}
+	committed = true
+	rlog.Info("Payment successful")
+
+	sendPurchaseMail(ctx, []string{tx.SenderEmail}, mailtempl.PurchaseConfirmation{
+		CustomerName: tx.SenderName,
+		ItemName:     tx.BillTitle,
+		ItemPrice:    ticketPrice,
+		Items:        items,
+		Subtotal:     breakdown.Subtotal,
+		Discount:     breakdown.Discount,
+		PromoCode:    promoCode,
+		Fee:          breakdown.Fee,
+		FeeAbsorbed:  breakdown.FeeAbsorbed,
+		Tax:          breakdown.Tax,
+		TaxName:      breakdown.TaxName,
+		TotalPrice:   breakdown.Total,
+		OrderNumber:  tx.ID,
+	}, reservation.TicketHashes, attendees)
+
+	return nil
+}
 
-			rlog.Info("Processing", "TicketId", ticketID)
-			err = query.ChangeTicketsStatus(ctx, db.ChangeTicketsStatusParams{
-				Status:   db.TicketStatusSold,
-				TicketID: ticketID,
-			})
+// sellTickets marks tickets as sold and registers one attendee per ticket, the n-th attendee on the n-th ticket.
+func sellTickets(ctx context.Context, q *db.Queries, eventID pgtype.UUID, ticketIDs []pgtype.UUID, attendees []*map[string]string) error {
+	// counter types keep their totals on the type, rows of other types are left alone
+	if err := q.SellIssuedTickets(ctx, ticketIDs); err != nil {
+		rlog.Error("Error: Error selling issued tickets: ", err.Error())
+		return err
+	}
 
-			for j := range buyTicketData[fmt.Sprintf("reserve:%d", tx.BillLinkID)].Attendees {
-				attendeeData, err := json.Marshal(buyTicketData[fmt.Sprintf("reserve:%d", tx.BillLinkID)].Attendees[j])
-				if err != nil {
-					rlog.Error("Error: Error marshalling attendee data: ", err.Error())
-					return
-				}
-
-				_, err = query.InsertAttendee(ctx, db.InsertAttendeeParams{
-					EventID:  buyTicketData[fmt.Sprintf("reserve:%d", tx.BillLinkID)].EventID,
-					TicketID: ticketID,
-					Data:     attendeeData,
-				})
-				if err != nil {
-					rlog.Error("Error: Error inserting attendee: ", err.Error())
-					return
-				}
-			}
-			if err != nil {
-				rlog.Error("Error: Error updating tickets status: ", tx.Status, err.Error())
-				return
+	for i, ticketID := range ticketIDs {
+		rlog.Info("Processing", "TicketId", ticketID)
+		err := q.ChangeTicketsStatus(ctx, db.ChangeTicketsStatusParams{
+			Status:   db.TicketStatusSold,
+			TicketID: ticketID,
+		})
+		if err != nil {
+			rlog.Error("Error: Error updating tickets status: ", err.Error())
+			return err
+		}
 
-			}
+		// reservations from before attendees were counted may be short, the ticket still needs a row to be scanned
+		attendee := map[string]string{}
+		if i < len(attendees) && attendees[i] != nil {
+			attendee = *attendees[i]
 		}
-		rlog.Info("Payment successful")
-
-		var buff bytes.Buffer
-		ctx := context.Background()
-		err = mailtempl.PurchaseConfirmationEmail(mailtempl.PurchaseConfirmation{
-			CustomerName: tx.SenderName,
-			ItemName:     tx.BillTitle,
-			ItemPrice:    strconv.Itoa(ticketPrice),
-			TotalPrice:   strconv.Itoa(tx.Amount),
-			OrderNumber:  tx.ID,
-		}).Render(ctx, &buff)
+		attendeeData, err := json.Marshal(attendee)
 		if err != nil {
-			rlog.Error("Error: Error rendering purchase confirmation email: ", err.Error())
-			return
+			rlog.Error("Error: Error marshalling attendee data: ", err.Error())
+			return err
 		}
 
-		body := buff.String()
-		err = mail.SendTicketMail(ctx, &mail.SendTicketMailRequest{
-			Recipients:   []string{tx.SenderEmail},
-			TicketHashes: buyTicketData[fmt.Sprintf("reserve:%d", tx.BillLinkID)].TicketHashes,
-			Body:         body,
+		_, err = q.InsertAttendee(ctx, db.InsertAttendeeParams{
+			EventID:  eventID,
+			TicketID: ticketID,
+			Data:     attendeeData,
 		})
 		if err != nil {
-			rlog.Error("Error: Error sending ticket mail: ", err.Error())
-			return
+			rlog.Error("Error: Error inserting attendee: ", err.Error())
+			return err
 		}
+	}
 
-		delete(buyTicketData, fmt.Sprintf("reserve:%d", tx.BillLinkID))
-	case "FAILED":
-		rollbackTickets(tx.Status)
-	case "CANCELLED":
-		rollbackTickets(tx.Status)
-	case "EXPIRED":
-		rollbackTickets(tx.Status)
-	default:
-		rlog.Error("Error: Unknown payment status", "status", tx.Status)
-		rollbackTickets(tx.Status)
+	return nil
+}
+
+// purchasedItems lists the lines of an order for the confirmation email.
+func purchasedItems(items []OrderItem) []mailtempl.PurchasedItem {
+	purchased := make([]mailtempl.PurchasedItem, 0, len(items))
+	for _, item := range items {
+		purchased = append(purchased, mailtempl.PurchasedItem{
+			Name:      item.TicketName,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			Total:     item.UnitPrice.Mul(int64(item.Quantity)),
+		})
 	}
 
-	// Commit the transaction if all operations are successful
-	if err := dbTX.Commit(ctx); err != nil {
-		rlog.Error("Failed to commit transaction", "err", err)
-		res.WriteHeader(http.StatusInternalServerError)
+	return purchased
+}
+
+// sendPurchaseMail mails the purchase confirmation with the QR codes of the tickets, naming the attendee each code
+// belongs to. A failed email does not undo the purchase, so errors are only logged.
+func sendPurchaseMail(ctx context.Context, recipients []string, data mailtempl.PurchaseConfirmation, ticketHashes []string, attendees []*map[string]string) {
+	labels := make([]string, len(ticketHashes))
+	data.Tickets = make([]mailtempl.PurchasedTicket, 0, len(ticketHashes))
+	for i := range ticketHashes {
+		var attendee map[string]string
+		if i < len(attendees) && attendees[i] != nil {
+			attendee = *attendees[i]
+		}
+		labels[i] = attendeeLabel(attendee, i)
+		data.Tickets = append(data.Tickets, mailtempl.PurchasedTicket{
+			Attendee:   labels[i],
+			Attachment: mail.TicketAttachmentName(i, labels[i]),
+		})
+	}
+
+	var buff bytes.Buffer
+	err := mailtempl.PurchaseConfirmationEmail(data).Render(ctx, &buff)
+	if err != nil {
+		rlog.Error("Error: Error rendering purchase confirmation email: ", err.Error())
 		return
 	}
-	committed = true
 
-	res.WriteHeader(http.StatusOK)
+	err = mail.SendTicketMail(ctx, &mail.SendTicketMailRequest{
+		Recipients:   recipients,
+		TicketHashes: ticketHashes,
+		TicketLabels: labels,
+		Body:         buff.String(),
+	})
+	if err != nil {
+		rlog.Error("Error: Error sending ticket mail: ", err.Error())
+	}
+}
+
+// attendeeNameFields are the ticket inputs most events use for the attendee's name, in order of preference.
+var attendeeNameFields = []string{"name", "full_name", "fullname", "fullName", "email"}
+
+// attendeeLabel picks a human readable name for the i-th attendee of a purchase.
+func attendeeLabel(attendee map[string]string, i int) string {
+	for _, field := range attendeeNameFields {
+		if value := strings.TrimSpace(attendee[field]); value != "" {
+			return value
+		}
+	}
+	return fmt.Sprintf("Attendee %d", i+1)
 }
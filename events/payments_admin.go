@@ -0,0 +1,315 @@
+package events
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"encore.dev"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+	"github.com/lichtlabs/ggrims-service/money"
+)
+
+// exportBatchSize is how many payments the export reads per query.
+const exportBatchSize = 500
+
+// ListPaymentsQuery pages through an event's payments like any other list. From and To take a date (2006-01-02,
+// To is inclusive) or an RFC 3339 timestamp, Email matches the payer's email case-insensitively and Status is one
+// of paid, partially_refunded or refunded.
+type ListPaymentsQuery struct {
+	ListQuery
+	From   string `query:"from"`
+	To     string `query:"to"`
+	Email  string `query:"email"`
+	Status string `query:"status"`
+}
+
+// PaymentAttendee represents an attendee registered on a paid ticket.
+type PaymentAttendee struct {
+	ID     pgtype.UUID       `json:"id"`
+	Status db.AttendeeStatus `json:"status"`
+	Data   map[string]string `json:"data"`
+}
+
+// PaymentTicket represents a ticket bought with a payment and its attendees.
+type PaymentTicket struct {
+	ID        pgtype.UUID       `json:"id"`
+	Name      string            `json:"name"`
+	Status    db.TicketStatus   `json:"status"`
+	Attendees []PaymentAttendee `json:"attendees"`
+}
+
+// PaymentDetail combines a payment with the tickets it bought.
+type PaymentDetail struct {
+	Payment
+	Tickets []PaymentTicket `json:"tickets"`
+}
+
+func toPayment(payment db.PaymentSummary) Payment {
+	var transaction Transaction
+	if err := json.Unmarshal(payment.Data, &transaction); err != nil {
+		rlog.Error("An error occurred while decoding payment data", "paymentID", payment.ID, "err", err.Error())
+	}
+
+	return Payment{
+		ID:            payment.ID,
+		EventID:       payment.EventID,
+		OrderID:       payment.OrderID,
+		Name:          payment.Name,
+		Email:         payment.Email,
+		BillLinkID:    payment.BillLinkID,
+		TransactionID: transaction.ID,
+		Amount:        money.New(payment.Amount, payment.Currency),
+		Status:        payment.Status,
+		CreatedAt:     payment.CreatedAt,
+	}
+}
+
+// listPaymentsParams turns the filters of a ListPaymentsQuery into query parameters.
+func listPaymentsParams(eventID uuid.UUID, params *ListPaymentsQuery) (db.ListPaymentParams, error) {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	from, err := parseDateFilter(params.From, false)
+	if err != nil {
+		return db.ListPaymentParams{}, eb.Cause(err).Msg("Invalid from date").Err()
+	}
+	to, err := parseDateFilter(params.To, true)
+	if err != nil {
+		return db.ListPaymentParams{}, eb.Cause(err).Msg("Invalid to date").Err()
+	}
+
+	extractedParam := extractQuery(&params.ListQuery)
+
+	return db.ListPaymentParams{
+		EventID: pgtype.UUID{
+			Bytes: eventID,
+			Valid: true,
+		},
+		CreatedFrom: from,
+		CreatedTo:   to,
+		Email: pgtype.Text{
+			String: params.Email,
+			Valid:  params.Email != "",
+		},
+		Status: pgtype.Text{
+			String: params.Status,
+			Valid:  params.Status != "",
+		},
+		OrderBy: extractedParam.OrderBy,
+		Offsets: extractedParam.Page,
+		Limits:  extractedParam.Limit,
+	}, nil
+}
+
+// parseDateFilter parses a date or RFC 3339 timestamp. A plain date used as an upper bound includes the whole day.
+func parseDateFilter(value string, endOfDay bool) (pgtype.Timestamptz, error) {
+	if value == "" {
+		return pgtype.Timestamptz{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, value)
+		if err != nil {
+			return pgtype.Timestamptz{}, err
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+
+	return pgtype.Timestamptz{
+		Time:  t,
+		Valid: true,
+	}, nil
+}
+
+// ListEventPayments List payments made for an event
+//
+//encore:api auth method=GET path=/v1/events/:id/payments
+func ListEventPayments(ctx context.Context, id uuid.UUID, params *ListPaymentsQuery) (*BaseResponse[[]Payment], error) {
+	eb := errs.B()
+
+	queryParams, err := listPaymentsParams(id, params)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := query.ListPayment(ctx, queryParams)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving payments", "ListEventPayments:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving payments").Err()
+	}
+
+	payments := make([]Payment, 0, len(data))
+	for _, payment := range data {
+		payments = append(payments, toPayment(payment))
+	}
+
+	return &BaseResponse[[]Payment]{
+		Data:    payments,
+		Message: "Payments retrieved successfully",
+	}, nil
+}
+
+// GetPayment Get a payment including the tickets it bought and their attendees
+//
+//encore:api auth method=GET path=/v1/payments/:id
+func GetPayment(ctx context.Context, id uuid.UUID) (*BaseResponse[PaymentDetail], error) {
+	eb := errs.B()
+
+	payment, err := query.GetPayment(ctx, pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Payment not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving payment", "GetPayment:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving payment").Err()
+	}
+
+	detail := PaymentDetail{
+		Payment: toPayment(payment),
+		Tickets: []PaymentTicket{},
+	}
+
+	reservation, err := query.GetReservation(ctx, payment.BillLinkID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// payments from before reservations were stored cannot be traced to their tickets
+		return &BaseResponse[PaymentDetail]{
+			Data:    detail,
+			Message: "Payment retrieved successfully",
+		}, nil
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving reservation", "GetPayment:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving payment tickets").Err()
+	}
+
+	tickets, err := query.ListTicketsByID(ctx, reservation.TicketIds)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving tickets", "GetPayment:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving payment tickets").Err()
+	}
+
+	attendees, err := query.ListTicketAttendees(ctx, reservation.TicketIds)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving attendees", "GetPayment:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving payment attendees").Err()
+	}
+
+	ticketAttendees := map[pgtype.UUID][]PaymentAttendee{}
+	for _, attendee := range attendees {
+		var data map[string]string
+		if err := json.Unmarshal(attendee.Data, &data); err != nil {
+			rlog.Error("An error occurred while decoding attendee data", "GetPayment:err", err.Error())
+		}
+		ticketAttendees[attendee.TicketID] = append(ticketAttendees[attendee.TicketID], PaymentAttendee{
+			ID:     attendee.ID,
+			Status: attendee.Status,
+			Data:   data,
+		})
+	}
+
+	for _, ticket := range tickets {
+		ticketData := PaymentTicket{
+			ID:        ticket.ID,
+			Name:      ticket.Name,
+			Status:    ticket.Status,
+			Attendees: ticketAttendees[ticket.ID],
+		}
+		if ticketData.Attendees == nil {
+			ticketData.Attendees = []PaymentAttendee{}
+		}
+		detail.Tickets = append(detail.Tickets, ticketData)
+	}
+
+	return &BaseResponse[PaymentDetail]{
+		Data:    detail,
+		Message: "Payment retrieved successfully",
+	}, nil
+}
+
+// ExportEventPayments Export an event's payments as CSV, using the same filters as ListEventPayments without paging
+//
+//encore:api auth raw method=GET path=/v1/events/:id/payments/export
+func ExportEventPayments(res http.ResponseWriter, req *http.Request) {
+	eventID, err := uuid.FromString(encore.CurrentRequest().PathParams.Get("id"))
+	if err != nil {
+		http.Error(res, "invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	values := req.URL.Query()
+	queryParams, err := listPaymentsParams(eventID, &ListPaymentsQuery{
+		ListQuery: ListQuery{
+			OrderBy: values.Get("order_by"),
+		},
+		From:   values.Get("from"),
+		To:     values.Get("to"),
+		Email:  values.Get("email"),
+		Status: values.Get("status"),
+	})
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/csv")
+	res.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="payments-%s.csv"`, eventID))
+
+	w := csv.NewWriter(res)
+	_ = w.Write([]string{"id", "created_at", "name", "email", "amount", "currency", "status", "bill_link_id", "transaction_id", "order_id"})
+
+	queryParams.Limits = exportBatchSize
+	for offset := int32(0); ; offset += exportBatchSize {
+		queryParams.Offsets = offset
+		data, err := query.ListPayment(req.Context(), queryParams)
+		if err != nil {
+			// the header is already sent, so a failure can only cut the file short
+			rlog.Error("An error occurred while exporting payments", "ExportEventPayments:err", err.Error())
+			break
+		}
+
+		for _, row := range data {
+			payment := toPayment(row)
+			orderID := ""
+			if payment.OrderID.Valid {
+				orderID = uuid.UUID(payment.OrderID.Bytes).String()
+			}
+			_ = w.Write([]string{
+				uuid.UUID(payment.ID.Bytes).String(),
+				payment.CreatedAt.Time.Format(time.RFC3339),
+				payment.Name,
+				payment.Email,
+				strconv.FormatInt(payment.Amount.Amount, 10),
+				payment.Amount.Currency,
+				payment.Status,
+				strconv.Itoa(int(payment.BillLinkID)),
+				payment.TransactionID,
+				orderID,
+			})
+		}
+
+		if len(data) < exportBatchSize {
+			break
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		rlog.Error("An error occurred while writing payments export", "ExportEventPayments:err", err.Error())
+	}
+}
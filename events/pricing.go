@@ -0,0 +1,166 @@
+package events
+
+import (
+	"encore.dev/beta/errs"
+	"github.com/lichtlabs/ggrims-service/events/db"
+	"github.com/lichtlabs/ggrims-service/money"
+)
+
+// defaultFeeValue is the per ticket service fee, in minor units, events start with.
+const defaultFeeValue = 1000
+
+// FeeRule describes the service fee charged on an event's orders.
+//
+// Value is in minor units of the ticket currency for flat fees and in basis points (1/100 of a percent) of the
+// ticket price for percentage fees. An absorbed fee is paid by the organizer out of the ticket price instead of
+// being added to what the buyer pays.
+type FeeRule struct {
+	Type     db.FeeType `json:"type"`
+	Value    int64      `json:"value"`
+	Per      db.FeePer  `json:"per"`
+	Absorbed bool       `json:"absorbed"`
+}
+
+// TaxRule describes the tax, such as PPN, added to an event's orders. Rate is in basis points; zero means no tax.
+type TaxRule struct {
+	Name string `json:"name"`
+	Rate int32  `json:"rate"`
+}
+
+// PriceBreakdown itemizes what an order costs. Total is what the buyer pays: the subtotal less the discount, the
+// fee unless the organizer absorbs it, and tax on both.
+type PriceBreakdown struct {
+	Subtotal    money.Money `json:"subtotal"`
+	Discount    money.Money `json:"discount"`
+	Fee         money.Money `json:"fee"`
+	FeeAbsorbed bool        `json:"fee_absorbed"`
+	Tax         money.Money `json:"tax"`
+	TaxName     string      `json:"tax_name"`
+	Total       money.Money `json:"total"`
+}
+
+// defaultFeeRule returns the fee rule of events created without one.
+func defaultFeeRule() FeeRule {
+	return FeeRule{
+		Type:  db.FeeTypeFlat,
+		Value: defaultFeeValue,
+		Per:   db.FeePerTicket,
+	}
+}
+
+// validateFeeRule rejects unknown fee types and scopes, negative values and percentages above 100%.
+func validateFeeRule(fee FeeRule) error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	switch fee.Type {
+	case db.FeeTypeFlat, db.FeeTypePercentage:
+	default:
+		return eb.Msgf("Unknown fee type %q", fee.Type).Err()
+	}
+	switch fee.Per {
+	case db.FeePerTicket, db.FeePerOrder:
+	default:
+		return eb.Msgf("Unknown fee scope %q", fee.Per).Err()
+	}
+	if fee.Value < 0 {
+		return eb.Msg("Fee must not be negative").Err()
+	}
+	if fee.Type == db.FeeTypePercentage && fee.Value > 10000 {
+		return eb.Msg("Percentage fee must not exceed 10000 basis points").Err()
+	}
+
+	return nil
+}
+
+// validateTaxRate rejects tax rates outside 0 to 100%.
+func validateTaxRate(rate int32) error {
+	if rate < 0 || rate > 10000 {
+		return errs.B().Code(errs.InvalidArgument).Msg("Tax rate must be between 0 and 10000 basis points").Err()
+	}
+
+	return nil
+}
+
+// pricingRules reads the fee and tax rules stored on an event.
+func pricingRules(row db.GetEventPricingRow) (FeeRule, TaxRule) {
+	fee := FeeRule{
+		Type:     row.FeeType,
+		Value:    row.FeeValue,
+		Per:      row.FeePer,
+		Absorbed: row.FeeAbsorbed,
+	}
+	tax := TaxRule{
+		Name: row.TaxName,
+		Rate: row.TaxRate,
+	}
+
+	return fee, tax
+}
+
+// priceLine is one ticket type of an order: how many tickets are bought at which price.
+type priceLine struct {
+	UnitPrice money.Money
+	Quantity  int
+}
+
+// priceOrder computes the breakdown of buying the given lines under the given rules, less discount. All lines and
+// the discount must be in the same currency. Free tickets are never charged a fee, and an order that is free after
+// its discount is not charged a fee at all.
+func priceOrder(lines []priceLine, fee FeeRule, tax TaxRule, discount money.Money) PriceBreakdown {
+	currency := lines[0].UnitPrice.Currency
+	subtotal := money.New(0, currency)
+	feeAmount := money.New(0, currency)
+
+	for _, line := range lines {
+		quantity := int64(line.Quantity)
+		subtotal = subtotal.Add(line.UnitPrice.Mul(quantity))
+
+		switch {
+		case line.UnitPrice.IsZero() || fee.Per != db.FeePerTicket:
+		case fee.Type == db.FeeTypeFlat:
+			feeAmount = feeAmount.Add(money.New(fee.Value, currency).Mul(quantity))
+		default:
+			feeAmount = feeAmount.Add(line.UnitPrice.Percent(fee.Value).Mul(quantity))
+		}
+	}
+
+	payable := subtotal.Sub(discount)
+	switch {
+	case payable.IsZero():
+		feeAmount = money.New(0, currency)
+	case fee.Per != db.FeePerOrder:
+	case fee.Type == db.FeeTypeFlat:
+		feeAmount = money.New(fee.Value, currency)
+	default:
+		feeAmount = payable.Percent(fee.Value)
+	}
+
+	taxable := payable
+	if !fee.Absorbed {
+		taxable = taxable.Add(feeAmount)
+	}
+	taxAmount := taxable.Percent(int64(tax.Rate))
+
+	return PriceBreakdown{
+		Subtotal:    subtotal,
+		Discount:    discount,
+		Fee:         feeAmount,
+		FeeAbsorbed: fee.Absorbed,
+		Tax:         taxAmount,
+		TaxName:     tax.Name,
+		Total:       taxable.Add(taxAmount),
+	}
+}
+
+// orderBreakdown reads the price breakdown stored on an order.
+func orderBreakdown(order db.Order) PriceBreakdown {
+	return PriceBreakdown{
+		Subtotal:    money.New(order.Subtotal, order.Currency),
+		Discount:    money.New(order.Discount, order.Currency),
+		Fee:         money.New(order.Fee, order.Currency),
+		FeeAbsorbed: order.FeeAbsorbed,
+		Tax:         money.New(order.Tax, order.Currency),
+		TaxName:     order.TaxName,
+		Total:       money.New(order.Amount, order.Currency),
+	}
+}
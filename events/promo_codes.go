@@ -0,0 +1,467 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+	"github.com/lichtlabs/ggrims-service/money"
+)
+
+// maxCodeLength is the longest promo or access code an admin can create.
+const maxCodeLength = 64
+
+// PromoCode is a code buyers enter to get a discount on an event's tickets.
+//
+// Value is in minor units of Currency for flat discounts and in basis points (1/100 of a percent) of the ticket
+// price for percentage discounts. A code without ticket types applies to every type of the event.
+type PromoCode struct {
+	ID            pgtype.UUID     `json:"id"`
+	EventID       pgtype.UUID     `json:"event_id"`
+	Code          string          `json:"code"`
+	DiscountType  db.DiscountType `json:"discount_type"`
+	Value         int64           `json:"value"`
+	Currency      string          `json:"currency"`
+	TicketTypeIDs []pgtype.UUID   `json:"ticket_type_ids"`
+	// MaxUses caps how many orders can use the code, 0 means unlimited. Orders awaiting payment count as used.
+	MaxUses   int32              `json:"max_uses"`
+	UsedCount int32              `json:"used_count"`
+	StartsAt  pgtype.Timestamptz `json:"starts_at"`
+	EndsAt    pgtype.Timestamptz `json:"ends_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+func toPromoCode(promo db.PromoCode) PromoCode {
+	return PromoCode{
+		ID:            promo.ID,
+		EventID:       promo.EventID,
+		Code:          promo.Code,
+		DiscountType:  promo.DiscountType,
+		Value:         promo.Value,
+		Currency:      promo.Currency,
+		TicketTypeIDs: promo.TicketTypeIds,
+		MaxUses:       promo.MaxUses,
+		UsedCount:     promo.UsedCount,
+		StartsAt:      promo.StartsAt,
+		EndsAt:        promo.EndsAt,
+		CreatedAt:     promo.CreatedAt,
+		UpdatedAt:     promo.UpdatedAt,
+	}
+}
+
+// CreatePromoCodeRequest describes a new promo code of an event.
+type CreatePromoCodeRequest struct {
+	Code         string          `json:"code"`
+	DiscountType db.DiscountType `json:"discount_type"`
+	Value        int64           `json:"value"`
+	// Currency of a flat discount, it defaults to IDR
+	Currency string `json:"currency"`
+	// TicketTypeIDs limits the code to these types of the event, left out it applies to all of them
+	TicketTypeIDs []uuid.UUID `json:"ticket_type_ids"`
+	MaxUses       int32       `json:"max_uses"`
+	// StartsAt and EndsAt bound when the code can be used, left out it can be used right away and until sales end
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// Validate requires a code and checks the discount, usage cap and validity window.
+func (req *CreatePromoCodeRequest) Validate() error {
+	if err := validateCodeName(req.Code); err != nil {
+		return err
+	}
+	if err := validateDiscount(req.DiscountType, req.Value); err != nil {
+		return err
+	}
+	if req.Currency != "" && !money.IsSupported(req.Currency) {
+		return errs.B().Code(errs.InvalidArgument).Msgf("Unsupported currency %q", req.Currency).Err()
+	}
+	if req.MaxUses < 0 {
+		return errs.B().Code(errs.InvalidArgument).Msg("Max uses must not be negative").Err()
+	}
+	return validateCodeWindow(req.StartsAt, req.EndsAt)
+}
+
+// UpdatePromoCodeRequest changes a promo code. Fields left out stay as they are.
+type UpdatePromoCodeRequest struct {
+	Code         *string          `json:"code"`
+	DiscountType *db.DiscountType `json:"discount_type"`
+	Value        *int64           `json:"value"`
+	Currency     *string          `json:"currency"`
+	// TicketTypeIDs replaces the types the code applies to, an empty list makes it apply to all of them
+	TicketTypeIDs []uuid.UUID `json:"ticket_type_ids"`
+	MaxUses       *int32      `json:"max_uses"`
+	StartsAt      *time.Time  `json:"starts_at"`
+	EndsAt        *time.Time  `json:"ends_at"`
+}
+
+// Validate checks the fields that are set. The discount and the validity window are checked once merged with the
+// code.
+func (req *UpdatePromoCodeRequest) Validate() error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if req.Code != nil {
+		if err := validateCodeName(*req.Code); err != nil {
+			return err
+		}
+	}
+	if req.Currency != nil && !money.IsSupported(*req.Currency) {
+		return eb.Msgf("Unsupported currency %q", *req.Currency).Err()
+	}
+	if req.MaxUses != nil && *req.MaxUses < 0 {
+		return eb.Msg("Max uses must not be negative").Err()
+	}
+
+	return nil
+}
+
+// validateCodeName requires a code of at most maxCodeLength characters without spaces.
+func validateCodeName(code string) error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if code == "" {
+		return eb.Msg("Code is required").Err()
+	}
+	if len(code) > maxCodeLength {
+		return eb.Msgf("Code must be at most %d characters", maxCodeLength).Err()
+	}
+	if strings.ContainsAny(code, " \t\n") {
+		return eb.Msg("Code must not contain spaces").Err()
+	}
+
+	return nil
+}
+
+// validateDiscount rejects unknown discount types, negative values and percentages above 100%.
+func validateDiscount(discountType db.DiscountType, value int64) error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	switch discountType {
+	case db.DiscountTypeFlat, db.DiscountTypePercentage:
+	default:
+		return eb.Msgf("Unknown discount type %q", discountType).Err()
+	}
+	if value < 0 {
+		return eb.Msg("Discount must not be negative").Err()
+	}
+	if discountType == db.DiscountTypePercentage && value > 10000 {
+		return eb.Msg("Percentage discount must not exceed 10000 basis points").Err()
+	}
+
+	return nil
+}
+
+// validateCodeWindow rejects a validity window that closes before it opens.
+func validateCodeWindow(start, end *time.Time) error {
+	if start != nil && end != nil && !start.Before(*end) {
+		return errs.B().Code(errs.InvalidArgument).Msg("starts_at must be before ends_at").Err()
+	}
+
+	return nil
+}
+
+// eventTicketTypes converts the ticket types a code is limited to, rejecting types that are not of the event.
+func eventTicketTypes(ctx context.Context, q *db.Queries, eventID pgtype.UUID, ids []uuid.UUID) ([]pgtype.UUID, error) {
+	if ids == nil {
+		return nil, nil
+	}
+
+	ticketTypes, err := q.ListTicketType(ctx, eventID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket types", "eventTicketTypes:err", err.Error())
+		return nil, errs.B().Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving ticket types").Err()
+	}
+
+	typeIDs := make([]pgtype.UUID, 0, len(ids))
+	for _, id := range ids {
+		typeID := pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		}
+		if !slices.ContainsFunc(ticketTypes, func(ticketType db.ListTicketTypeRow) bool { return ticketType.ID == typeID }) {
+			return nil, errs.B().Code(errs.InvalidArgument).Msgf("Ticket type %s is not of this event", id).Err()
+		}
+		typeIDs = append(typeIDs, typeID)
+	}
+
+	return typeIDs, nil
+}
+
+// checkPromoCode rejects a code that is not valid yet, no longer valid or used up.
+func checkPromoCode(promo db.PromoCode, now time.Time) error {
+	eb := errs.B().Code(errs.FailedPrecondition)
+
+	if promo.StartsAt.Valid && now.Before(promo.StartsAt.Time) {
+		return eb.Msgf("Promo code %s can be used from %s", promo.Code, promo.StartsAt.Time.Format(time.RFC3339)).Err()
+	}
+	if promo.EndsAt.Valid && !now.Before(promo.EndsAt.Time) {
+		return eb.Msgf("Promo code %s has expired", promo.Code).Err()
+	}
+	if promo.MaxUses > 0 && promo.UsedCount >= promo.MaxUses {
+		return eb.Msgf("Promo code %s has been used up", promo.Code).Err()
+	}
+
+	return nil
+}
+
+// promoApplies reports whether a code discounts tickets of the given type.
+func promoApplies(promo db.PromoCode, ticketTypeID pgtype.UUID) bool {
+	return len(promo.TicketTypeIds) == 0 || slices.Contains(promo.TicketTypeIds, ticketTypeID)
+}
+
+// promoDiscount returns the discount a code gives on eligible, the subtotal of the tickets it applies to. The
+// discount never exceeds eligible, so a code cannot make an order cost less than nothing.
+func promoDiscount(promo db.PromoCode, eligible money.Money) (money.Money, error) {
+	discount := eligible.Percent(promo.Value)
+	if promo.DiscountType == db.DiscountTypeFlat {
+		if promo.Currency != eligible.Currency {
+			return money.Money{}, errs.B().Code(errs.InvalidArgument).Msgf("Promo code %s is for orders in %s", promo.Code, promo.Currency).Err()
+		}
+		discount = money.New(promo.Value, promo.Currency)
+	}
+
+	if discount.Amount > eligible.Amount {
+		return eligible, nil
+	}
+	return discount, nil
+}
+
+// redeemPromoCode uses up one use of a code for an order. The use is given back when the order's reservation is
+// released.
+func redeemPromoCode(ctx context.Context, q *db.Queries, promo db.PromoCode, orderID pgtype.UUID, discount money.Money) error {
+	eb := errs.B()
+
+	// the use is only counted while the code has uses left, so concurrent orders cannot overrun the cap
+	used, err := q.UsePromoCode(ctx, promo.ID)
+	if err != nil {
+		return eb.Cause(err).Code(errs.Internal).Msg("An error occurred while redeeming the promo code").Err()
+	}
+	if used == 0 {
+		return eb.Code(errs.ResourceExhausted).Msgf("Promo code %s has been used up", promo.Code).Err()
+	}
+
+	err = q.InsertPromoCodeRedemption(ctx, db.InsertPromoCodeRedemptionParams{
+		PromoCodeID: promo.ID,
+		OrderID:     orderID,
+		Discount:    discount.Amount,
+		Currency:    discount.Currency,
+	})
+	if err != nil {
+		return eb.Cause(err).Code(errs.Internal).Msg("An error occurred while redeeming the promo code").Err()
+	}
+
+	return nil
+}
+
+// CreatePromoCode Create a promo code for an event
+//
+//encore:api auth method=POST path=/v1/events/:id/promo-codes
+func CreatePromoCode(ctx context.Context, id uuid.UUID, req *CreatePromoCodeRequest) (*BaseResponse[PromoCode], error) {
+	eb := errs.B()
+
+	eventID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	ticketTypeIDs, err := eventTicketTypes(ctx, query, eventID, req.TicketTypeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	promo, err := query.InsertPromoCode(ctx, db.InsertPromoCodeParams{
+		EventID:       eventID,
+		Code:          req.Code,
+		DiscountType:  req.DiscountType,
+		Value:         req.Value,
+		Currency:      money.New(req.Value, req.Currency).Currency,
+		TicketTypeIds: ticketTypeIDs,
+		MaxUses:       req.MaxUses,
+		StartsAt:      timestamptz(req.StartsAt),
+		EndsAt:        timestamptz(req.EndsAt),
+	})
+	if isUniqueViolation(err) {
+		return nil, eb.Code(errs.AlreadyExists).Msgf("The event already has a promo code %q", req.Code).Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while creating promo code", "CreatePromoCode:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while creating promo code").Err()
+	}
+
+	return &BaseResponse[PromoCode]{
+		Data:    toPromoCode(promo),
+		Message: "Promo code created successfully",
+	}, nil
+}
+
+// ListPromoCodes List the promo codes of an event with how often they were used
+//
+//encore:api auth method=GET path=/v1/events/:id/promo-codes
+func ListPromoCodes(ctx context.Context, id uuid.UUID, params *ListQuery) (*BaseResponse[[]PromoCode], error) {
+	eb := errs.B()
+
+	extractedParam := extractQuery(params)
+	data, err := query.ListPromoCode(ctx, db.ListPromoCodeParams{
+		EventID: pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		},
+		Offsets: extractedParam.Page,
+		Limits:  extractedParam.Limit,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while retrieving promo codes", "ListPromoCodes:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving promo codes").Err()
+	}
+
+	promoCodes := make([]PromoCode, 0, len(data))
+	for _, promo := range data {
+		promoCodes = append(promoCodes, toPromoCode(promo))
+	}
+
+	return &BaseResponse[[]PromoCode]{
+		Data:    promoCodes,
+		Message: "Promo codes retrieved successfully",
+	}, nil
+}
+
+// GetPromoCode Get a promo code
+//
+//encore:api auth method=GET path=/v1/promo-codes/:id
+func GetPromoCode(ctx context.Context, id uuid.UUID) (*BaseResponse[PromoCode], error) {
+	eb := errs.B()
+
+	promo, err := query.GetPromoCode(ctx, pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Promo code not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving promo code", "GetPromoCode:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving promo code").Err()
+	}
+
+	return &BaseResponse[PromoCode]{
+		Data:    toPromoCode(promo),
+		Message: "Promo code retrieved successfully",
+	}, nil
+}
+
+// UpdatePromoCode Update a promo code
+//
+// Orders that already used the code keep the discount they got.
+//
+//encore:api auth method=PUT path=/v1/promo-codes/:id
+func UpdatePromoCode(ctx context.Context, id uuid.UUID, req *UpdatePromoCodeRequest) (*BaseResponse[PromoCode], error) {
+	eb := errs.B()
+
+	promoCodeID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	current, err := query.GetPromoCode(ctx, promoCodeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Promo code not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving promo code", "UpdatePromoCode:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving promo code").Err()
+	}
+
+	discountType, value := current.DiscountType, current.Value
+	if req.DiscountType != nil {
+		discountType = *req.DiscountType
+	}
+	if req.Value != nil {
+		value = *req.Value
+	}
+	if err := validateDiscount(discountType, value); err != nil {
+		return nil, err
+	}
+	startsAt, endsAt := timestampPtr(current.StartsAt), timestampPtr(current.EndsAt)
+	if req.StartsAt != nil {
+		startsAt = req.StartsAt
+	}
+	if req.EndsAt != nil {
+		endsAt = req.EndsAt
+	}
+	if err := validateCodeWindow(startsAt, endsAt); err != nil {
+		return nil, err
+	}
+
+	params := db.UpdatePromoCodeParams{
+		PromoCodeID: promoCodeID,
+		StartsAt:    timestamptz(req.StartsAt),
+		EndsAt:      timestamptz(req.EndsAt),
+	}
+	if req.Code != nil {
+		params.Code = pgtype.Text{String: *req.Code, Valid: true}
+	}
+	if req.DiscountType != nil {
+		params.DiscountType = db.NullDiscountType{DiscountType: *req.DiscountType, Valid: true}
+	}
+	if req.Value != nil {
+		params.Value = pgtype.Int8{Int64: *req.Value, Valid: true}
+	}
+	if req.Currency != nil {
+		params.Currency = pgtype.Text{String: *req.Currency, Valid: true}
+	}
+	if req.MaxUses != nil {
+		params.MaxUses = pgtype.Int4{Int32: *req.MaxUses, Valid: true}
+	}
+	params.TicketTypeIds, err = eventTicketTypes(ctx, query, current.EventID, req.TicketTypeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	promo, err := query.UpdatePromoCode(ctx, params)
+	if isUniqueViolation(err) {
+		return nil, eb.Code(errs.AlreadyExists).Msgf("The event already has a promo code %q", *req.Code).Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while updating promo code", "UpdatePromoCode:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating promo code").Err()
+	}
+
+	return &BaseResponse[PromoCode]{
+		Data:    toPromoCode(promo),
+		Message: "Promo code updated successfully",
+	}, nil
+}
+
+// DeletePromoCode Delete a promo code, orders that used it keep their discount
+//
+//encore:api auth method=DELETE path=/v1/promo-codes/:id
+func DeletePromoCode(ctx context.Context, id uuid.UUID) (*BaseResponse[DeletesResponse], error) {
+	eb := errs.B()
+
+	deleted, err := query.DeletePromoCode(ctx, pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while deleting promo code", "DeletePromoCode:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while deleting promo code").Err()
+	}
+	if deleted == 0 {
+		return nil, eb.Code(errs.NotFound).Msg("Promo code not found").Err()
+	}
+
+	return &BaseResponse[DeletesResponse]{
+		Data: DeletesResponse{
+			Deleted: int(deleted),
+		},
+		Message: "Promo code deleted successfully",
+	}, nil
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"encore.dev"
+)
+
+// Payment provider names as stored on events and reservations.
+const (
+	PaymentProviderFlip = "flip"
+	PaymentProviderFake = "fake"
+	// PaymentProviderNone is recorded on free orders, which are confirmed without a provider.
+	PaymentProviderNone = "none"
+)
+
+// Transaction statuses reported by payment providers. Providers translate their own statuses into these.
+const (
+	TransactionPending    = "PENDING"
+	TransactionSuccessful = "SUCCESSFUL"
+	TransactionFailed     = "FAILED"
+	TransactionCancelled  = "CANCELLED"
+	TransactionExpired    = "EXPIRED"
+)
+
+// Refund statuses reported by payment providers. Providers translate their own statuses into these.
+const (
+	RefundPending    = "PENDING"
+	RefundSuccessful = "SUCCESSFUL"
+	RefundFailed     = "FAILED"
+)
+
+var (
+	// ErrInvalidCallback is returned by PaymentProvider.ParseCallback when a callback cannot be authenticated.
+	ErrInvalidCallback = errors.New("payment callback could not be verified")
+	// ErrRefundNotSupported is returned by PaymentProvider.Refund when the provider cannot refund through its API.
+	ErrRefundNotSupported = errors.New("payment provider does not support refunds")
+)
+
+// PaymentProvider is a payment gateway that can bill buyers for tickets.
+type PaymentProvider interface {
+	// Name returns the identifier stored on events and reservations.
+	Name() string
+	// CreateBill creates a payment link the buyer is redirected to.
+	CreateBill(ctx context.Context, req *CreateBillRequest) (*CreateBillResponse, error)
+	// ParseCallback verifies an incoming payment notification and decodes the transaction it reports.
+	ParseCallback(req *http.Request) (*Transaction, error)
+	// BillPayments returns the transactions the provider has recorded for a bill.
+	BillPayments(ctx context.Context, billLinkID int) ([]Transaction, error)
+	// Refund returns the money of a successful transaction to the buyer.
+	Refund(ctx context.Context, req *RefundRequest) (*RefundResponse, error)
+}
+
+// RefundRequest represents the parameters required to refund a transaction.
+type RefundRequest struct {
+	BillLinkID    int    `json:"bill_link_id"`
+	TransactionID string `json:"transaction_id"`
+	Amount        int    `json:"amount"`
+	Reason        string `json:"reason"`
+	// Reference identifies the refund, a provider seeing it again returns the earlier refund instead of paying twice
+	Reference string `json:"reference"`
+}
+
+// RefundResponse represents the provider's record of a refund.
+type RefundResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+var (
+	flip    = &flipProvider{}
+	fakePay = newFakeProvider()
+)
+
+// paymentProvider returns the provider registered under name.
+// The fake provider is only available when running locally or in tests.
+func paymentProvider(name string) (PaymentProvider, error) {
+	switch name {
+	case PaymentProviderFlip:
+		return flip, nil
+	case PaymentProviderFake:
+		if fakeProviderEnabled() {
+			return fakePay, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown payment provider %q", name)
+}
+
+// fakeProviderEnabled reports whether the fake provider may be used in the current environment.
+func fakeProviderEnabled() bool {
+	env := encore.Meta().Environment
+	return env.Cloud == encore.CloudLocal || env.Type == encore.EnvTest
+}
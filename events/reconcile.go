@@ -0,0 +1,289 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+)
+
+// reconcileGracePeriod gives the provider's callback a head start before a pending reservation is polled.
+const reconcileGracePeriod = 1 * time.Minute
+
+// reconcileWindow is how long after a reservation stops being pending it is still checked against the provider.
+const reconcileWindow = 24 * time.Hour
+
+// reconcileBatchSize caps how many reservations a single reconciliation run checks.
+const reconcileBatchSize = 200
+
+// Kinds of payment mismatches flagged by reconciliation.
+const (
+	MismatchAmount            = "amount_mismatch"
+	MismatchPaidAfterRelease  = "paid_after_release"
+	MismatchMissingPayment    = "missing_payment"
+	MismatchNotPaidAtProvider = "not_paid_at_provider"
+)
+
+// Actions taken when reconciling a reservation.
+const (
+	ReconcileUnchanged = "unchanged"
+	ReconcileSettled   = "settled"
+	ReconcileReleased  = "released"
+	ReconcileFlagged   = "flagged"
+)
+
+// Poll the provider every minute so a lost callback is picked up before the reservation expires.
+var _ = cron.NewJob("reconcile-payments", cron.JobConfig{
+	Title:    "Reconcile reservations with the payment provider",
+	Every:    1 * cron.Minute,
+	Endpoint: ReconcilePayments,
+})
+
+// ReconcileResult reports what reconciling a single bill did.
+type ReconcileResult struct {
+	BillLinkID int                 `json:"bill_link_id"`
+	State      db.ReservationState `json:"state"`
+	Action     string              `json:"action"`
+	Mismatches []string            `json:"mismatches"`
+}
+
+// ReconcilePaymentsResponse summarises a reconciliation run.
+type ReconcilePaymentsResponse struct {
+	Checked  int `json:"checked"`
+	Settled  int `json:"settled"`
+	Released int `json:"released"`
+	Flagged  int `json:"flagged"`
+	Failed   int `json:"failed"`
+}
+
+// ReconcilePayments asks the payment provider about every pending reservation whose callback has not arrived,
+// and settles or releases it the same way the callback would. Reservations that are no longer pending are checked
+// once more, so payments the provider recorded after the tickets were released, or payments we never stored,
+// are flagged as mismatches.
+//
+//encore:api private
+func ReconcilePayments(ctx context.Context) (*ReconcilePaymentsResponse, error) {
+	eb := errs.B()
+
+	reservations, err := query.ListReservationsToReconcile(ctx, db.ListReservationsToReconcileParams{
+		PendingBefore: pgtype.Timestamptz{
+			Time:  time.Now().Add(-reconcileGracePeriod),
+			Valid: true,
+		},
+		SettledAfter: pgtype.Timestamptz{
+			Time:  time.Now().Add(-reconcileWindow),
+			Valid: true,
+		},
+		Limits: reconcileBatchSize,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while listing reservations to reconcile", "ReconcilePayments:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while listing reservations to reconcile").Err()
+	}
+
+	res := &ReconcilePaymentsResponse{}
+	for _, reservation := range reservations {
+		res.Checked++
+
+		// one unreachable bill must not hold up the others
+		result, err := reconcileReservation(ctx, reservation)
+		if err != nil {
+			rlog.Error("An error occurred while reconciling reservation", "billLinkID", reservation.BillLinkID, "err", err.Error())
+			res.Failed++
+			continue
+		}
+
+		switch result.Action {
+		case ReconcileSettled:
+			res.Settled++
+		case ReconcileReleased:
+			res.Released++
+		case ReconcileFlagged:
+			res.Flagged++
+		}
+	}
+
+	if res.Settled > 0 || res.Released > 0 || res.Flagged > 0 || res.Failed > 0 {
+		rlog.Info("Reconciled payments", "checked", res.Checked, "settled", res.Settled, "released", res.Released, "flagged", res.Flagged, "failed", res.Failed)
+	}
+
+	return res, nil
+}
+
+// ReconcileBill Reconcile the reservation of a single bill with the payment provider
+//
+//encore:api auth method=POST path=/v1/bills/:id/reconcile
+func ReconcileBill(ctx context.Context, id int) (*BaseResponse[ReconcileResult], error) {
+	eb := errs.B()
+
+	reservation, err := query.GetReservation(ctx, int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("No reservation for this bill").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving reservation", "ReconcileBill:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving reservation").Err()
+	}
+
+	result, err := reconcileReservation(ctx, reservation)
+	if err != nil {
+		rlog.Error("An error occurred while reconciling reservation", "ReconcileBill:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("An error occurred while reconciling the bill").Err()
+	}
+
+	return &BaseResponse[ReconcileResult]{
+		Data:    *result,
+		Message: "Bill reconciled successfully",
+	}, nil
+}
+
+// reconcileReservation compares a reservation with the payments its provider recorded for the bill.
+func reconcileReservation(ctx context.Context, reservation db.Reservation) (*ReconcileResult, error) {
+	provider, err := paymentProvider(reservation.PaymentProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := provider.BillPayments(ctx, int(reservation.BillLinkID))
+	if err != nil {
+		return nil, err
+	}
+
+	var successful, latest *Transaction
+	for i := range payments {
+		if payments[i].Status == TransactionSuccessful && successful == nil {
+			successful = &payments[i]
+		}
+		latest = &payments[i]
+	}
+
+	result := &ReconcileResult{
+		BillLinkID: int(reservation.BillLinkID),
+		State:      reservation.State,
+		Action:     ReconcileUnchanged,
+		Mismatches: []string{},
+	}
+
+	flag := func(kind, detail string) error {
+		result.Action = ReconcileFlagged
+		result.Mismatches = append(result.Mismatches, kind)
+
+		flagged, err := query.InsertPaymentMismatch(ctx, db.InsertPaymentMismatchParams{
+			ReservationID: reservation.ID,
+			BillLinkID:    reservation.BillLinkID,
+			Kind:          kind,
+			Detail:        detail,
+		})
+		if flagged > 0 {
+			rlog.Warn("Payment mismatch", "billLinkID", reservation.BillLinkID, "kind", kind, "detail", detail)
+		}
+		return err
+	}
+
+	switch reservation.State {
+	case db.ReservationStatePending:
+		tx := successful
+		action := ReconcileSettled
+		if tx == nil {
+			if latest == nil || latest.Status == TransactionPending {
+				return result, nil
+			}
+			tx, action = latest, ReconcileReleased
+		}
+
+		err := settlePayment(ctx, provider, tx)
+		switch {
+		case err == nil:
+			result.Action = action
+			return result, query.MarkReservationReconciled(ctx, reservation.ID)
+		case errors.Is(err, errReservationSettled):
+			// the callback got there first
+		case errors.Is(err, errPaymentNotSettled):
+			// the provider reported a status we do not act on, check again on the next run
+		case errors.Is(err, errAmountMismatch):
+			return result, flag(MismatchAmount, fmt.Sprintf("provider reported %d, reservation expects %d", tx.Amount, reservation.Amount))
+		default:
+			return nil, err
+		}
+
+		return result, nil
+
+	case db.ReservationStatePaid:
+		exists, err := query.CheckPaymentExists(ctx, reservation.BillLinkID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			if err := flag(MismatchMissingPayment, "reservation is paid but no payment was stored"); err != nil {
+				return nil, err
+			}
+		}
+		if successful == nil {
+			if err := flag(MismatchNotPaidAtProvider, "reservation is paid but the provider has no successful payment"); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		if successful != nil {
+			detail := fmt.Sprintf("payment %s arrived after the reservation was %s", successful.ID, reservation.State)
+			if err := flag(MismatchPaidAfterRelease, detail); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, query.MarkReservationReconciled(ctx, reservation.ID)
+}
+
+// PaymentMismatch represents a disagreement between our records and the payment provider's.
+type PaymentMismatch struct {
+	ID            pgtype.UUID        `json:"id"`
+	ReservationID pgtype.UUID        `json:"reservation_id"`
+	BillLinkID    int32              `json:"bill_link_id"`
+	Kind          string             `json:"kind"`
+	Detail        string             `json:"detail"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+// ListPaymentMismatches List payment mismatches flagged by reconciliation
+//
+//encore:api auth method=GET path=/v1/payment-mismatches
+func ListPaymentMismatches(ctx context.Context, params *ListQuery) (*BaseResponse[[]PaymentMismatch], error) {
+	eb := errs.B()
+
+	extractedParam := extractQuery(params)
+	data, err := query.ListPaymentMismatch(ctx, db.ListPaymentMismatchParams{
+		OrderBy: extractedParam.OrderBy,
+		Offsets: extractedParam.Page,
+		Limits:  extractedParam.Limit,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while retrieving payment mismatches", "ListPaymentMismatches:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving payment mismatches").Err()
+	}
+
+	mismatches := make([]PaymentMismatch, 0, len(data))
+	for _, mismatch := range data {
+		mismatches = append(mismatches, PaymentMismatch{
+			ID:            mismatch.ID,
+			ReservationID: mismatch.ReservationID,
+			BillLinkID:    mismatch.BillLinkID,
+			Kind:          mismatch.Kind,
+			Detail:        mismatch.Detail,
+			CreatedAt:     mismatch.CreatedAt,
+		})
+	}
+
+	return &BaseResponse[[]PaymentMismatch]{
+		Data:    mismatches,
+		Message: "Payment mismatches retrieved successfully",
+	}, nil
+}
@@ -0,0 +1,545 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+	"github.com/lichtlabs/ggrims-service/mail"
+	mailtempl "github.com/lichtlabs/ggrims-service/mail/template"
+	"github.com/lichtlabs/ggrims-service/money"
+)
+
+const (
+	// RefundStatusManual is recorded when the provider cannot refund through its API and the money is returned by hand.
+	RefundStatusManual = "MANUAL"
+	// RefundStatusPending is recorded before the provider is asked to pay the refund out.
+	RefundStatusPending = "PENDING"
+	// RefundStatusFailed is recorded when the provider turned the refund down, its tickets can be refunded again.
+	RefundStatusFailed = "FAILED"
+	// RefundStatusProcessing is recorded when the provider accepted the refund but has not paid it out yet.
+	RefundStatusProcessing = "PROCESSING"
+	// RefundStatusCompleted is recorded when the provider paid the refund out.
+	RefundStatusCompleted = "COMPLETED"
+)
+
+// refundStatus maps the status a provider reported for a refund to the status recorded for it. A status the
+// provider accepted the refund with but that is not known to be final is recorded as processing.
+func refundStatus(providerStatus string) string {
+	switch providerStatus {
+	case RefundSuccessful:
+		return RefundStatusCompleted
+	case RefundFailed:
+		return RefundStatusFailed
+	default:
+		return RefundStatusProcessing
+	}
+}
+
+// RefundOrderRequest selects what to refund. Without TicketIDs every ticket of the order that has not been
+// refunded yet is refunded. Restock puts the tickets back on sale with a new QR code instead of retiring them.
+type RefundOrderRequest struct {
+	TicketIDs []uuid.UUID `json:"ticket_ids"`
+	Reason    string      `json:"reason"`
+	Restock   bool        `json:"restock"`
+}
+
+// Refund represents a refund of some or all tickets of an order.
+type Refund struct {
+	ID                pgtype.UUID        `json:"id"`
+	OrderID           pgtype.UUID        `json:"order_id"`
+	PaymentID         pgtype.UUID        `json:"payment_id"`
+	TicketIDs         []pgtype.UUID      `json:"ticket_ids"`
+	Amount            money.Money        `json:"amount"`
+	Reason            string             `json:"reason"`
+	Status            string             `json:"status"`
+	ProviderReference string             `json:"provider_reference"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+}
+
+func toRefund(refund db.Refund) Refund {
+	return Refund{
+		ID:                refund.ID,
+		OrderID:           refund.OrderID,
+		PaymentID:         refund.PaymentID,
+		TicketIDs:         refund.TicketIds,
+		Amount:            money.New(refund.Amount, refund.Currency),
+		Reason:            refund.Reason,
+		Status:            refund.Status,
+		ProviderReference: refund.ProviderReference.String,
+		CreatedAt:         refund.CreatedAt,
+	}
+}
+
+// RefundOrder Refund a paid order, or selected tickets of it, through its payment provider
+//
+// Refunded tickets are retired, or put back on sale with a new hash when restocking, and their attendees are
+// marked as refunded so the old QR codes are rejected at the door. Once every ticket is refunded the order
+// moves to refunded. The buyer is notified by email.
+//
+// The refund is recorded as pending before the provider pays it out. If completing it fails afterwards, calling
+// RefundOrder again resumes the pending refund, with the tickets and restock choice it was started with, and the
+// provider recognises it by its ID so the money is not paid out twice.
+//
+//encore:api auth method=POST path=/v1/orders/:id/refund
+func RefundOrder(ctx context.Context, id uuid.UUID, req *RefundOrderRequest) (*BaseResponse[Refund], error) {
+	eb := errs.B()
+
+	orderID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	pending, err := startRefund(ctx, orderID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := paymentProvider(pending.order.PaymentProvider)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("The order's payment provider is not available").Err()
+	}
+
+	status := RefundStatusManual
+	var providerReference pgtype.Text
+	refundRes, err := provider.Refund(ctx, &RefundRequest{
+		BillLinkID:    int(pending.billLinkID),
+		TransactionID: pending.transaction.ID,
+		Amount:        int(pending.refund.Amount),
+		Reason:        pending.refund.Reason,
+		Reference:     uuid.UUID(pending.refund.ID.Bytes).String(),
+	})
+	// a refund reported as failed is turned down just like one the provider rejects outright
+	if err == nil && refundStatus(refundRes.Status) == RefundStatusFailed {
+		err = fmt.Errorf("provider reported refund %s as failed", refundRes.ID)
+	}
+	switch {
+	case errors.Is(err, ErrRefundNotSupported):
+		rlog.Warn("Payment provider cannot refund, the money has to be returned manually", "orderID", orderID, "provider", provider.Name())
+	case err != nil:
+		rlog.Error("An error occurred while refunding the payment", "RefundOrder:err", err.Error())
+		// the provider turned the refund down, so its tickets can be refunded again
+		_, updateErr := query.UpdateRefundStatus(ctx, db.UpdateRefundStatusParams{
+			Status:   RefundStatusFailed,
+			RefundID: pending.refund.ID,
+		})
+		if updateErr != nil {
+			rlog.Error("An error occurred while recording the failed refund", "RefundOrder:err", updateErr.Error())
+		}
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("The payment provider could not refund the payment").Err()
+	default:
+		status = refundStatus(refundRes.Status)
+		providerReference = pgtype.Text{
+			String: refundRes.ID,
+			Valid:  true,
+		}
+	}
+
+	refund, err := completeRefund(ctx, pending.refund.ID, status, providerReference)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed email does not undo the refund
+	sendRefundMail(ctx, pending.order, pending.transaction, refund)
+
+	return &BaseResponse[Refund]{
+		Data:    toRefund(refund),
+		Message: "Order refunded successfully",
+	}, nil
+}
+
+// pendingRefund is a refund recorded before the provider is asked to pay it out, with what the provider needs.
+type pendingRefund struct {
+	order       db.Order
+	refund      db.Refund
+	billLinkID  int32
+	transaction Transaction
+}
+
+// startRefund records the refund req asks for as pending and commits it, so the tickets cannot be refunded twice
+// while the provider pays it out. An earlier refund of the order that is still pending is returned instead.
+func startRefund(ctx context.Context, orderID pgtype.UUID, req *RefundOrderRequest) (pendingRefund, error) {
+	eb := errs.B()
+
+	// Start a database transaction
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return pendingRefund{}, eb.Cause(err).Code(errs.Unavailable).Msg("failed to start transaction").Err()
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+
+	// Lock the order so two refunds of the same order cannot overlap
+	order, err := qtx.GetOrderForUpdate(ctx, orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pendingRefund{}, eb.Code(errs.NotFound).Msg("Order not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving order", "RefundOrder:err", err.Error())
+		return pendingRefund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving order").Err()
+	}
+	if order.Status != db.OrderStatusPaid {
+		return pendingRefund{}, eb.Code(errs.FailedPrecondition).Msgf("Only paid orders can be refunded, this order is %s", order.Status).Err()
+	}
+	if order.Amount == 0 {
+		return pendingRefund{}, eb.Code(errs.FailedPrecondition).Msg("Free orders have nothing to refund").Err()
+	}
+
+	reservation, err := qtx.GetPaidReservationByOrder(ctx, orderID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving the order's tickets", "RefundOrder:err", err.Error())
+		return pendingRefund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the order's tickets").Err()
+	}
+
+	payment, err := qtx.GetOrderPayment(ctx, orderID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving the order's payment", "RefundOrder:err", err.Error())
+		return pendingRefund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the order's payment").Err()
+	}
+
+	var transaction Transaction
+	if err := json.Unmarshal(payment.Data, &transaction); err != nil {
+		rlog.Error("An error occurred while decoding the payment", "RefundOrder:err", err.Error())
+		return pendingRefund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while decoding the payment").Err()
+	}
+
+	pending := pendingRefund{
+		order:       order,
+		billLinkID:  reservation.BillLinkID,
+		transaction: transaction,
+	}
+
+	previous, err := qtx.ListOrderRefund(ctx, orderID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving earlier refunds", "RefundOrder:err", err.Error())
+		return pendingRefund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving earlier refunds").Err()
+	}
+
+	refunded := map[pgtype.UUID]bool{}
+	var refundedAmount int64
+	for _, refund := range previous {
+		if refund.Status == RefundStatusFailed {
+			continue
+		}
+		if refund.Status == RefundStatusPending {
+			if len(req.TicketIDs) > 0 && !sameTickets(req.TicketIDs, refund.TicketIds) {
+				return pendingRefund{}, eb.Code(errs.FailedPrecondition).Msg("An earlier refund of this order is still pending, retry it with the same tickets first").Err()
+			}
+			pending.refund = refund
+			return pending, nil
+		}
+		refundedAmount += refund.Amount
+		for _, ticketID := range refund.TicketIds {
+			refunded[ticketID] = true
+		}
+	}
+
+	var remaining []pgtype.UUID
+	for _, ticketID := range reservation.TicketIds {
+		if !refunded[ticketID] {
+			remaining = append(remaining, ticketID)
+		}
+	}
+
+	ticketIDs := remaining
+	if len(req.TicketIDs) > 0 {
+		inOrder := map[pgtype.UUID]bool{}
+		for _, ticketID := range remaining {
+			inOrder[ticketID] = true
+		}
+
+		ticketIDs = nil
+		for _, requested := range req.TicketIDs {
+			ticketID := pgtype.UUID{
+				Bytes: requested,
+				Valid: true,
+			}
+			if !inOrder[ticketID] {
+				return pendingRefund{}, eb.Code(errs.InvalidArgument).Msgf("Ticket %s is not a refundable ticket of this order", requested).Err()
+			}
+			delete(inOrder, ticketID)
+			ticketIDs = append(ticketIDs, ticketID)
+		}
+	}
+	if len(ticketIDs) == 0 {
+		return pendingRefund{}, eb.Code(errs.FailedPrecondition).Msg("All tickets of this order have already been refunded").Err()
+	}
+
+	// The last refund of an order returns whatever is left, so rounding never leaves money behind
+	amount := money.New(order.Amount-refundedAmount, order.Currency)
+	if len(ticketIDs) < len(remaining) {
+		amount, err = refundAmount(ctx, qtx, order, ticketIDs)
+		if err != nil {
+			rlog.Error("An error occurred while pricing the refund", "RefundOrder:err", err.Error())
+			return pendingRefund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while pricing the refund").Err()
+		}
+	}
+
+	pending.refund, err = qtx.InsertRefund(ctx, db.InsertRefundParams{
+		PaymentID: payment.ID,
+		OrderID:   orderID,
+		EventID:   order.EventID,
+		TicketIds: ticketIDs,
+		Amount:    amount.Amount,
+		Currency:  amount.Currency,
+		Reason:    req.Reason,
+		Status:    RefundStatusPending,
+		Restock:   req.Restock,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while recording the refund", "RefundOrder:err", err.Error())
+		return pendingRefund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while recording the refund").Err()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		rlog.Error("failed to commit your transaction", "err", err.Error())
+		return pendingRefund{}, eb.Cause(err).Code(errs.Unavailable).Msg("failed to commit your transaction").Err()
+	}
+	committed = true
+
+	return pending, nil
+}
+
+// refundAmount returns what refunding some tickets of an order gives back: the unit price of each ticket's order
+// line, plus its share of the order's discount, fee and tax in proportion to that price.
+func refundAmount(ctx context.Context, q *db.Queries, order db.Order, ticketIDs []pgtype.UUID) (money.Money, error) {
+	items, err := q.ListOrderItem(ctx, order.ID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	tickets, err := q.ListTicketsByID(ctx, ticketIDs)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	var subtotal int64
+	for _, ticket := range tickets {
+		// items from before lines kept their type are matched by name, the ticket keeps the name it was sold under
+		unitPrice := ticket.Price
+		for _, item := range items {
+			if (item.TicketTypeID.Valid && item.TicketTypeID == ticket.TicketTypeID) || (!item.TicketTypeID.Valid && item.TicketName == ticket.Name) {
+				unitPrice = item.UnitPrice
+				break
+			}
+		}
+		subtotal += unitPrice
+	}
+
+	if order.Subtotal == 0 {
+		return money.New(0, order.Currency), nil
+	}
+	return money.New(order.Amount, order.Currency).Share(subtotal, order.Subtotal), nil
+}
+
+// sameTickets reports whether requested names exactly the tickets of ticketIDs.
+func sameTickets(requested []uuid.UUID, ticketIDs []pgtype.UUID) bool {
+	if len(requested) != len(ticketIDs) {
+		return false
+	}
+	for _, ticketID := range requested {
+		if !slices.Contains(ticketIDs, pgtype.UUID{Bytes: ticketID, Valid: true}) {
+			return false
+		}
+	}
+	return true
+}
+
+// completeRefund retires or restocks the tickets of a pending refund once the provider paid it out, records the
+// provider's status and moves the order to refunded when no ticket is left. A refund that is no longer pending was
+// completed by a concurrent retry and is returned as it is.
+func completeRefund(ctx context.Context, refundID pgtype.UUID, status string, providerReference pgtype.Text) (db.Refund, error) {
+	eb := errs.B()
+
+	// Start a database transaction
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return db.Refund{}, eb.Cause(err).Code(errs.Unavailable).Msg("failed to start transaction").Err()
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+
+	refund, err := qtx.GetRefundForUpdate(ctx, refundID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving the refund", "RefundOrder:err", err.Error())
+		return db.Refund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the refund").Err()
+	}
+	if refund.Status != RefundStatusPending {
+		return refund, nil
+	}
+
+	for _, ticketID := range refund.TicketIds {
+		if refund.Restock {
+			// tickets of counter types are returned to the type's capacity, the others are put back on sale
+			var returned int64
+			returned, err = qtx.ReturnIssuedTicket(ctx, ticketID)
+			if err == nil && returned == 0 {
+				err = qtx.RestockTicket(ctx, db.RestockTicketParams{
+					Hash: pgtype.Text{
+						String: newTicketHash(),
+						Valid:  true,
+					},
+					TicketID: ticketID,
+				})
+			}
+		} else {
+			err = qtx.ChangeTicketsStatus(ctx, db.ChangeTicketsStatusParams{
+				Status:   db.TicketStatusRefunded,
+				TicketID: ticketID,
+			})
+		}
+		if err != nil {
+			rlog.Error("An error occurred while changing ticket status", "RefundOrder:err", err.Error())
+			return db.Refund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while changing ticket status").Err()
+		}
+
+		err = qtx.UpdateTicketAttendeesStatus(ctx, db.UpdateTicketAttendeesStatusParams{
+			Status:   db.AttendeeStatusRefunded,
+			TicketID: ticketID,
+		})
+		if err != nil {
+			rlog.Error("An error occurred while updating attendee status", "RefundOrder:err", err.Error())
+			return db.Refund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating attendee status").Err()
+		}
+	}
+
+	// restocked tickets go to the waitlist first
+	if refund.Restock {
+		ticketTypeIDs, err := qtx.ListTicketTypeIDsOfTickets(ctx, refund.TicketIds)
+		if err == nil {
+			err = offerToWaitlist(ctx, qtx, ticketTypeIDs)
+		}
+		if err != nil {
+			rlog.Error("An error occurred while offering tickets to the waitlist", "RefundOrder:err", err.Error())
+			return db.Refund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while offering tickets to the waitlist").Err()
+		}
+	}
+
+	refund, err = qtx.UpdateRefundStatus(ctx, db.UpdateRefundStatusParams{
+		Status:            status,
+		ProviderReference: providerReference,
+		RefundID:          refund.ID,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while recording the refund", "RefundOrder:err", err.Error())
+		return db.Refund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while recording the refund").Err()
+	}
+
+	fullyRefunded, err := orderFullyRefunded(ctx, qtx, refund.OrderID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving earlier refunds", "RefundOrder:err", err.Error())
+		return db.Refund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving earlier refunds").Err()
+	}
+	if fullyRefunded {
+		reason := "refunded"
+		if refund.Reason != "" {
+			reason = fmt.Sprintf("refunded: %s", refund.Reason)
+		}
+		if err := transitionOrder(ctx, qtx, refund.OrderID, db.OrderStatusRefunded, reason); err != nil {
+			rlog.Error("An error occurred while updating the order", "RefundOrder:err", err.Error())
+			return db.Refund{}, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating the order").Err()
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		rlog.Error("failed to commit your transaction", "err", err.Error())
+		return db.Refund{}, eb.Cause(err).Code(errs.DataLoss).Msg("failed to commit your transaction").Err()
+	}
+	committed = true
+
+	return refund, nil
+}
+
+// orderFullyRefunded reports whether every ticket of an order is covered by a completed refund.
+func orderFullyRefunded(ctx context.Context, q *db.Queries, orderID pgtype.UUID) (bool, error) {
+	reservation, err := q.GetPaidReservationByOrder(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	refunds, err := q.ListOrderRefund(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+
+	refunded := map[pgtype.UUID]bool{}
+	for _, refund := range refunds {
+		if refund.Status == RefundStatusPending || refund.Status == RefundStatusFailed {
+			continue
+		}
+		for _, ticketID := range refund.TicketIds {
+			refunded[ticketID] = true
+		}
+	}
+	for _, ticketID := range reservation.TicketIds {
+		if !refunded[ticketID] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// sendRefundMail tells the buyer which tickets were refunded. Errors are logged only.
+func sendRefundMail(ctx context.Context, order db.Order, transaction Transaction, refund db.Refund) {
+	recipient := order.BuyerEmail
+	if recipient == "" {
+		recipient = transaction.SenderEmail
+	}
+	if recipient == "" {
+		rlog.Warn("Order has no buyer email, skipping refund email", "orderID", order.ID)
+		return
+	}
+
+	var buff bytes.Buffer
+	err := mailtempl.RefundConfirmationEmail(mailtempl.RefundConfirmation{
+		CustomerName: order.BuyerName,
+		ItemName:     transaction.BillTitle,
+		TicketCount:  len(refund.TicketIds),
+		Amount:       money.New(refund.Amount, refund.Currency),
+		Reason:       refund.Reason,
+		OrderNumber:  transaction.ID,
+		Manual:       refund.Status == RefundStatusManual,
+	}).Render(ctx, &buff)
+	if err != nil {
+		rlog.Error("Error: Error rendering refund confirmation email: ", err.Error())
+		return
+	}
+
+	err = mail.SendTicketMail(ctx, &mail.SendTicketMailRequest{
+		Subject:    "Your refund has been processed",
+		Recipients: []string{recipient},
+		Body:       buff.String(),
+	})
+	if err != nil {
+		rlog.Error("Error: Error sending refund mail: ", err.Error())
+	}
+}
@@ -0,0 +1,164 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+)
+
+// ReservationTTL is how long reserved tickets are held while waiting for the payment to complete.
+const ReservationTTL = 7 * time.Minute
+
+// releaseBatchSize caps how many expired reservations a single sweep transaction locks at once.
+const releaseBatchSize = 100
+
+// Sweep expired reservations every minute so pending tickets go back on sale even across restarts.
+var _ = cron.NewJob("release-expired-reservations", cron.JobConfig{
+	Title:    "Release expired ticket reservations",
+	Every:    1 * cron.Minute,
+	Endpoint: ReleaseExpiredReservations,
+})
+
+// ReleaseExpiredReservationsResponse reports how many reservations a sweep released.
+type ReleaseExpiredReservationsResponse struct {
+	Released int `json:"released"`
+}
+
+// ReleaseExpiredReservations releases every pending reservation whose payment window has passed.
+// Rows are claimed with FOR UPDATE SKIP LOCKED, so several instances can sweep at the same time
+// without releasing the same reservation twice or blocking on each other.
+//
+//encore:api private
+func ReleaseExpiredReservations(ctx context.Context) (*ReleaseExpiredReservationsResponse, error) {
+	eb := errs.B()
+
+	released := 0
+	for {
+		n, err := releaseExpiredBatch(ctx)
+		if err != nil {
+			rlog.Error("An error occurred while releasing expired reservations", "ReleaseExpiredReservations:err", err.Error())
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while releasing expired reservations").Err()
+		}
+		released += n
+
+		if n < releaseBatchSize {
+			break
+		}
+	}
+
+	if released > 0 {
+		rlog.Info("Released expired reservations", "released", released)
+	}
+
+	return &ReleaseExpiredReservationsResponse{
+		Released: released,
+	}, nil
+}
+
+// releaseExpiredBatch releases one batch of expired reservations inside a single transaction.
+func releaseExpiredBatch(ctx context.Context) (int, error) {
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+	reservations, err := qtx.ListExpiredReservationsForUpdate(ctx, releaseBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, reservation := range reservations {
+		err := releaseReservation(ctx, qtx, reservation, db.ReservationStateExpired, "payment not received before expiry")
+		if err != nil {
+			return 0, err
+		}
+		rlog.Info("Reverted ticket statuses due to no payment", "billLinkID", reservation.BillLinkID)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	committed = true
+
+	return len(reservations), nil
+}
+
+// reservationAttendees decodes the attendee payloads stored on a reservation.
+func reservationAttendees(reservation db.Reservation) ([]*map[string]string, error) {
+	var attendees []*map[string]string
+	if err := json.Unmarshal(reservation.Attendees, &attendees); err != nil {
+		return nil, err
+	}
+
+	return attendees, nil
+}
+
+// releaseTickets puts held tickets back on sale. Tickets of counter types go back to the type's capacity and lose
+// their rows, the remaining ones become available again with the current details and price of their type, as if
+// they had been on sale all along.
+func releaseTickets(ctx context.Context, q *db.Queries, ticketIDs []pgtype.UUID) error {
+	if err := q.ReleaseIssuedTickets(ctx, ticketIDs); err != nil {
+		return err
+	}
+
+	return q.MakeTicketsAvailable(ctx, ticketIDs)
+}
+
+// releaseReservation puts the tickets held by a reservation back on sale, offering them to the waitlist first, gives
+// back the promo and access codes used by its order, and moves the reservation and its order to the given state,
+// recording why it was released. The caller is expected to hold the reservation row lock.
+func releaseReservation(ctx context.Context, q *db.Queries, reservation db.Reservation, state db.ReservationState, reason string) error {
+	// counter types drop the rows of released tickets, so their types are looked up first
+	ticketTypeIDs, err := q.ListTicketTypeIDsOfTickets(ctx, reservation.TicketIds)
+	if err != nil {
+		return err
+	}
+	if err := releaseTickets(ctx, q, reservation.TicketIds); err != nil {
+		return err
+	}
+	if err := offerToWaitlist(ctx, q, ticketTypeIDs); err != nil {
+		return err
+	}
+
+	err = q.UpdateReservationState(ctx, db.UpdateReservationStateParams{
+		State: state,
+		ReleaseReason: pgtype.Text{
+			String: reason,
+			Valid:  true,
+		},
+		ReservationID: reservation.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !reservation.OrderID.Valid {
+		return nil
+	}
+	// a released order gives its promo code use and access code tickets back
+	if err := q.ReleasePromoCodeRedemption(ctx, reservation.OrderID); err != nil {
+		return err
+	}
+	if err := q.ReleaseAccessCodeRedemption(ctx, reservation.OrderID); err != nil {
+		return err
+	}
+	return transitionOrder(ctx, q, reservation.OrderID, orderStatusForReservation(state), reason)
+}
@@ -0,0 +1,177 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"encore.dev/beta/errs"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+	"github.com/lichtlabs/ggrims-service/money"
+)
+
+// maxPricePhases caps how many price phases a ticket type can have.
+const maxPricePhases = 10
+
+// PricePhaseRequest describes one price phase of a ticket type, such as early bird, regular or on the door.
+type PricePhaseRequest struct {
+	Name  string `json:"name"`
+	Price int64  `json:"price"`
+	// EndsAt ends the phase at a date and SoldLimit once that many tickets of the type are sold.
+	// Left out, the phase does not end that way.
+	EndsAt    *time.Time `json:"ends_at"`
+	SoldLimit int32      `json:"sold_limit"`
+}
+
+// PricePhase is a price a ticket type sells for until the phase ends.
+type PricePhase struct {
+	Name      string             `json:"name"`
+	Price     money.Money        `json:"price"`
+	EndsAt    pgtype.Timestamptz `json:"ends_at"`
+	SoldLimit int32              `json:"sold_limit"`
+}
+
+func toPricePhases(phases []db.TicketPricePhase, currency string) []PricePhase {
+	result := make([]PricePhase, 0, len(phases))
+	for _, phase := range phases {
+		result = append(result, PricePhase{
+			Name:      phase.Name,
+			Price:     money.New(phase.Price, currency),
+			EndsAt:    phase.EndsAt,
+			SoldLimit: phase.SoldLimit,
+		})
+	}
+
+	return result
+}
+
+// listEventPricePhases returns the price phases of every ticket type of an event, by ticket type.
+func listEventPricePhases(ctx context.Context, eventID pgtype.UUID) (map[pgtype.UUID][]db.TicketPricePhase, error) {
+	phases, err := query.ListEventTicketPricePhase(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := make(map[pgtype.UUID][]db.TicketPricePhase)
+	for _, phase := range phases {
+		byType[phase.TicketTypeID] = append(byType[phase.TicketTypeID], phase)
+	}
+
+	return byType, nil
+}
+
+// timestamptz converts an optional time into a nullable timestamp.
+func timestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+
+	return pgtype.Timestamptz{
+		Time:  *t,
+		Valid: true,
+	}
+}
+
+// timestampPtr converts a nullable timestamp into an optional time.
+func timestampPtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+// validateSalesWindow rejects a sales window that closes before it opens.
+func validateSalesWindow(start, end *time.Time) error {
+	if start != nil && end != nil && !start.Before(*end) {
+		return errs.B().Code(errs.InvalidArgument).Msg("sales_start must be before sales_end").Err()
+	}
+
+	return nil
+}
+
+// validatePricePhases requires a name and a non-negative price on every phase. Every phase but the last needs an
+// end date or a sold limit, as the phases after an endless one would never apply.
+func validatePricePhases(phases []PricePhaseRequest) error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if len(phases) > maxPricePhases {
+		return eb.Msgf("A ticket type can have at most %d price phases", maxPricePhases).Err()
+	}
+	for i, phase := range phases {
+		if phase.Name == "" {
+			return eb.Msgf("Price phase %d needs a name", i+1).Err()
+		}
+		if err := validatePrice(phase.Price, ""); err != nil {
+			return err
+		}
+		if phase.SoldLimit < 0 {
+			return eb.Msgf("Sold limit of price phase %q must not be negative", phase.Name).Err()
+		}
+		if i < len(phases)-1 && phase.EndsAt == nil && phase.SoldLimit == 0 {
+			return eb.Msgf("Price phase %q needs an end date or a sold limit, only the last phase can be open ended", phase.Name).Err()
+		}
+	}
+
+	return nil
+}
+
+// replacePricePhases swaps the price phases of a ticket type for the given ones.
+func replacePricePhases(ctx context.Context, q *db.Queries, ticketTypeID pgtype.UUID, phases []PricePhaseRequest) error {
+	if err := q.DeleteTicketPricePhases(ctx, ticketTypeID); err != nil {
+		return err
+	}
+
+	for i, phase := range phases {
+		err := q.InsertTicketPricePhase(ctx, db.InsertTicketPricePhaseParams{
+			TicketTypeID: ticketTypeID,
+			Position:     int32(i),
+			Name:         phase.Name,
+			Price:        phase.Price,
+			EndsAt:       timestamptz(phase.EndsAt),
+			SoldLimit:    phase.SoldLimit,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// currentPrice returns the price of the first phase that has neither passed its end date nor reached its sold
+// limit, given how many tickets are sold. Reserved tickets do not count, so abandoned reservations cannot end a
+// phase early. A type without phases, or whose phases all ended, sells at basePrice. A purchase is priced as a
+// whole at the phase it starts in.
+func currentPrice(basePrice money.Money, phases []db.TicketPricePhase, sold int64, now time.Time) money.Money {
+	for _, phase := range phases {
+		if phase.EndsAt.Valid && !now.Before(phase.EndsAt.Time) {
+			continue
+		}
+		if phase.SoldLimit > 0 && sold >= int64(phase.SoldLimit) {
+			continue
+		}
+		return money.New(phase.Price, basePrice.Currency)
+	}
+
+	return basePrice
+}
+
+// checkSalesWindow rejects a purchase before a ticket type's sales open or after they close. Sales close at the
+// end of the event unless the type closes them earlier.
+func checkSalesWindow(name string, start, end pgtype.Timestamptz, eventEnd time.Time, now time.Time) error {
+	eb := errs.B().Code(errs.FailedPrecondition)
+
+	if start.Valid && now.Before(start.Time) {
+		return eb.Msgf("Sales of %s tickets open on %s", name, start.Time.Format(time.RFC3339)).Err()
+	}
+	closes := eventEnd
+	if end.Valid && end.Time.Before(closes) {
+		closes = end.Time
+	}
+	if !now.Before(closes) {
+		return eb.Msgf("Sales of %s tickets have ended", name).Err()
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+)
+
+// ScanResult describes the outcome of scanning a ticket QR code at the door.
+type ScanResult string
+
+const (
+	ScanResultAccepted    ScanResult = "accepted"
+	ScanResultAlreadyUsed ScanResult = "already_used"
+	ScanResultUnknown     ScanResult = "unknown"
+	ScanResultWrongEvent  ScanResult = "wrong_event"
+	ScanResultNotPaid     ScanResult = "not_paid"
+	ScanResultRefunded    ScanResult = "refunded"
+)
+
+// ScanTicketRequest represents the payload sent by door staff when scanning a ticket for an event.
+type ScanTicketRequest struct {
+	EventID uuid.UUID `json:"event_id"`
+}
+
+// ScanTicketResponse describes whether the scanned ticket is accepted, and for whom.
+type ScanTicketResponse struct {
+	Accepted   bool              `json:"accepted"`
+	Result     ScanResult        `json:"result"`
+	TicketID   pgtype.UUID       `json:"ticket_id"`
+	TicketName string            `json:"ticket_name"`
+	AttendeeID pgtype.UUID       `json:"attendee_id"`
+	Attendee   map[string]string `json:"attendee"`
+}
+
+// ScanTicket looks up a ticket by the hash encoded in its QR code and checks the attendee in.
+// The ticket must be sold and belong to the event being scanned; the linked attendee is moved
+// from waiting to attended, so a second scan of the same code is rejected.
+//
+//encore:api auth method=POST path=/scans/:hash
+func ScanTicket(ctx context.Context, hash string, req *ScanTicketRequest) (*BaseResponse[ScanTicketResponse], error) {
+	eb := errs.B()
+
+	// Start a database transaction
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to start transaction").Err()
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+
+	reject := func(result ScanResult, msg string, data ScanTicketResponse) (*BaseResponse[ScanTicketResponse], error) {
+		data.Result = result
+		return &BaseResponse[ScanTicketResponse]{
+			Data:    data,
+			Message: msg,
+		}, nil
+	}
+
+	ticket, err := qtx.GetTicketByHash(ctx, pgtype.Text{
+		String: hash,
+		Valid:  true,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return reject(ScanResultUnknown, "Ticket not found", ScanTicketResponse{})
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket", "ScanTicket:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving ticket").Err()
+	}
+
+	data := ScanTicketResponse{
+		TicketID:   ticket.ID,
+		TicketName: ticket.Name,
+	}
+
+	if ticket.EventID.Bytes != req.EventID {
+		return reject(ScanResultWrongEvent, "Ticket belongs to another event", data)
+	}
+	if ticket.Status == db.TicketStatusRefunded {
+		return reject(ScanResultRefunded, "Ticket has been refunded", data)
+	}
+	if ticket.Status != db.TicketStatusSold {
+		return reject(ScanResultNotPaid, "Ticket has not been paid", data)
+	}
+
+	// Lock the attendee row so two simultaneous scans cannot both be accepted
+	attendee, err := qtx.GetTicketAttendeeForUpdate(ctx, ticket.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return reject(ScanResultUnknown, "Ticket has no attendee", data)
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving attendee", "ScanTicket:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving attendee").Err()
+	}
+
+	data.AttendeeID = attendee.ID
+	if err := json.Unmarshal(attendee.Data, &data.Attendee); err != nil {
+		rlog.Error("An error occurred while decoding attendee data", "ScanTicket:err", err.Error())
+	}
+
+	if attendee.Status == db.AttendeeStatusAttended {
+		return reject(ScanResultAlreadyUsed, "Ticket has already been used", data)
+	}
+
+	err = qtx.UpdateAttendeeStatus(ctx, db.UpdateAttendeeStatusParams{
+		Status:     db.AttendeeStatusAttended,
+		AttendeeID: attendee.ID,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while updating attendee status", "ScanTicket:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating attendee status").Err()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		rlog.Error("failed to commit your transaction", "err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to commit transaction").Err()
+	}
+	committed = true
+
+	data.Accepted = true
+	data.Result = ScanResultAccepted
+	return &BaseResponse[ScanTicketResponse]{
+		Data:    data,
+		Message: "Ticket accepted",
+	}, nil
+}
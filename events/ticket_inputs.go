@@ -0,0 +1,130 @@
+package events
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ticket input types with a format checked on purchase. Any other type is accepted as free text.
+const (
+	InputTypeEmail  = "email"
+	InputTypePhone  = "tel"
+	InputTypeNumber = "number"
+	InputTypeDate   = "date"
+)
+
+// phonePattern accepts an optional leading +, digits and the usual separators.
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{5,19}$`)
+
+// AttendeeFieldError describes why one field of one attendee was rejected.
+type AttendeeFieldError struct {
+	// Item is the position of the cart item the attendee belongs to, starting at 0
+	Item int `json:"item"`
+	// Attendee is the position of the attendee in its item, starting at 0
+	Attendee int    `json:"attendee"`
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+}
+
+// AttendeeErrors is returned as the error details of a purchase whose attendees do not match the event's inputs.
+type AttendeeErrors struct {
+	Fields []AttendeeFieldError `json:"fields"`
+}
+
+func (AttendeeErrors) ErrDetails() {}
+
+// validateAttendees checks every attendee against the event's ticket inputs and returns the attendees with only
+// the fields the event asks for. Keys the event does not know are dropped.
+func validateAttendees(inputs []*EventTicketInput, attendees []*map[string]string) ([]*map[string]string, []AttendeeFieldError) {
+	var fieldErrors []AttendeeFieldError
+	cleaned := make([]*map[string]string, 0, len(attendees))
+
+	for i, attendee := range attendees {
+		data := map[string]string{}
+		if attendee != nil {
+			data = *attendee
+		}
+
+		values := map[string]string{}
+		for _, input := range inputs {
+			if input == nil {
+				continue
+			}
+
+			value := strings.TrimSpace(data[input.Name])
+			if value == "" {
+				if input.Required != nil && *input.Required {
+					fieldErrors = append(fieldErrors, AttendeeFieldError{
+						Attendee: i,
+						Field:    input.Name,
+						Message:  fmt.Sprintf("%s is required", inputLabel(input)),
+					})
+				}
+				continue
+			}
+
+			if msg := checkInputValue(input, value); msg != "" {
+				fieldErrors = append(fieldErrors, AttendeeFieldError{
+					Attendee: i,
+					Field:    input.Name,
+					Message:  msg,
+				})
+				continue
+			}
+
+			values[input.Name] = value
+		}
+
+		cleaned = append(cleaned, &values)
+	}
+
+	return cleaned, fieldErrors
+}
+
+// checkInputValue returns why a non-empty value does not fit the input, or an empty string when it does.
+func checkInputValue(input *EventTicketInput, value string) string {
+	label := inputLabel(input)
+
+	if len(input.Options) > 0 {
+		for _, option := range input.Options {
+			if option != nil && option.Value == value {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%s must be one of the listed options", label)
+	}
+
+	switch input.Type {
+	case InputTypeEmail:
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Address != value {
+			return fmt.Sprintf("%s must be a valid email address", label)
+		}
+	case InputTypePhone:
+		if !phonePattern.MatchString(value) {
+			return fmt.Sprintf("%s must be a valid phone number", label)
+		}
+	case InputTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Sprintf("%s must be a number", label)
+		}
+	case InputTypeDate:
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return fmt.Sprintf("%s must be a date formatted as YYYY-MM-DD", label)
+		}
+	}
+
+	return ""
+}
+
+// inputLabel names an input in error messages, preferring the label shown to buyers.
+func inputLabel(input *EventTicketInput) string {
+	if input.Label != "" {
+		return input.Label
+	}
+	return input.Name
+}
@@ -0,0 +1,741 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+	"github.com/lichtlabs/ggrims-service/money"
+)
+
+// TicketType describes a kind of ticket of an event and how many of it are on sale.
+type TicketType struct {
+	ID          pgtype.UUID `json:"id"`
+	EventID     pgtype.UUID `json:"event_id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`
+	Benefits    []string    `json:"benefits"`
+	Min         int32       `json:"min"`
+	Max         int32       `json:"max"`
+	Quantity    int32       `json:"quantity"`
+	// InventoryMode is rows when every unsold ticket has a row, or counter when only the capacity is counted
+	InventoryMode db.InventoryMode `json:"inventory_mode"`
+	Available     int64            `json:"available"`
+	Sold          int64            `json:"sold"`
+	// Visibility is hidden for types that are only listed and sold with an access code
+	Visibility db.TicketVisibility `json:"visibility"`
+	// SalesStart and SalesEnd bound when the type is on sale, sales close at the end of the event at the latest
+	SalesStart pgtype.Timestamptz `json:"sales_start"`
+	SalesEnd   pgtype.Timestamptz `json:"sales_end"`
+	// CurrentPrice is what a ticket sells for right now, the price of the current phase when the type has phases
+	CurrentPrice money.Money        `json:"current_price"`
+	PricePhases  []PricePhase       `json:"price_phases"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+}
+
+func toTicketType(ticketType db.GetTicketTypeRow, phases []db.TicketPricePhase) TicketType {
+	var benefits []string
+	if err := json.Unmarshal(ticketType.Benefits, &benefits); err != nil {
+		rlog.Error("An error occurred while decoding benefits", "ticketTypeID", ticketType.ID, "err", err.Error())
+	}
+
+	return TicketType{
+		ID:            ticketType.ID,
+		EventID:       ticketType.EventID,
+		Name:          ticketType.Name,
+		Description:   ticketType.Description,
+		Price:         money.New(ticketType.Price, ticketType.Currency),
+		Benefits:      benefits,
+		Min:           ticketType.Min,
+		Max:           ticketType.Max,
+		Quantity:      ticketType.Quantity,
+		InventoryMode: ticketType.InventoryMode,
+		Available:     ticketType.Available,
+		Sold:          ticketType.Sold,
+		Visibility:    ticketType.Visibility,
+		SalesStart:    ticketType.SalesStart,
+		SalesEnd:      ticketType.SalesEnd,
+		CurrentPrice:  currentPrice(money.New(ticketType.Price, ticketType.Currency), phases, ticketType.Sold, time.Now()),
+		PricePhases:   toPricePhases(phases, ticketType.Currency),
+		CreatedAt:     ticketType.CreatedAt,
+		UpdatedAt:     ticketType.UpdatedAt,
+	}
+}
+
+// CreateTicketTypeRequest describes a new ticket type and how many tickets of it to put on sale.
+type CreateTicketTypeRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Price       int64    `json:"price"`
+	Currency    string   `json:"currency"`
+	Benefits    []string `json:"benefits"`
+	// Min and Max bound how many tickets of this type one order may contain, 0 leaves the bound out
+	Min      int32 `json:"min"`
+	Max      int32 `json:"max"`
+	Quantity int   `json:"quantity"`
+	// InventoryMode defaults to rows. Counter suits large general admission types, it only creates ticket rows
+	// when tickets are bought, and cannot be changed later.
+	InventoryMode db.InventoryMode `json:"inventory_mode"`
+	// Visibility defaults to public. Hidden types are left out of public listings and can only be bought with an
+	// access code.
+	Visibility db.TicketVisibility `json:"visibility"`
+	// SalesStart and SalesEnd bound when the type is on sale, left out it is on sale until the event ends
+	SalesStart *time.Time `json:"sales_start"`
+	SalesEnd   *time.Time `json:"sales_end"`
+	// PricePhases replace Price while they last, in the order given
+	PricePhases []PricePhaseRequest `json:"price_phases"`
+}
+
+// Validate requires a name and checks the price, order limits, quantity, inventory mode, visibility, sales window
+// and price phases.
+func (req *CreateTicketTypeRequest) Validate() error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if req.Name == "" {
+		return eb.Msg("Name is required").Err()
+	}
+	switch req.InventoryMode {
+	case "", db.InventoryModeRows, db.InventoryModeCounter:
+	default:
+		return eb.Msgf("Unknown inventory mode %q", req.InventoryMode).Err()
+	}
+	if req.Visibility != "" {
+		if err := validateVisibility(req.Visibility); err != nil {
+			return err
+		}
+	}
+	if err := validatePrice(req.Price, req.Currency); err != nil {
+		return err
+	}
+	if err := validateOrderLimits(req.Min, req.Max); err != nil {
+		return err
+	}
+	if err := validateSalesWindow(req.SalesStart, req.SalesEnd); err != nil {
+		return err
+	}
+	if err := validatePricePhases(req.PricePhases); err != nil {
+		return err
+	}
+	return validateQuantity(req.Quantity)
+}
+
+// UpdateTicketTypeRequest changes a ticket type. Fields left out stay as they are.
+type UpdateTicketTypeRequest struct {
+	Name        *string              `json:"name"`
+	Description *string              `json:"description"`
+	Price       *int64               `json:"price"`
+	Currency    *string              `json:"currency"`
+	Benefits    []string             `json:"benefits"`
+	Min         *int32               `json:"min"`
+	Max         *int32               `json:"max"`
+	Quantity    *int                 `json:"quantity"`
+	Visibility  *db.TicketVisibility `json:"visibility"`
+	SalesStart  *time.Time           `json:"sales_start"`
+	SalesEnd    *time.Time           `json:"sales_end"`
+	// ClearSalesStart and ClearSalesEnd remove the opening or closing date of the sales window
+	ClearSalesStart bool `json:"clear_sales_start"`
+	ClearSalesEnd   bool `json:"clear_sales_end"`
+	// PricePhases replaces all phases of the type, an empty list removes them
+	PricePhases []PricePhaseRequest `json:"price_phases"`
+}
+
+// Validate checks the fields that are set. Min and max, and the sales window, are checked once merged with the type.
+func (req *UpdateTicketTypeRequest) Validate() error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if req.Name != nil && *req.Name == "" {
+		return eb.Msg("Name must not be empty").Err()
+	}
+	if req.Price != nil {
+		if err := validatePrice(*req.Price, ""); err != nil {
+			return err
+		}
+	}
+	if req.Currency != nil && !money.IsSupported(*req.Currency) {
+		return eb.Msgf("Unsupported currency %q", *req.Currency).Err()
+	}
+	if req.ClearSalesStart && req.SalesStart != nil {
+		return eb.Msg("sales_start cannot be set and cleared at once").Err()
+	}
+	if req.ClearSalesEnd && req.SalesEnd != nil {
+		return eb.Msg("sales_end cannot be set and cleared at once").Err()
+	}
+	if req.Visibility != nil {
+		if err := validateVisibility(*req.Visibility); err != nil {
+			return err
+		}
+	}
+	if err := validatePricePhases(req.PricePhases); err != nil {
+		return err
+	}
+	if req.Quantity != nil {
+		return validateQuantity(*req.Quantity)
+	}
+
+	return nil
+}
+
+// validateOrderLimits rejects negative limits and a min above the max.
+func validateOrderLimits(min, max int32) error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if min < 0 || max < 0 {
+		return eb.Msg("Min and max must not be negative").Err()
+	}
+	if max > 0 && min > max {
+		return eb.Msgf("Min (%d) must not be greater than max (%d)", min, max).Err()
+	}
+
+	return nil
+}
+
+// validateVisibility rejects unknown ticket type visibilities.
+func validateVisibility(visibility db.TicketVisibility) error {
+	switch visibility {
+	case db.TicketVisibilityPublic, db.TicketVisibilityHidden:
+		return nil
+	default:
+		return errs.B().Code(errs.InvalidArgument).Msgf("Unknown visibility %q", visibility).Err()
+	}
+}
+
+// visibleToCaller reports whether a ticket type may be shown to the caller. Hidden types are only shown to admins,
+// buyers see them through ListDistinctTickets with an access code.
+func visibleToCaller(visibility db.TicketVisibility) bool {
+	if visibility != db.TicketVisibilityHidden {
+		return true
+	}
+	_, authenticated := auth.UserID()
+	return authenticated
+}
+
+// validateQuantity rejects a negative number of tickets.
+func validateQuantity(quantity int) error {
+	if quantity < 0 {
+		return errs.B().Code(errs.InvalidArgument).Msg("Quantity must not be negative").Err()
+	}
+	return nil
+}
+
+// newTicketHashes returns count random ticket hashes.
+func newTicketHashes(count int) []string {
+	hashes := make([]string, count)
+	for i := range hashes {
+		hashes[i] = newTicketHash()
+	}
+	return hashes
+}
+
+// addTickets puts count more tickets of a type on sale. Row types get a row per ticket copying the type's details,
+// counter types get a higher capacity. The caller is expected to hold the type's row lock.
+func addTickets(ctx context.Context, q *db.Queries, ticketType db.TicketType, count int) (int64, error) {
+	if count <= 0 {
+		return 0, nil
+	}
+
+	if ticketType.InventoryMode == db.InventoryModeCounter {
+		return int64(count), q.SetTicketTypeCapacity(ctx, db.SetTicketTypeCapacityParams{
+			Quantity:     ticketType.Quantity + int32(count),
+			TicketTypeID: ticketType.ID,
+		})
+	}
+
+	issued, err := q.InsertTypeTickets(ctx, db.InsertTypeTicketsParams{
+		Hashes:       newTicketHashes(count),
+		TicketTypeID: ticketType.ID,
+	})
+	if err != nil {
+		return issued, err
+	}
+
+	return issued, q.SyncTicketTypeQuantity(ctx, ticketType.ID)
+}
+
+// removeTickets takes up to count unsold tickets of a type off sale. Tickets of row types that are locked by a
+// purchase in progress are skipped, so fewer than count may be removed. The caller is expected to hold the type's
+// row lock.
+func removeTickets(ctx context.Context, q *db.Queries, ticketType db.TicketType, count int) (int64, error) {
+	if count <= 0 {
+		return 0, nil
+	}
+
+	if ticketType.InventoryMode == db.InventoryModeCounter {
+		unsold := int(ticketType.Quantity - ticketType.ReservedCount - ticketType.SoldCount)
+		count = min(count, unsold)
+		return int64(count), q.SetTicketTypeCapacity(ctx, db.SetTicketTypeCapacityParams{
+			Quantity:     ticketType.Quantity - int32(count),
+			TicketTypeID: ticketType.ID,
+		})
+	}
+
+	removed, err := q.DeleteAvailableTicketsOfType(ctx, db.DeleteAvailableTicketsOfTypeParams{
+		TicketTypeID: ticketType.ID,
+		Limits:       int32(count),
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, q.SyncTicketTypeQuantity(ctx, ticketType.ID)
+}
+
+// reserveCounterTickets takes amount tickets off a counter type's capacity and issues their rows as pending, as
+// part of the caller's transaction. The capacity update locks the type's row until the transaction ends, so a
+// purchase that fails or never commits hands the tickets back by rolling back. Nothing is issued when less than
+// amount is left.
+func reserveCounterTickets(ctx context.Context, q *db.Queries, ticketTypeID pgtype.UUID, amount int) ([]db.GetAvailableTicketsRow, error) {
+	reserved, err := q.ReserveTicketTypeCapacity(ctx, db.ReserveTicketTypeCapacityParams{
+		Amount:       int32(amount),
+		TicketTypeID: ticketTypeID,
+	})
+	if err != nil || reserved == 0 {
+		return nil, err
+	}
+
+	issued, err := q.IssueReservedTickets(ctx, db.IssueReservedTicketsParams{
+		Hashes:       newTicketHashes(amount),
+		TicketTypeID: ticketTypeID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := make([]db.GetAvailableTicketsRow, 0, len(issued))
+	for _, ticket := range issued {
+		tickets = append(tickets, db.GetAvailableTicketsRow(ticket))
+	}
+
+	return tickets, nil
+}
+
+// isUniqueViolation reports whether err was caused by a unique constraint, such as a duplicate ticket type name.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// CreateTicketType Create a ticket type of an event and put its tickets on sale
+//
+//encore:api auth method=POST path=/v1/events/:id/ticket-types
+func CreateTicketType(ctx context.Context, id uuid.UUID, req *CreateTicketTypeRequest) (*BaseResponse[TicketType], error) {
+	eb := errs.B()
+
+	benefits, err := json.Marshal(req.Benefits)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.InvalidArgument).Msg("Invalid benefits").Err()
+	}
+
+	inventoryMode := req.InventoryMode
+	if inventoryMode == "" {
+		inventoryMode = db.InventoryModeRows
+	}
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = db.TicketVisibilityPublic
+	}
+
+	// Start a database transaction
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to start transaction").Err()
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+
+	ticketTypeID, err := qtx.InsertTicketType(ctx, db.InsertTicketTypeParams{
+		EventID: pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		},
+		Name:          req.Name,
+		Description:   req.Description,
+		Price:         req.Price,
+		Currency:      money.New(req.Price, req.Currency).Currency,
+		Benefits:      benefits,
+		Min:           req.Min,
+		Max:           req.Max,
+		InventoryMode: inventoryMode,
+		SalesStart:    timestamptz(req.SalesStart),
+		SalesEnd:      timestamptz(req.SalesEnd),
+		Visibility:    visibility,
+	})
+	if isUniqueViolation(err) {
+		return nil, eb.Code(errs.AlreadyExists).Msgf("The event already has a ticket type named %q", req.Name).Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while creating ticket type", "CreateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while creating ticket type").Err()
+	}
+
+	created, err := qtx.GetTicketTypeForUpdate(ctx, ticketTypeID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket type", "CreateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving ticket type").Err()
+	}
+	if _, err := addTickets(ctx, qtx, created, req.Quantity); err != nil {
+		rlog.Error("An error occurred while creating tickets", "CreateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while creating tickets").Err()
+	}
+	if err := replacePricePhases(ctx, qtx, ticketTypeID, req.PricePhases); err != nil {
+		rlog.Error("An error occurred while creating price phases", "CreateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while creating price phases").Err()
+	}
+
+	ticketType, err := qtx.GetTicketType(ctx, ticketTypeID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket type", "CreateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving ticket type").Err()
+	}
+	phases, err := qtx.ListTicketPricePhase(ctx, ticketTypeID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving price phases", "CreateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving price phases").Err()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		rlog.Error("failed to commit your transaction", "err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to commit transaction").Err()
+	}
+	committed = true
+
+	return &BaseResponse[TicketType]{
+		Data:    toTicketType(ticketType, phases),
+		Message: "Ticket type created successfully",
+	}, nil
+}
+
+// ListTicketTypes List the ticket types of an event with how many tickets are left
+//
+// Hidden types are only listed for admins.
+//
+//encore:api public method=GET path=/v1/events/:id/ticket-types
+func ListTicketTypes(ctx context.Context, id uuid.UUID) (*BaseResponse[[]TicketType], error) {
+	eb := errs.B()
+
+	eventID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	data, err := query.ListTicketType(ctx, eventID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket types", "ListTicketTypes:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving ticket types").Err()
+	}
+	phases, err := listEventPricePhases(ctx, eventID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving price phases", "ListTicketTypes:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving price phases").Err()
+	}
+
+	ticketTypes := make([]TicketType, 0, len(data))
+	for _, ticketType := range data {
+		if !visibleToCaller(ticketType.Visibility) {
+			continue
+		}
+		ticketTypes = append(ticketTypes, toTicketType(db.GetTicketTypeRow(ticketType), phases[ticketType.ID]))
+	}
+
+	return &BaseResponse[[]TicketType]{
+		Data:    ticketTypes,
+		Message: "Ticket types retrieved successfully",
+	}, nil
+}
+
+// GetTicketType Get a ticket type with how many tickets are left
+//
+//encore:api public method=GET path=/v1/ticket-types/:id
+func GetTicketType(ctx context.Context, id uuid.UUID) (*BaseResponse[TicketType], error) {
+	eb := errs.B()
+
+	ticketType, err := query.GetTicketType(ctx, pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	})
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !visibleToCaller(ticketType.Visibility)) {
+		return nil, eb.Code(errs.NotFound).Msg("Ticket type not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket type", "GetTicketType:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving ticket type").Err()
+	}
+	phases, err := query.ListTicketPricePhase(ctx, ticketType.ID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving price phases", "GetTicketType:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving price phases").Err()
+	}
+
+	return &BaseResponse[TicketType]{
+		Data:    toTicketType(ticketType, phases),
+		Message: "Ticket type retrieved successfully",
+	}, nil
+}
+
+// UpdateTicketType Update a ticket type
+//
+// Changes only reach tickets that are still on sale: sold and reserved tickets keep the details and the price
+// they were bought at. Quantity adds tickets or takes unsold ones off sale, and cannot go below the tickets
+// already sold or reserved.
+//
+//encore:api auth method=PUT path=/v1/ticket-types/:id
+func UpdateTicketType(ctx context.Context, id uuid.UUID, req *UpdateTicketTypeRequest) (*BaseResponse[TicketType], error) {
+	eb := errs.B()
+
+	// Start a database transaction
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to start transaction").Err()
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+
+	ticketTypeID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	// Lock the type so concurrent changes to its quantity cannot interleave
+	current, err := qtx.GetTicketTypeForUpdate(ctx, ticketTypeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Ticket type not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket type", "UpdateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving ticket type").Err()
+	}
+
+	min, max := current.Min, current.Max
+	if req.Min != nil {
+		min = *req.Min
+	}
+	if req.Max != nil {
+		max = *req.Max
+	}
+	if err := validateOrderLimits(min, max); err != nil {
+		return nil, err
+	}
+	salesStart, salesEnd := timestampPtr(current.SalesStart), timestampPtr(current.SalesEnd)
+	if req.SalesStart != nil || req.ClearSalesStart {
+		salesStart = req.SalesStart
+	}
+	if req.SalesEnd != nil || req.ClearSalesEnd {
+		salesEnd = req.SalesEnd
+	}
+	if err := validateSalesWindow(salesStart, salesEnd); err != nil {
+		return nil, err
+	}
+
+	params := db.UpdateTicketTypeParams{
+		TicketTypeID: ticketTypeID,
+	}
+	if req.Name != nil {
+		params.Name = pgtype.Text{String: *req.Name, Valid: true}
+	}
+	if req.Description != nil {
+		params.Description = pgtype.Text{String: *req.Description, Valid: true}
+	}
+	if req.Price != nil {
+		params.Price = pgtype.Int8{Int64: *req.Price, Valid: true}
+	}
+	if req.Currency != nil {
+		params.Currency = pgtype.Text{String: *req.Currency, Valid: true}
+	}
+	if req.Benefits != nil {
+		params.Benefits, err = json.Marshal(req.Benefits)
+		if err != nil {
+			return nil, eb.Cause(err).Code(errs.InvalidArgument).Msg("Invalid benefits").Err()
+		}
+	}
+	if req.Min != nil {
+		params.Min = pgtype.Int4{Int32: *req.Min, Valid: true}
+	}
+	if req.Max != nil {
+		params.Max = pgtype.Int4{Int32: *req.Max, Valid: true}
+	}
+	params.SalesStart = timestamptz(req.SalesStart)
+	params.SalesEnd = timestamptz(req.SalesEnd)
+	params.ClearSalesStart = req.ClearSalesStart
+	params.ClearSalesEnd = req.ClearSalesEnd
+	if req.Visibility != nil {
+		params.Visibility = db.NullTicketVisibility{TicketVisibility: *req.Visibility, Valid: true}
+	}
+
+	err = qtx.UpdateTicketType(ctx, params)
+	if isUniqueViolation(err) {
+		return nil, eb.Code(errs.AlreadyExists).Msgf("The event already has a ticket type named %q", *req.Name).Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while updating ticket type", "UpdateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating ticket type").Err()
+	}
+
+	// Tickets that are sold or reserved keep what they were bought with
+	if _, err := qtx.UpdateAvailableTicketsOfType(ctx, ticketTypeID); err != nil {
+		rlog.Error("An error occurred while updating tickets", "UpdateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating tickets").Err()
+	}
+	if req.PricePhases != nil {
+		if err := replacePricePhases(ctx, qtx, ticketTypeID, req.PricePhases); err != nil {
+			rlog.Error("An error occurred while updating price phases", "UpdateTicketType:err", err.Error())
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating price phases").Err()
+		}
+	}
+
+	if req.Quantity != nil {
+		counts, err := qtx.GetTicketType(ctx, ticketTypeID)
+		if err != nil {
+			rlog.Error("An error occurred while counting tickets", "UpdateTicketType:err", err.Error())
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while counting tickets").Err()
+		}
+		if int64(*req.Quantity) < counts.Claimed {
+			return nil, eb.Code(errs.FailedPrecondition).Msgf("Quantity cannot be lower than the %d tickets already sold or reserved", counts.Claimed).Err()
+		}
+
+		change := *req.Quantity - int(counts.Available+counts.Claimed)
+		if change > 0 {
+			if _, err := addTickets(ctx, qtx, current, change); err != nil {
+				rlog.Error("An error occurred while creating tickets", "UpdateTicketType:err", err.Error())
+				return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while creating tickets").Err()
+			}
+		}
+		if change < 0 {
+			withdrawn, err := removeTickets(ctx, qtx, current, -change)
+			if err != nil {
+				rlog.Error("An error occurred while deleting tickets", "UpdateTicketType:err", err.Error())
+				return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while deleting tickets").Err()
+			}
+			if withdrawn < int64(-change) {
+				return nil, eb.Code(errs.Aborted).Msg("Some of these tickets are being bought right now, try again shortly").Err()
+			}
+		}
+	}
+
+	ticketType, err := qtx.GetTicketType(ctx, ticketTypeID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket type", "UpdateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving ticket type").Err()
+	}
+	phases, err := qtx.ListTicketPricePhase(ctx, ticketTypeID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving price phases", "UpdateTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving price phases").Err()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		rlog.Error("failed to commit your transaction", "err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to commit transaction").Err()
+	}
+	committed = true
+
+	return &BaseResponse[TicketType]{
+		Data:    toTicketType(ticketType, phases),
+		Message: "Ticket type updated successfully",
+	}, nil
+}
+
+// DeleteTicketType Delete a ticket type that has not sold any tickets yet
+//
+//encore:api auth method=DELETE path=/v1/ticket-types/:id
+func DeleteTicketType(ctx context.Context, id uuid.UUID) (*BaseResponse[DeletesResponse], error) {
+	eb := errs.B()
+
+	// Start a database transaction
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to start transaction").Err()
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+
+	ticketTypeID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	current, err := qtx.GetTicketTypeForUpdate(ctx, ticketTypeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Ticket type not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket type", "DeleteTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving ticket type").Err()
+	}
+
+	counts, err := qtx.GetTicketType(ctx, ticketTypeID)
+	if err != nil {
+		rlog.Error("An error occurred while counting tickets", "DeleteTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while counting tickets").Err()
+	}
+	if counts.Claimed > 0 {
+		return nil, eb.Code(errs.FailedPrecondition).Msgf("%d tickets of this type are sold or reserved, lower its quantity instead", counts.Claimed).Err()
+	}
+
+	// remove the tickets first, so one a buyer is holding right now is not deleted from under them
+	withdrawn, err := removeTickets(ctx, qtx, current, int(counts.Available))
+	if err != nil {
+		rlog.Error("An error occurred while deleting tickets", "DeleteTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while deleting tickets").Err()
+	}
+	if withdrawn < counts.Available {
+		return nil, eb.Code(errs.Aborted).Msg("Some of these tickets are being bought right now, try again shortly").Err()
+	}
+
+	if err := qtx.DeleteTicketType(ctx, ticketTypeID); err != nil {
+		rlog.Error("An error occurred while deleting ticket type", "DeleteTicketType:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while deleting ticket type").Err()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		rlog.Error("failed to commit your transaction", "err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to commit transaction").Err()
+	}
+	committed = true
+
+	return &BaseResponse[DeletesResponse]{
+		Data: DeletesResponse{
+			Deleted: int(withdrawn),
+		},
+		Message: "Ticket type deleted successfully",
+	}, nil
+}
@@ -3,6 +3,7 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -17,95 +18,125 @@ import (
 	"encore.dev/types/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/lichtlabs/ggrims-service/events/db"
+	mailtempl "github.com/lichtlabs/ggrims-service/mail/template"
+	"github.com/lichtlabs/ggrims-service/money"
 )
 
 // LetterBytes is a constant string containing alphanumeric characters used for generating random strings.
 const LetterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
+// newTicketHash returns a random hash to encode in a ticket's QR code.
+func newTicketHash() string {
+	b := make([]byte, 32)
+	for i := range b {
+		b[i] = LetterBytes[rand.Intn(len(LetterBytes))]
+	}
+	return string(b)
+}
+
 // CreateTicketRequest represents a request to create a new ticket for an event. It includes the ticket's name,
 // description, price, associated benefits, and the total number of tickets to create.
 type CreateTicketRequest struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
-	Price       string   `json:"price"`
+	Price       int64    `json:"price"`
+	Currency    string   `json:"currency"`
 	Benefits    []string `json:"benefits"`
 	TicketCount int      `json:"ticket_count"`
-	Min         int      `json:"min"`
-	Max         int      `json:"max"`
+	// Min and Max bound how many tickets of this type one order may contain, 0 leaves the bound out
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Validate rejects negative prices, unsupported currencies and order limits where min exceeds max.
+func (req *CreateTicketRequest) Validate() error {
+	if err := validatePrice(req.Price, req.Currency); err != nil {
+		return err
+	}
+	if err := validateOrderLimits(int32(req.Min), int32(req.Max)); err != nil {
+		return err
+	}
+	return validateQuantity(req.TicketCount)
 }
 
 // CreateTickets creates multiple tickets for an event and inserts them into the database within a transaction.
-// It takes a context, event ID (UUID), and a CreateTicketRequest object as input.
+// The tickets belong to the ticket type with the same name, which is created from the request when the event
+// does not have it yet. Tickets added to an existing type copy that type's details.
 // Returns a BaseResponse containing the count of created tickets and a success message, or an error if operation fails.
 //
 //encore:api auth method=POST path=/v1/events/:id/tickets/create
 func CreateTickets(ctx context.Context, id uuid.UUID, req *CreateTicketRequest) (*BaseResponse[InsertionResponse], error) {
 	eb := errs.B()
 
+	benefits, err := json.Marshal(req.Benefits)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to marshal err").Err()
+	}
+
 	// Start a database transaction
 	tx, err := pgxDB.Begin(ctx)
 	if err != nil {
 		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to start transaction").Err()
 	}
-	defer func(tx pgx.Tx, ctx context.Context) {
-		err := tx.Rollback(ctx)
-		if err != nil {
-			rlog.Error("An error occurred while rolling back the transaction", "Rollback:err", err.Error())
-		}
-	}(tx, ctx)
 
-	ticketHash := func(n int) string {
-		b := make([]byte, n)
-		for i := range b {
-			b[i] = LetterBytes[rand.Intn(len(LetterBytes))]
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("An error occurred while rolling back the transaction", "Rollback:err", err.Error())
+			}
 		}
-		return string(b)
-	}
+	}()
 
-	benefits, err := json.Marshal(req.Benefits)
-	if err != nil {
-		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to marshal err").Err()
+	qtx := query.WithTx(tx)
+
+	eventID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
 	}
 
-	created := 0
-	for i := 0; i < req.TicketCount; i++ {
-		_, err := query.InsertTicket(ctx, db.InsertTicketParams{
-			Name:        req.Name,
-			Description: req.Description,
-			Price:       req.Price,
-			Benefits:    benefits,
-			Hash: pgtype.Text{
-				String: ticketHash(32),
-				Valid:  true,
-			},
-			EventID: pgtype.UUID{
-				Bytes: id,
-				Valid: true,
-			},
-			Min: pgtype.Int4{
-				Int32: int32(req.Min),
-				Valid: true,
-			},
-			Max: pgtype.Int4{
-				Int32: int32(req.Max),
-				Valid: true,
-			},
+	ticketType, err := qtx.GetTicketTypeByNameForUpdate(ctx, db.GetTicketTypeByNameForUpdateParams{
+		EventID: eventID,
+		Name:    req.Name,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		var ticketTypeID pgtype.UUID
+		ticketTypeID, err = qtx.InsertTicketType(ctx, db.InsertTicketTypeParams{
+			EventID:       eventID,
+			Name:          req.Name,
+			Description:   req.Description,
+			Price:         req.Price,
+			Currency:      money.New(req.Price, req.Currency).Currency,
+			Benefits:      benefits,
+			Min:           int32(req.Min),
+			Max:           int32(req.Max),
+			InventoryMode: db.InventoryModeRows,
 		})
-		if err != nil {
-			rlog.Error("An error occurred while creating a ticket", "CreateTicket:err", err.Error())
-			return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("failed to create tickets").Err()
+		if err == nil {
+			ticketType, err = qtx.GetTicketTypeForUpdate(ctx, ticketTypeID)
 		}
-		created++
+	}
+	if err != nil {
+		rlog.Error("An error occurred while creating a ticket", "CreateTicket:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("failed to create tickets").Err()
+	}
+
+	created, err := addTickets(ctx, qtx, ticketType, req.TicketCount)
+	if err != nil {
+		rlog.Error("An error occurred while creating a ticket", "CreateTicket:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("failed to create tickets").Err()
 	}
 
 	// Commit the transaction if all tickets are created successfully
 	if err := tx.Commit(ctx); err != nil {
 		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to commit transaction").Err()
 	}
+	committed = true
 
 	return &BaseResponse[InsertionResponse]{
 		Data: InsertionResponse{
-			Created: created,
+			Created: int(created),
 		},
 		Message: "Tickets created successfully",
 	}, nil
@@ -115,46 +146,88 @@ func CreateTickets(ctx context.Context, id uuid.UUID, req *CreateTicketRequest)
 type UpdateTicketRequest struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
-	Price       string   `json:"price"`
+	Price       int64    `json:"price"`
+	Currency    string   `json:"currency"`
 	Benefits    []string `json:"benefits"`
-	TicketCount int      `json:"ticket_count"`
+	// Deprecated: every unsold ticket of the type is updated
+	TicketCount int `json:"ticket_count"`
 }
 
-// UpdateTickets updates a specified number of tickets in the database within a single transaction.
+// Validate rejects negative prices and unsupported currencies.
+func (req *UpdateTicketRequest) Validate() error {
+	return validatePrice(req.Price, req.Currency)
+}
+
+// UpdateTickets updates the ticket type named in the request and its unsold tickets. Sold and reserved tickets keep
+// the details and price they were bought with. Use UpdateTicketType to rename a type or change its quantity.
 //
 //encore:api auth method=PUT path=/v1/events/:id/tickets/update
 func UpdateTickets(ctx context.Context, id uuid.UUID, req *UpdateTicketRequest) (*BaseResponse[UpdatesResponse], error) {
 	eb := errs.B()
 
+	benefits, err := json.Marshal(req.Benefits)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to marshal err").Err()
+	}
+
 	// Start a database transaction
 	tx, err := pgxDB.Begin(ctx)
 	if err != nil {
 		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to start transaction").Err()
 	}
-	defer func(tx pgx.Tx, ctx context.Context) {
-		err := tx.Rollback(ctx)
-		if err != nil {
-			rlog.Error("An error occurred while rolling back the transaction", "Rollback:err", err.Error())
-		}
-	}(tx, ctx)
-
-	updated := 0
-	for i := 0; i < req.TicketCount; i++ {
-		err := query.UpdateTicket(ctx, db.UpdateTicketParams{
-			Name:        req.Name,
-			Description: req.Description,
-			Price:       req.Price,
-			Benefits:    []byte(strings.Join(req.Benefits, ",")),
-			EventID: pgtype.UUID{
-				Bytes: id,
-				Valid: true,
-			},
-		})
-		if err != nil {
-			rlog.Error("An error occurred while updating a ticket", "UpdateTicket:err", err.Error())
-			return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("failed to update tickets").Err()
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("An error occurred while rolling back the transaction", "Rollback:err", err.Error())
+			}
 		}
-		updated++
+	}()
+
+	qtx := query.WithTx(tx)
+
+	ticketType, err := qtx.GetTicketTypeByNameForUpdate(ctx, db.GetTicketTypeByNameForUpdateParams{
+		EventID: pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		},
+		Name: req.Name,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msgf("The event has no ticket type named %q", req.Name).Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while updating a ticket", "UpdateTicket:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("failed to update tickets").Err()
+	}
+
+	err = qtx.UpdateTicketType(ctx, db.UpdateTicketTypeParams{
+		Description: pgtype.Text{
+			String: req.Description,
+			Valid:  true,
+		},
+		Price: pgtype.Int8{
+			Int64: req.Price,
+			Valid: true,
+		},
+		Currency: pgtype.Text{
+			String: money.New(req.Price, req.Currency).Currency,
+			Valid:  true,
+		},
+		Benefits:     benefits,
+		TicketTypeID: ticketType.ID,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while updating a ticket", "UpdateTicket:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("failed to update tickets").Err()
+	}
+
+	updated, err := qtx.UpdateAvailableTicketsOfType(ctx, ticketType.ID)
+	if err != nil {
+		rlog.Error("An error occurred while updating a ticket", "UpdateTicket:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("failed to update tickets").Err()
 	}
 
 	// Commit the transaction if all tickets are updated successfully
@@ -162,12 +235,13 @@ func UpdateTickets(ctx context.Context, id uuid.UUID, req *UpdateTicketRequest)
 		log.Println("failed to commit transaction", err)
 		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to commit transaction").Err()
 	}
+	committed = true
 
 	return &BaseResponse[UpdatesResponse]{
 		Data: UpdatesResponse{
-			Updated: req.TicketCount,
+			Updated: int(updated),
 		},
-		Message: "Tickets created successfully",
+		Message: "Tickets updated successfully",
 	}, nil
 }
 
@@ -177,62 +251,124 @@ type DeleteTicketRequest struct {
 	TicketCount int    `json:"ticket_count"`
 }
 
-// DeleteTickets removes a specified number of tickets based on the given `DeleteTicketRequest`.
-// It returns a `BaseResponse` containing `DeletesResponse` with the number of deleted tickets or an error.
+// DeleteTickets takes up to the requested number of unsold tickets of the named type off sale. Sold and reserved
+// tickets are never deleted. It returns a `BaseResponse` containing `DeletesResponse` with the number of deleted
+// tickets or an error.
 //
 //encore:api auth method=DELETE path=/v1/events/:id/tickets/delete
 func DeleteTickets(ctx context.Context, id uuid.UUID, req *DeleteTicketRequest) (*BaseResponse[DeletesResponse], error) {
 	eb := errs.B()
 
-	err := query.DeleteTicket(ctx, db.DeleteTicketParams{
-		TicketName: req.TicketName,
-		Limits:     int32(req.TicketCount),
-		EvID: pgtype.UUID{
+	// Start a database transaction
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to start transaction").Err()
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("An error occurred while rolling back the transaction", "Rollback:err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+
+	ticketType, err := qtx.GetTicketTypeByNameForUpdate(ctx, db.GetTicketTypeByNameForUpdateParams{
+		EventID: pgtype.UUID{
 			Bytes: id,
 			Valid: true,
 		},
+		Name: req.TicketName,
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msgf("The event has no ticket type named %q", req.TicketName).Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while deleting a ticket", "DeleteTicket:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("failed to delete tickets").Err()
+	}
+
+	deleted, err := removeTickets(ctx, qtx, ticketType, req.TicketCount)
 	if err != nil {
 		rlog.Error("An error occurred while deleting a ticket", "DeleteTicket:err", err.Error())
 		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("failed to delete tickets").Err()
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, eb.Cause(err).Code(errs.Unavailable).Msg("failed to commit transaction").Err()
+	}
+	committed = true
+
 	return &BaseResponse[DeletesResponse]{
 		Data: DeletesResponse{
-			Deleted: req.TicketCount,
+			Deleted: int(deleted),
 		},
 		Message: "Tickets deleted successfully",
 	}, nil
 }
 
 type ListDistinctTicketsResponse struct {
-	EventID     pgtype.UUID        `json:"event_id"`
-	Name        string             `json:"name"`
-	Description string             `json:"description"`
-	Price       string             `json:"price"`
-	Benefits    []string           `json:"benefits"`
-	Status      db.TicketStatus    `json:"status"`
-	Min         int32              `json:"min"`
-	Max         int32              `json:"max"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
-	Count       int64              `json:"count"`
+	EventID     pgtype.UUID         `json:"event_id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Price       money.Money         `json:"price"`
+	Benefits    []string            `json:"benefits"`
+	Status      db.TicketStatus     `json:"status"`
+	Min         int32               `json:"min"`
+	Max         int32               `json:"max"`
+	SalesStart  pgtype.Timestamptz  `json:"sales_start"`
+	SalesEnd    pgtype.Timestamptz  `json:"sales_end"`
+	Visibility  db.TicketVisibility `json:"visibility"`
+	CreatedAt   pgtype.Timestamptz  `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz  `json:"updated_at"`
+	Count       int64               `json:"count"`
+}
+
+// ListDistinctTicketsParams holds the optional access code that adds the hidden ticket types it unlocks.
+type ListDistinctTicketsParams struct {
+	AccessCode string `query:"access_code"`
 }
 
 // ListDistinctTickets retrieves a list of distinct tickets based on a given event ID.
 // It returns a BaseResponse containing a list of ListDistinctTicketsResponse or an error if the operation fails.
+// Hidden ticket types are only included when params carries an access code that unlocks them.
 //
 //encore:api public method=GET path=/v1/events/:id/tickets/distinct
-func ListDistinctTickets(ctx context.Context, id uuid.UUID) (*BaseResponse[[]ListDistinctTicketsResponse], error) {
+func ListDistinctTickets(ctx context.Context, id uuid.UUID, params *ListDistinctTicketsParams) (*BaseResponse[[]ListDistinctTicketsResponse], error) {
 	eb := errs.B()
 
-	data, err := query.ListDistinctTicket(ctx, pgtype.UUID{
+	eventID := pgtype.UUID{
 		Bytes: id,
 		Valid: true,
+	}
+
+	var unlocked []pgtype.UUID
+	if params.AccessCode != "" {
+		accessCode, err := getAccessCode(ctx, query, eventID, params.AccessCode, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		unlocked, err = unlockedTicketTypes(ctx, query, accessCode)
+		if err != nil {
+			return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving the unlocked ticket types").Err()
+		}
+	}
+
+	data, err := query.ListDistinctTicket(ctx, db.ListDistinctTicketParams{
+		EventID:               eventID,
+		UnlockedTicketTypeIds: unlocked,
 	})
 	if err != nil {
 		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving distinct tickets").Err()
 	}
+	phases, err := listEventPricePhases(ctx, eventID)
+	if err != nil {
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving price phases").Err()
+	}
 
 	var tickets []ListDistinctTicketsResponse
 	for _, ticket := range data {
@@ -246,11 +382,14 @@ func ListDistinctTickets(ctx context.Context, id uuid.UUID) (*BaseResponse[[]Lis
 			EventID:     ticket.EventID,
 			Name:        ticket.Name,
 			Description: ticket.Description,
-			Price:       ticket.Price,
+			Price:       currentPrice(money.New(ticket.Price, ticket.Currency), phases[ticket.ID], ticket.Sold, time.Now()),
 			Benefits:    benefits,
-			Status:      ticket.Status,
-			Min:         ticket.Min.Int32,
-			Max:         ticket.Max.Int32,
+			Status:      db.TicketStatusAvailable,
+			Min:         ticket.Min,
+			Max:         ticket.Max,
+			SalesStart:  ticket.SalesStart,
+			SalesEnd:    ticket.SalesEnd,
+			Visibility:  ticket.Visibility,
 			CreatedAt:   ticket.CreatedAt,
 			UpdatedAt:   ticket.UpdatedAt,
 			Count:       ticket.Count,
@@ -264,10 +403,117 @@ func ListDistinctTickets(ctx context.Context, id uuid.UUID) (*BaseResponse[[]Lis
 }
 
 // BuyTicketRequest represents the payload required to purchase tickets for an event.
+//
+// Items lists the ticket types of a cart, each with its quantity and attendees, which are reserved together and
+// paid with one bill. Requests without items buy TicketAmount tickets of TicketName for Attendees.
+//
+// A retry sent with the same IdempotencyKey within IdempotencyKeyTTL gets the original reservation and bill back
+// instead of reserving again. A retry of a free order gets the confirmed order back.
 type BuyTicketRequest struct {
+	IdempotencyKey string `header:"Idempotency-Key"`
+
+	Items        []*BuyTicketItem     `json:"items"`
 	TicketName   string               `json:"ticket_name"`
 	TicketAmount int                  `json:"ticket_amount"`
 	Attendees    []*map[string]string `json:"attendees"`
+	BuyerName    string               `json:"buyer_name"`
+	BuyerEmail   string               `json:"buyer_email"`
+	// ClaimToken buys the tickets a waitlist offer holds, the cart has to contain them with the offered quantity
+	ClaimToken string `json:"claim_token"`
+	// PromoCode discounts the tickets of the types it applies to
+	PromoCode string `json:"promo_code"`
+	// AccessCode unlocks hidden ticket types, it is needed for every line of a hidden type
+	AccessCode string `json:"access_code"`
+}
+
+// BuyTicketItem is one line of a cart: how many tickets of a type to buy and who attends on them.
+type BuyTicketItem struct {
+	TicketName string               `json:"ticket_name"`
+	Quantity   int                  `json:"quantity"`
+	Attendees  []*map[string]string `json:"attendees"`
+}
+
+// cartItems returns the lines of the purchase, turning a single ticket purchase into a one line cart.
+func (req *BuyTicketRequest) cartItems() []*BuyTicketItem {
+	if len(req.Items) > 0 {
+		return req.Items
+	}
+
+	return []*BuyTicketItem{
+		{
+			TicketName: req.TicketName,
+			Quantity:   req.TicketAmount,
+			Attendees:  req.Attendees,
+		},
+	}
+}
+
+// Validate requires at least one ticket and one attendees entry per ticket on every line, and each ticket type
+// on one line only.
+func (req *BuyTicketRequest) Validate() error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+		return eb.Msgf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength).Err()
+	}
+
+	names := make(map[string]bool)
+	for _, item := range req.cartItems() {
+		if item == nil {
+			return eb.Msg("Cart items must not be empty").Err()
+		}
+		if item.Quantity < 1 {
+			return eb.Msgf("At least 1 %s ticket must be bought", item.TicketName).Err()
+		}
+		if len(item.Attendees) != item.Quantity {
+			return eb.Msgf("Expected %d attendees for %s, one per ticket, got %d", item.Quantity, item.TicketName, len(item.Attendees)).Err()
+		}
+		if names[item.TicketName] {
+			return eb.Msgf("%s tickets must be on a single line of the cart", item.TicketName).Err()
+		}
+		names[item.TicketName] = true
+	}
+
+	return nil
+}
+
+// checkOrderQuantity rejects an amount outside the min and max a ticket type allows per order.
+// Zero bounds are not enforced.
+func checkOrderQuantity(name string, amount int, min, max int32) error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if min > 0 && amount < int(min) {
+		return eb.Msgf("At least %d %s tickets must be bought per order", min, name).Err()
+	}
+	if max > 0 && amount > int(max) {
+		return eb.Msgf("At most %d %s tickets can be bought per order", max, name).Err()
+	}
+
+	return nil
+}
+
+// cartLine is a cart item with its ticket type and, once reserved, its tickets. The type's price phases and how
+// many of its tickets were sold before the purchase set the price of the line.
+type cartLine struct {
+	item       *BuyTicketItem
+	ticketType db.TicketType
+	phases     []db.TicketPricePhase
+	sold       int64
+	tickets    []db.GetAvailableTicketsRow
+}
+
+// cartTitle names a purchase on its bill: the ticket type of a single line cart, or each type with its quantity.
+func cartTitle(lines []cartLine) string {
+	if len(lines) == 1 {
+		return lines[0].ticketType.Name
+	}
+
+	parts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		parts = append(parts, fmt.Sprintf("%s x%d", line.ticketType.Name, len(line.tickets)))
+	}
+
+	return strings.Join(parts, ", ")
 }
 
 // BuyTicketResponse combines the data of a ticket purchase and the billing response.
@@ -298,93 +544,554 @@ func BuyTickets(ctx context.Context, id uuid.UUID, req *BuyTicketRequest) (*Base
 		}
 	}()
 
-	// get available tickets with name
-	availableTickets, err := query.GetAvailableTickets(ctx, db.GetAvailableTicketsParams{
-		Name:   req.TicketName,
-		Limits: int32(req.TicketAmount),
-	})
-	if availableTickets == nil {
-		return nil, eb.Code(errs.NotFound).Msg("No tickets available").Err()
+	qtx := query.WithTx(tx)
+
+	eventID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
 	}
+
+	// a retry returns the reservation of the first request, before anything else is reserved
+	var requestHash string
+	if req.IdempotencyKey != "" {
+		requestHash, err = purchaseHash(req)
+		if err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while checking the idempotency key").Err()
+		}
+		replayed, err := replayPurchase(ctx, qtx, eventID, req.IdempotencyKey, requestHash)
+		if err != nil {
+			return nil, err
+		}
+		if replayed != nil {
+			message := "Tickets reserved"
+			switch replayed.OrderStatus {
+			case db.OrderStatusPaid:
+				message = "Tickets confirmed"
+			case db.OrderStatusRefunded:
+				message = "Tickets refunded"
+			}
+			return &BaseResponse[BuyTicketResponse]{
+				Data:    *replayed,
+				Message: message,
+			}, nil
+		}
+	}
+
+	items := req.cartItems()
+
+	// check attendees against the event's ticket inputs before any tickets are locked
+	inputsData, err := qtx.GetEventTicketInputs(ctx, eventID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the event's ticket inputs").Err()
+	}
+	var inputs []*EventTicketInput
+	if len(inputsData) > 0 {
+		if err := json.Unmarshal(inputsData, &inputs); err != nil {
+			rlog.Error("An error occurred while decoding ticket inputs", "BuyTickets:err", err.Error())
+			return nil, eb.Code(errs.Internal).Msg("An error occurred while decoding ticket inputs").Err()
+		}
+	}
+	var fieldErrors []AttendeeFieldError
+	for i, item := range items {
+		attendeeData, itemErrors := validateAttendees(inputs, item.Attendees)
+		for _, fieldError := range itemErrors {
+			fieldError.Item = i
+			fieldErrors = append(fieldErrors, fieldError)
+		}
+		item.Attendees = attendeeData
+	}
+	if len(fieldErrors) > 0 {
+		return nil, eb.Code(errs.InvalidArgument).Msg("Some attendee details are missing or invalid").Details(AttendeeErrors{
+			Fields: fieldErrors,
+		}).Err()
+	}
+
+	eventEnd, err := qtx.GetEventEndDate(ctx, eventID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Event not found").Err()
+	}
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the event").Err()
+	}
+	now := time.Now()
+
+	var promo db.PromoCode
+	if req.PromoCode != "" {
+		promo, err = qtx.GetPromoCodeByCode(ctx, db.GetPromoCodeByCodeParams{
+			EventID: eventID,
+			Code:    req.PromoCode,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, eb.Code(errs.NotFound).Msgf("Promo code %s not found", req.PromoCode).Err()
+		}
+		if err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the promo code").Err()
+		}
+		if err := checkPromoCode(promo, now); err != nil {
+			return nil, err
+		}
+	}
+
+	var accessCode db.AccessCode
+	if req.AccessCode != "" {
+		accessCode, err = getAccessCode(ctx, qtx, eventID, req.AccessCode, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// check every line before any tickets are reserved
+	lines := make([]cartLine, 0, len(items))
+	var hiddenTickets int
+	for _, item := range items {
+		ticketType, err := qtx.GetTicketTypeByName(ctx, db.GetTicketTypeByNameParams{
+			EventID: eventID,
+			Name:    item.TicketName,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, eb.Code(errs.NotFound).Msgf("No %s tickets available", item.TicketName).Err()
+		}
+		if err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the ticket type").Err()
+		}
+		// without a code unlocking it a hidden type looks like one that does not exist
+		if ticketType.Visibility == db.TicketVisibilityHidden {
+			if !accessCodeUnlocks(accessCode, ticketType.ID) {
+				return nil, eb.Code(errs.NotFound).Msgf("No %s tickets available", item.TicketName).Err()
+			}
+			hiddenTickets += item.Quantity
+		}
+		if err := checkSalesWindow(item.TicketName, ticketType.SalesStart, ticketType.SalesEnd, eventEnd.Time, now); err != nil {
+			return nil, err
+		}
+		if err := checkOrderQuantity(item.TicketName, item.Quantity, ticketType.Min, ticketType.Max); err != nil {
+			return nil, err
+		}
+		if len(lines) > 0 && ticketType.Currency != lines[0].ticketType.Currency {
+			return nil, eb.Code(errs.InvalidArgument).Msg("All tickets of an order must be in the same currency").Err()
+		}
+
+		phases, err := qtx.ListTicketPricePhase(ctx, ticketType.ID)
+		if err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving price phases").Err()
+		}
+		// phases ending at a sold limit need to know how many tickets are sold
+		var sold int64
+		if len(phases) > 0 {
+			counts, err := qtx.GetTicketType(ctx, ticketType.ID)
+			if err != nil {
+				return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while counting tickets").Err()
+			}
+			sold = counts.Sold
+		}
+
+		lines = append(lines, cartLine{
+			item:       item,
+			ticketType: ticketType,
+			phases:     phases,
+			sold:       sold,
+		})
+	}
+
+	// tickets held for a waitlist offer can only be bought with its claim token
+	var offer db.WaitlistEntry
+	if req.ClaimToken != "" {
+		offer, err = claimWaitlistOffer(ctx, qtx, eventID, req.ClaimToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// reserve all lines or none; a failed line rolls back the rows and counters reserved so far
+	var offerClaimed bool
+	for i := range lines {
+		line := &lines[i]
+
+		if offer.ID.Valid && line.ticketType.ID == offer.TicketTypeID {
+			if line.item.Quantity != int(offer.Quantity) {
+				return nil, eb.Code(errs.InvalidArgument).Msgf("The waitlist offer holds %d %s tickets", offer.Quantity, line.item.TicketName).Err()
+			}
+			held, err := qtx.GetTicketsByIDs(ctx, offer.TicketIds)
+			if err != nil {
+				return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the offered tickets").Err()
+			}
+			for _, ticket := range held {
+				line.tickets = append(line.tickets, db.GetAvailableTicketsRow(ticket))
+			}
+			offerClaimed = true
+			continue
+		}
+
+		if line.ticketType.InventoryMode == db.InventoryModeCounter {
+			line.tickets, err = reserveCounterTickets(ctx, qtx, line.ticketType.ID, line.item.Quantity)
+			if err != nil {
+				return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while reserving tickets").Err()
+			}
+		} else {
+			// lock available tickets of this type; rows held by concurrent buyers are skipped
+			line.tickets, err = qtx.GetAvailableTickets(ctx, db.GetAvailableTicketsParams{
+				TicketTypeID: line.ticketType.ID,
+				Limits:       int32(line.item.Quantity),
+			})
+			if err != nil {
+				return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving available tickets").Err()
+			}
+		}
+
+		if len(line.tickets) < line.item.Quantity {
+			left := len(line.tickets)
+			if line.ticketType.InventoryMode == db.InventoryModeCounter {
+				left = int(line.ticketType.Quantity - line.ticketType.ReservedCount - line.ticketType.SoldCount)
+			}
+			if left <= 0 {
+				return nil, eb.Code(errs.NotFound).Msgf("No %s tickets available", line.item.TicketName).Err()
+			}
+			return nil, eb.Code(errs.ResourceExhausted).Msgf("Only %d %s tickets available", left, line.item.TicketName).Err()
+		}
+	}
+
+	if offer.ID.Valid {
+		if !offerClaimed {
+			return nil, eb.Code(errs.InvalidArgument).Msg("The cart does not contain the tickets of the waitlist offer").Err()
+		}
+		err := qtx.UpdateWaitlistEntryState(ctx, db.UpdateWaitlistEntryStateParams{
+			State:   db.WaitlistStateClaimed,
+			EntryID: offer.ID,
+		})
+		if err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while claiming the waitlist offer").Err()
+		}
+	}
+
+	pricing, err := qtx.GetEventPricing(ctx, eventID)
 	if err != nil {
-		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving available tickets").Err()
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the event's fees").Err()
+	}
+	fee, tax := pricingRules(pricing)
+
+	var ticketIds []pgtype.UUID
+	var ticketHashes []string
+	var attendees []*map[string]string
+	priceLines := make([]priceLine, 0, len(lines))
+	orderItems := make([]db.InsertOrderItemParams, 0, len(lines))
+	purchased := make([]OrderItem, 0, len(lines))
+	// every line is in the same currency, checked with the cart, so the discount is summed in it
+	eligible := money.New(0, lines[0].ticketType.Currency)
+	for _, line := range lines {
+		// the type sets the price, rows of the line may still carry what they were put on sale at
+		price := currentPrice(money.New(line.ticketType.Price, line.ticketType.Currency), line.phases, line.sold, now)
+		if promo.ID.Valid && promoApplies(promo, line.ticketType.ID) {
+			eligible = eligible.Add(price.Mul(int64(len(line.tickets))))
+		}
+
+		priceLines = append(priceLines, priceLine{
+			UnitPrice: price,
+			Quantity:  len(line.tickets),
+		})
+		orderItems = append(orderItems, db.InsertOrderItemParams{
+			TicketTypeID: line.ticketType.ID,
+			TicketName:   line.ticketType.Name,
+			Quantity:     int32(len(line.tickets)),
+			UnitPrice:    price.Amount,
+		})
+		purchased = append(purchased, OrderItem{
+			TicketName: line.ticketType.Name,
+			Quantity:   int32(len(line.tickets)),
+			UnitPrice:  price,
+		})
+		for j, ticket := range line.tickets {
+			ticketIds = append(ticketIds, ticket.ID)
+			ticketHashes = append(ticketHashes, ticket.Hash.String)
+			attendees = append(attendees, line.item.Attendees[j])
+		}
+	}
+
+	// apply the promo code before anything is billed
+	discount := money.New(0, eligible.Currency)
+	if promo.ID.Valid {
+		if eligible.IsZero() {
+			return nil, eb.Code(errs.InvalidArgument).Msgf("Promo code %s does not apply to these tickets", promo.Code).Err()
+		}
+		discount, err = promoDiscount(promo, eligible)
+		if err != nil {
+			return nil, err
+		}
+	}
+	promoCode := pgtype.Text{
+		String: promo.Code,
+		Valid:  promo.ID.Valid,
 	}
 
 	// call payments
-	price, err := strconv.Atoi(availableTickets[0].Price)
+	breakdown := priceOrder(priceLines, fee, tax, discount)
+	amount := breakdown.Total
+
+	// free orders are confirmed right away, there is nothing to pay
+	if amount.IsZero() {
+		if req.BuyerName == "" || req.BuyerEmail == "" {
+			return nil, eb.Code(errs.InvalidArgument).Msg("Buyer name and email are required for free tickets").Err()
+		}
+
+		// serialise claims of the same email so two requests cannot both pass the cap
+		err := qtx.LockBuyerEmail(ctx, db.LockBuyerEmailParams{
+			EventID:    eventID,
+			BuyerEmail: req.BuyerEmail,
+		})
+		if err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while checking free ticket claims").Err()
+		}
+		limit, err := qtx.GetEventFreeTicketLimit(ctx, eventID)
+		if err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while checking free ticket claims").Err()
+		}
+		claimed, err := qtx.CountFreeTicketsByEmail(ctx, db.CountFreeTicketsByEmailParams{
+			EventID:    eventID,
+			BuyerEmail: req.BuyerEmail,
+		})
+		if err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while checking free ticket claims").Err()
+		}
+		if claimed+int32(len(ticketIds)) > limit {
+			return nil, eb.Code(errs.ResourceExhausted).Msgf("Each email can claim at most %d free tickets for this event, %d already claimed", limit, claimed).Err()
+		}
+
+		orderID, err := createOrder(ctx, qtx, db.InsertOrderParams{
+			EventID:         eventID,
+			BuyerName:       req.BuyerName,
+			BuyerEmail:      req.BuyerEmail,
+			Subtotal:        breakdown.Subtotal.Amount,
+			Discount:        breakdown.Discount.Amount,
+			PromoCode:       promoCode,
+			Fee:             breakdown.Fee.Amount,
+			FeeAbsorbed:     breakdown.FeeAbsorbed,
+			Tax:             breakdown.Tax.Amount,
+			TaxName:         breakdown.TaxName,
+			Amount:          amount.Amount,
+			Currency:        amount.Currency,
+			PaymentProvider: PaymentProviderNone,
+		}, orderItems)
+		if err != nil {
+			rlog.Error("An error occurred while creating the order", "BuyTickets:err", err.Error())
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while creating the order").Err()
+		}
+		if promo.ID.Valid {
+			if err := redeemPromoCode(ctx, qtx, promo, orderID, discount); err != nil {
+				return nil, err
+			}
+		}
+		if hiddenTickets > 0 {
+			if err := redeemAccessCode(ctx, qtx, accessCode, orderID, hiddenTickets); err != nil {
+				return nil, err
+			}
+		}
+		if err := transitionOrder(ctx, qtx, orderID, db.OrderStatusPaid, "free order confirmed"); err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating the order").Err()
+		}
+
+		if err := sellTickets(ctx, qtx, eventID, ticketIds, attendees); err != nil {
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while confirming the tickets").Err()
+		}
+
+		response := BuyTicketResponse{
+			BuyTicketData: BuyTicketData{
+				OrderID:      orderID,
+				EventID:      eventID,
+				OrderStatus:  db.OrderStatusPaid,
+				Breakdown:    breakdown,
+				Items:        purchased,
+				TicketAmount: len(ticketIds),
+				Attendees:    attendees,
+				TicketIDs:    ticketIds,
+			},
+		}
+		// kept with the order so a retry under the same key gets the same answer instead of a second order
+		if req.IdempotencyKey != "" {
+			responseData, err := json.Marshal(response)
+			if err != nil {
+				return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while marshalling the response").Err()
+			}
+			err = qtx.SetOrderIdempotencyKey(ctx, db.SetOrderIdempotencyKeyParams{
+				IdempotencyKey: req.IdempotencyKey,
+				RequestHash:    requestHash,
+				Response:       responseData,
+				OrderID:        orderID,
+			})
+			if err != nil {
+				return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while storing the idempotency key").Err()
+			}
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			rlog.Error("failed to commit your transaction", "err", err.Error())
+			return nil, eb.Cause(err).Code(errs.DataLoss).Msg("failed to commit your transaction").Err()
+		}
+		committed = true
+
+		// the QR codes are only sent by email, which is what makes the per-email cap hold
+		sendPurchaseMail(ctx, []string{req.BuyerEmail}, mailtempl.PurchaseConfirmation{
+			CustomerName: req.BuyerName,
+			ItemName:     cartTitle(lines),
+			Items:        purchasedItems(purchased),
+			Subtotal:     breakdown.Subtotal,
+			Discount:     breakdown.Discount,
+			PromoCode:    promoCode.String,
+			Fee:          breakdown.Fee,
+			FeeAbsorbed:  breakdown.FeeAbsorbed,
+			Tax:          breakdown.Tax,
+			TaxName:      breakdown.TaxName,
+			TotalPrice:   breakdown.Total,
+			OrderNumber:  uuid.UUID(orderID.Bytes).String(),
+		}, ticketHashes, attendees)
+
+		return &BaseResponse[BuyTicketResponse]{
+			Data:    response,
+			Message: "Tickets confirmed",
+		}, nil
+	}
+
+	providerName, err := qtx.GetEventPaymentProvider(ctx, eventID)
 	if err != nil {
-		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while converting price to int").Err()
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the payment provider").Err()
 	}
+	provider, err := paymentProvider(providerName)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("The event's payment provider is not available").Err()
+	}
+
+	expiredAt := time.Now().Add(ReservationTTL)
 
-	// create bill
-	createBillRes, err := CreateBill(ctx, &CreateBillRequest{
-		Title:  availableTickets[0].Name,
-		Amount: req.TicketAmount*price + (req.TicketAmount * 1000),
-		Type:   "SINGLE",
-		ExpiredDate: time.Now().Add(7 * time.Minute).Format("2006-01-02 15:04"),
+	orderID, err := createOrder(ctx, qtx, db.InsertOrderParams{
+		EventID:         eventID,
+		BuyerName:       req.BuyerName,
+		BuyerEmail:      req.BuyerEmail,
+		Subtotal:        breakdown.Subtotal.Amount,
+		Discount:        breakdown.Discount.Amount,
+		PromoCode:       promoCode,
+		Fee:             breakdown.Fee.Amount,
+		FeeAbsorbed:     breakdown.FeeAbsorbed,
+		Tax:             breakdown.Tax.Amount,
+		TaxName:         breakdown.TaxName,
+		Amount:          amount.Amount,
+		Currency:        amount.Currency,
+		PaymentProvider: provider.Name(),
+	}, orderItems)
+	if err != nil {
+		rlog.Error("An error occurred while creating the order", "BuyTickets:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while creating the order").Err()
+	}
+	if promo.ID.Valid {
+		if err := redeemPromoCode(ctx, qtx, promo, orderID, discount); err != nil {
+			return nil, err
+		}
+	}
+	if hiddenTickets > 0 {
+		if err := redeemAccessCode(ctx, qtx, accessCode, orderID, hiddenTickets); err != nil {
+			return nil, err
+		}
+	}
+
+	// create one bill for the whole cart
+	createBillRes, err := provider.CreateBill(ctx, &CreateBillRequest{
+		Title:       cartTitle(lines),
+		Amount:      int(amount.Amount),
+		Currency:    amount.Currency,
+		Type:        "SINGLE",
+		ExpiredDate: expiredAt.Format("2006-01-02 15:04"),
 	})
 	if err != nil {
 		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while creating a bill").Err()
 	}
 	rlog.Info("CreateBillResponse: ", "createBillRes", createBillRes)
 
-	var ticketIds []pgtype.UUID
-	var ticketHashes []string
-	for _, ticket := range availableTickets {
-		if err := query.ChangeTicketsStatus(ctx, db.ChangeTicketsStatusParams{
+	err = qtx.UpdateOrderProviderReference(ctx, db.UpdateOrderProviderReferenceParams{
+		ProviderReference: pgtype.Text{
+			String: strconv.Itoa(createBillRes.LinkID),
+			Valid:  true,
+		},
+		OrderID: orderID,
+	})
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating the order").Err()
+	}
+	if err := transitionOrder(ctx, qtx, orderID, db.OrderStatusAwaitingPayment, "bill created"); err != nil {
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while updating the order").Err()
+	}
+
+	for _, ticketID := range ticketIds {
+		if err := qtx.ChangeTicketsStatus(ctx, db.ChangeTicketsStatusParams{
 			Status:   db.TicketStatusPending,
-			TicketID: ticket.ID,
+			TicketID: ticketID,
 		}); err != nil {
 			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while changing ticket status").Err()
 		}
+	}
 
-		ticketIds = append(ticketIds, ticket.ID)
-		ticketHashes = append(ticketHashes, ticket.Hash.String)
+	// store the reservation so the payment callback can pick it up, even on another instance
+	attendeesData, err := json.Marshal(attendees)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while marshalling attendees").Err()
 	}
 
-	// store buy ticket data
-	reserveKey := fmt.Sprintf("reserve:%d", createBillRes.LinkID)
-	buyTicketData[reserveKey] = BuyTicketData{
-		TicketAmount: int(availableTickets[0].Count),
-		Attendees:    req.Attendees,
-		TicketIDs:    ticketIds,
-		TicketHashes: ticketHashes,
-		EventID: pgtype.UUID{
-			Bytes: id,
-			Valid: true,
+	response := BuyTicketResponse{
+		BuyTicketData{
+			OrderID:      orderID,
+			EventID:      eventID,
+			OrderStatus:  db.OrderStatusAwaitingPayment,
+			Breakdown:    breakdown,
+			Items:        purchased,
+			TicketAmount: len(ticketIds),
+			Attendees:    attendees,
+			TicketIDs:    ticketIds,
+		},
+		CreateBillResponse{
+			LinkID:                createBillRes.LinkID,
+			LinkURL:               createBillRes.LinkURL,
+			Title:                 createBillRes.Title,
+			Type:                  createBillRes.Type,
+			Amount:                createBillRes.Amount,
+			RedirectURL:           createBillRes.RedirectURL,
+			ExpiredDate:           createBillRes.ExpiredDate,
+			CreatedFrom:           createBillRes.CreatedFrom,
+			Status:                createBillRes.Status,
+			Step:                  createBillRes.Step,
+			IsAddressRequired:     createBillRes.IsAddressRequired,
+			IsPhoneNumberRequired: createBillRes.IsPhoneNumberRequired,
 		},
 	}
+	// kept with the reservation so a retry under the same key gets the same answer
+	responseData, err := json.Marshal(response)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while marshalling the response").Err()
+	}
 
-	// Start a goroutine to handle the timeout
-	go func() {
-		rlog.Info("Checking payment existence", "billLinkID", createBillRes.LinkID)
-		time.Sleep(7 * time.Minute)
-		
-		// Check if payment exists
-		paymentExists, err := query.CheckPaymentExists(context.Background(), int32(createBillRes.LinkID))
-		if err != nil {
-			rlog.Error("Error checking payment existence", "err", err)
-			return
-		}
-
-		if !paymentExists {
-			// No payment received, change ticket status back to available
-			for _, ticketID := range ticketIds {
-				err := query.ChangeTicketsStatus(context.Background(), db.ChangeTicketsStatusParams{
-					Status:   db.TicketStatusAvailable,
-					TicketID: ticketID,
-				})
-				if err != nil {
-					rlog.Error("Error reverting ticket status", "ticketID", ticketID, "err", err)
-				}
-			}
-			// Remove the reservation data
-			delete(buyTicketData, reserveKey)
-			rlog.Info("Reverted ticket statuses due to no payment", "billLinkID", createBillRes.LinkID)
-		} else {
-			rlog.Info("Payment received", "billLinkID", createBillRes.LinkID)
-		}
-	}()
+	_, err = qtx.InsertReservation(ctx, db.InsertReservationParams{
+		BillLinkID:      int32(createBillRes.LinkID),
+		EventID:         eventID,
+		OrderID:         orderID,
+		TicketIds:       ticketIds,
+		TicketHashes:    ticketHashes,
+		Attendees:       attendeesData,
+		Amount:          amount.Amount,
+		Currency:        amount.Currency,
+		PaymentProvider: provider.Name(),
+		ExpiredAt: pgtype.Timestamptz{
+			Time:  expiredAt,
+			Valid: true,
+		},
+		IdempotencyKey: pgtype.Text{
+			String: req.IdempotencyKey,
+			Valid:  req.IdempotencyKey != "",
+		},
+		RequestHash: pgtype.Text{
+			String: requestHash,
+			Valid:  requestHash != "",
+		},
+		Response: responseData,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while storing the reservation", "BuyTickets:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while storing the reservation").Err()
+	}
 
 	// Commit the transaction if all tickets are deleted successfully
 	if err := tx.Commit(ctx); err != nil {
@@ -394,40 +1101,34 @@ func BuyTickets(ctx context.Context, id uuid.UUID, req *BuyTicketRequest) (*Base
 	committed = true
 
 	return &BaseResponse[BuyTicketResponse]{
-		Data: BuyTicketResponse{
-			BuyTicketData{
-				EventID:      buyTicketData[reserveKey].EventID,
-				TicketAmount: req.TicketAmount,
-				Attendees:    req.Attendees,
-				TicketIDs:    buyTicketData[reserveKey].TicketIDs,
-			},
-			CreateBillResponse{
-				LinkID:                createBillRes.LinkID,
-				LinkURL:               createBillRes.LinkURL,
-				Title:                 createBillRes.Title,
-				Type:                  createBillRes.Type,
-				Amount:                createBillRes.Amount,
-				RedirectURL:           createBillRes.RedirectURL,
-				ExpiredDate:           createBillRes.ExpiredDate,
-				CreatedFrom:           createBillRes.CreatedFrom,
-				Status:                createBillRes.Status,
-				Step:                  createBillRes.Step,
-				IsAddressRequired:     createBillRes.IsAddressRequired,
-				IsPhoneNumberRequired: createBillRes.IsPhoneNumberRequired,
-			},
-		},
+		Data:    response,
 		Message: "Tickets reserved",
 	}, nil
 }
 
 // BuyTicketData holds the data required for purchasing tickets for an event.
 type BuyTicketData struct {
+	OrderID      pgtype.UUID          `json:"order_id"`
 	EventID      pgtype.UUID          `json:"event_id"`
+	OrderStatus  db.OrderStatus       `json:"order_status"`
+	Items        []OrderItem          `json:"items"`
 	TicketAmount int                  `json:"ticket_amount"`
 	Attendees    []*map[string]string `json:"attendees"`
 	TicketIDs    []pgtype.UUID        `json:"ticket_ids"`
 	TicketHashes []string             `json:"ticket_hashes"`
+	Breakdown    PriceBreakdown       `json:"breakdown"`
 }
 
-// buyTicketData is a map that stores temporary BuyTicketData keyed by a unique payment link_id identifier.
-var buyTicketData = map[string]BuyTicketData{}
+// validatePrice checks that a ticket price is a non-negative amount in minor units of a supported currency.
+func validatePrice(price int64, currency string) error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if price < 0 {
+		return eb.Msg("Price must not be negative").Err()
+	}
+	if currency != "" && !money.IsSupported(currency) {
+		return eb.Msgf("Unsupported currency %q", currency).Err()
+	}
+
+	return nil
+}
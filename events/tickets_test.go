@@ -0,0 +1,144 @@
+//go:build encore_app
+
+// Run with `encore test ./events/...`, which gives the service a fresh test database. Plain `go test` cannot start
+// the service, so the build tag keeps these tests out of it.
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+)
+
+// seedTicketType creates an event paid through the fake provider with one ticket type of the given mode and stock.
+func seedTicketType(t *testing.T, ctx context.Context, mode db.InventoryMode, stock int) (uuid.UUID, db.TicketType) {
+	t.Helper()
+
+	now := time.Now()
+	eventID, err := query.InsertEvent(ctx, db.InsertEventParams{
+		Name:        fmt.Sprintf("Oversell %s", mode),
+		Description: "Concurrent purchases",
+		Location:    "Test",
+		EventStartDate: pgtype.Timestamptz{
+			Time:  now.Add(24 * time.Hour),
+			Valid: true,
+		},
+		EventEndDate: pgtype.Timestamptz{
+			Time:  now.Add(48 * time.Hour),
+			Valid: true,
+		},
+		PaymentProvider:        PaymentProviderFake,
+		FeeType:                db.FeeTypeFlat,
+		FeePer:                 db.FeePerOrder,
+		MaxFreeTicketsPerEmail: defaultMaxFreeTicketsPerEmail,
+	})
+	if err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	ticketTypeID, err := query.InsertTicketType(ctx, db.InsertTicketTypeParams{
+		EventID:       eventID,
+		Name:          "Regular",
+		Description:   "Regular ticket",
+		Price:         10000,
+		Currency:      "IDR",
+		Benefits:      []byte("[]"),
+		InventoryMode: mode,
+		Visibility:    db.TicketVisibilityPublic,
+	})
+	if err != nil {
+		t.Fatalf("insert ticket type: %v", err)
+	}
+	ticketType, err := query.GetTicketTypeForUpdate(ctx, ticketTypeID)
+	if err != nil {
+		t.Fatalf("get ticket type: %v", err)
+	}
+	if _, err := addTickets(ctx, query, ticketType, stock); err != nil {
+		t.Fatalf("add tickets: %v", err)
+	}
+
+	return uuid.UUID(eventID.Bytes), ticketType
+}
+
+// TestBuyTicketsDoesNotOversell fires more concurrent purchases than there are tickets at each inventory mode and
+// checks that no more tickets are sold or reserved than were on sale, and none of them twice.
+func TestBuyTicketsDoesNotOversell(t *testing.T) {
+	const (
+		stock    = 5
+		buyers   = 20
+		perOrder = 2
+	)
+
+	for _, mode := range []db.InventoryMode{db.InventoryModeRows, db.InventoryModeCounter} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx := context.Background()
+			eventID, ticketType := seedTicketType(t, ctx, mode, stock)
+
+			var (
+				mu        sync.Mutex
+				wg        sync.WaitGroup
+				purchases int
+				ticketIDs = map[pgtype.UUID]int{}
+			)
+			for i := 0; i < buyers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					attendees := make([]*map[string]string, perOrder)
+					for j := range attendees {
+						attendees[j] = &map[string]string{"name": fmt.Sprintf("Buyer %d-%d", i, j)}
+					}
+					res, err := BuyTickets(ctx, eventID, &BuyTicketRequest{
+						TicketName:   ticketType.Name,
+						TicketAmount: perOrder,
+						Attendees:    attendees,
+						BuyerName:    fmt.Sprintf("Buyer %d", i),
+						BuyerEmail:   fmt.Sprintf("buyer%d@example.com", i),
+					})
+					if err != nil {
+						return
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+					purchases++
+					for _, id := range res.Data.TicketIDs {
+						ticketIDs[id]++
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			if purchases == 0 {
+				t.Fatal("no purchase went through")
+			}
+			if sold := purchases * perOrder; sold > stock {
+				t.Errorf("sold %d tickets, only %d were on sale", sold, stock)
+			}
+			for id, count := range ticketIDs {
+				if count > 1 {
+					t.Errorf("ticket %x was sold %d times", id.Bytes, count)
+				}
+			}
+
+			counts, err := query.GetTicketType(ctx, ticketType.ID)
+			if err != nil {
+				t.Fatalf("get ticket type: %v", err)
+			}
+			if counts.Claimed > stock {
+				t.Errorf("%d tickets are sold or reserved, only %d were on sale", counts.Claimed, stock)
+			}
+			if counts.Claimed != int64(len(ticketIDs)) {
+				t.Errorf("%d tickets are sold or reserved, purchases got %d", counts.Claimed, len(ticketIDs))
+			}
+		})
+	}
+}
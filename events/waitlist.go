@@ -0,0 +1,534 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/cron"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+	"github.com/lichtlabs/ggrims-service/mail"
+	mailtempl "github.com/lichtlabs/ggrims-service/mail/template"
+)
+
+// WaitlistOfferTTL is how long tickets are held for a waitlisted buyer before they go to the next one in line.
+const WaitlistOfferTTL = 2 * time.Hour
+
+// waitlistBatchSize caps how many offers a single waitlist transaction locks at once.
+const waitlistBatchSize = 100
+
+// Expire unclaimed offers and mail new ones every minute.
+var _ = cron.NewJob("process-waitlist", cron.JobConfig{
+	Title:    "Offer returned tickets to the waitlist",
+	Every:    1 * cron.Minute,
+	Endpoint: ProcessWaitlist,
+})
+
+// WaitlistEntry is a buyer waiting for tickets of a sold out type.
+type WaitlistEntry struct {
+	ID             pgtype.UUID        `json:"id"`
+	EventID        pgtype.UUID        `json:"event_id"`
+	TicketTypeID   pgtype.UUID        `json:"ticket_type_id"`
+	Name           string             `json:"name"`
+	Email          string             `json:"email"`
+	Quantity       int32              `json:"quantity"`
+	State          db.WaitlistState   `json:"state"`
+	OfferExpiresAt pgtype.Timestamptz `json:"offer_expires_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+func toWaitlistEntry(entry db.WaitlistEntry) WaitlistEntry {
+	return WaitlistEntry{
+		ID:             entry.ID,
+		EventID:        entry.EventID,
+		TicketTypeID:   entry.TicketTypeID,
+		Name:           entry.Name,
+		Email:          entry.Email,
+		Quantity:       entry.Quantity,
+		State:          entry.State,
+		OfferExpiresAt: entry.OfferExpiresAt,
+		CreatedAt:      entry.CreatedAt,
+		UpdatedAt:      entry.UpdatedAt,
+	}
+}
+
+// JoinWaitlistRequest represents the payload required to join the waitlist of a ticket type.
+type JoinWaitlistRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Quantity int32  `json:"quantity"`
+}
+
+// Validate requires a name, a valid email and at least one ticket.
+func (req *JoinWaitlistRequest) Validate() error {
+	eb := errs.B().Code(errs.InvalidArgument)
+
+	if strings.TrimSpace(req.Name) == "" {
+		return eb.Msg("Name is required").Err()
+	}
+	if _, err := netmail.ParseAddress(req.Email); err != nil {
+		return eb.Msg("A valid email is required").Err()
+	}
+	if req.Quantity < 1 {
+		return eb.Msg("Quantity must be at least 1").Err()
+	}
+
+	return nil
+}
+
+// JoinWaitlist Join the waitlist of a ticket type that has fewer tickets left than wanted
+//
+//encore:api public method=POST path=/v1/ticket-types/:id/waitlist
+func JoinWaitlist(ctx context.Context, id uuid.UUID, req *JoinWaitlistRequest) (*BaseResponse[WaitlistEntry], error) {
+	eb := errs.B()
+
+	ticketType, err := query.GetTicketType(ctx, pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	})
+	// hidden types are sold with access codes only, their tickets are not offered to a waitlist
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && ticketType.Visibility == db.TicketVisibilityHidden) {
+		return nil, eb.Code(errs.NotFound).Msg("Ticket type not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving ticket type", "JoinWaitlist:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving ticket type").Err()
+	}
+	if err := checkOrderQuantity(ticketType.Name, int(req.Quantity), ticketType.Min, ticketType.Max); err != nil {
+		return nil, err
+	}
+	// the waitlist is for sold out types, offers would otherwise hold tickets that can still be bought
+	if ticketType.Available >= int64(req.Quantity) {
+		return nil, eb.Code(errs.FailedPrecondition).Msgf("%d %s tickets are still available, buy them instead of joining the waitlist", ticketType.Available, ticketType.Name).Err()
+	}
+
+	entry, err := query.InsertWaitlistEntry(ctx, db.InsertWaitlistEntryParams{
+		EventID:      ticketType.EventID,
+		TicketTypeID: ticketType.ID,
+		Name:         strings.TrimSpace(req.Name),
+		Email:        strings.TrimSpace(req.Email),
+		Quantity:     req.Quantity,
+	})
+	if isUniqueViolation(err) {
+		return nil, eb.Code(errs.AlreadyExists).Msg("This email is already on the waitlist").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while joining the waitlist", "JoinWaitlist:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while joining the waitlist").Err()
+	}
+
+	return &BaseResponse[WaitlistEntry]{
+		Data:    toWaitlistEntry(entry),
+		Message: "Joined the waitlist successfully",
+	}, nil
+}
+
+// ListWaitlist List the waitlist of a ticket type in the order offers are made
+//
+//encore:api auth method=GET path=/v1/ticket-types/:id/waitlist
+func ListWaitlist(ctx context.Context, id uuid.UUID, params *ListQuery) (*BaseResponse[[]WaitlistEntry], error) {
+	eb := errs.B()
+
+	extractedParam := extractQuery(params)
+	data, err := query.ListWaitlistEntry(ctx, db.ListWaitlistEntryParams{
+		TicketTypeID: pgtype.UUID{
+			Bytes: id,
+			Valid: true,
+		},
+		Offsets: extractedParam.Page,
+		Limits:  extractedParam.Limit,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while retrieving the waitlist", "ListWaitlist:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving the waitlist").Err()
+	}
+
+	entries := make([]WaitlistEntry, 0, len(data))
+	for _, entry := range data {
+		entries = append(entries, toWaitlistEntry(entry))
+	}
+
+	return &BaseResponse[[]WaitlistEntry]{
+		Data:    entries,
+		Message: "Waitlist retrieved successfully",
+	}, nil
+}
+
+// WaitlistOffer describes the tickets held for a waitlisted buyer. They are bought through BuyTickets with the
+// offer's claim token.
+type WaitlistOffer struct {
+	EventID      pgtype.UUID        `json:"event_id"`
+	TicketTypeID pgtype.UUID        `json:"ticket_type_id"`
+	TicketName   string             `json:"ticket_name"`
+	Quantity     int32              `json:"quantity"`
+	State        db.WaitlistState   `json:"state"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
+}
+
+// GetWaitlistOffer Get the tickets held by a waitlist claim link
+//
+//encore:api public method=GET path=/v1/waitlist/offers/:token
+func GetWaitlistOffer(ctx context.Context, token string) (*BaseResponse[WaitlistOffer], error) {
+	eb := errs.B()
+
+	offer, err := query.GetWaitlistOffer(ctx, token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Waitlist offer not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving the waitlist offer", "GetWaitlistOffer:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving the waitlist offer").Err()
+	}
+
+	return &BaseResponse[WaitlistOffer]{
+		Data: WaitlistOffer{
+			EventID:      offer.EventID,
+			TicketTypeID: offer.TicketTypeID,
+			TicketName:   offer.TicketName,
+			Quantity:     offer.Quantity,
+			State:        offer.State,
+			ExpiresAt:    offer.OfferExpiresAt,
+		},
+		Message: "Waitlist offer retrieved successfully",
+	}, nil
+}
+
+// claimWaitlistOffer checks that the claim token holds an open offer on the event and returns it locked, so the
+// offer cannot expire or be claimed twice while the purchase goes on.
+func claimWaitlistOffer(ctx context.Context, q *db.Queries, eventID pgtype.UUID, token string) (db.WaitlistEntry, error) {
+	eb := errs.B()
+
+	offer, err := q.GetWaitlistOfferForUpdate(ctx, token)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && offer.EventID != eventID) {
+		return offer, eb.Code(errs.NotFound).Msg("Waitlist offer not found").Err()
+	}
+	if err != nil {
+		return offer, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving the waitlist offer").Err()
+	}
+	if offer.State != db.WaitlistStateOffered {
+		return offer, eb.Code(errs.FailedPrecondition).Msgf("The waitlist offer is %s", offer.State).Err()
+	}
+	if offer.OfferExpiresAt.Time.Before(time.Now()) {
+		return offer, eb.Code(errs.FailedPrecondition).Msg("The waitlist offer has expired").Err()
+	}
+
+	return offer, nil
+}
+
+// offerToWaitlist holds available tickets of each type for the people waiting on it, in the order they joined, as
+// long as enough are left for the next one in line. It runs wherever tickets come back, in the same transaction,
+// so nobody else can buy returned tickets before the waitlist had its turn.
+func offerToWaitlist(ctx context.Context, q *db.Queries, ticketTypeIDs []pgtype.UUID) error {
+	for _, ticketTypeID := range ticketTypeIDs {
+		for {
+			offered, err := offerNextWaitlistEntry(ctx, q, ticketTypeID)
+			if err != nil {
+				return err
+			}
+			if !offered {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// offerNextWaitlistEntry holds tickets for the first person waiting on the type. It reports false when nobody is
+// waiting or not enough tickets are left for them.
+func offerNextWaitlistEntry(ctx context.Context, q *db.Queries, ticketTypeID pgtype.UUID) (bool, error) {
+	entry, err := q.GetNextWaitlistEntryForUpdate(ctx, ticketTypeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	ticketType, err := q.GetTicketType(ctx, ticketTypeID)
+	if err != nil {
+		return false, err
+	}
+
+	var ticketIDs []pgtype.UUID
+	if ticketType.InventoryMode == db.InventoryModeCounter {
+		issued, err := reserveCounterTickets(ctx, q, ticketTypeID, int(entry.Quantity))
+		if err != nil || len(issued) == 0 {
+			return false, err
+		}
+		for _, ticket := range issued {
+			ticketIDs = append(ticketIDs, ticket.ID)
+		}
+	} else {
+		tickets, err := q.GetAvailableTickets(ctx, db.GetAvailableTicketsParams{
+			TicketTypeID: ticketTypeID,
+			Limits:       entry.Quantity,
+		})
+		if err != nil || len(tickets) < int(entry.Quantity) {
+			return false, err
+		}
+
+		for _, ticket := range tickets {
+			err := q.ChangeTicketsStatus(ctx, db.ChangeTicketsStatusParams{
+				Status:   db.TicketStatusPending,
+				TicketID: ticket.ID,
+			})
+			if err != nil {
+				return false, err
+			}
+			ticketIDs = append(ticketIDs, ticket.ID)
+		}
+	}
+
+	token, err := newClaimToken()
+	if err != nil {
+		return false, err
+	}
+
+	err = q.OfferWaitlistEntry(ctx, db.OfferWaitlistEntryParams{
+		ClaimToken: token,
+		TicketIds:  ticketIDs,
+		OfferExpiresAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(WaitlistOfferTTL),
+			Valid: true,
+		},
+		EntryID: entry.ID,
+	})
+	if err != nil {
+		return false, err
+	}
+	rlog.Info("Offered tickets to the waitlist", "ticketTypeID", ticketTypeID, "entryID", entry.ID, "quantity", entry.Quantity)
+
+	return true, nil
+}
+
+// newClaimToken returns the secret that makes up a waitlist claim link.
+func newClaimToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// ProcessWaitlistResponse reports what a waitlist run did.
+type ProcessWaitlistResponse struct {
+	Expired  int `json:"expired"`
+	Notified int `json:"notified"`
+}
+
+// ProcessWaitlist puts the tickets of unclaimed offers back to the waitlist, offers tickets that are on sale to
+// people still waiting, for example after capacity was added, and mails the claim links of new offers.
+//
+//encore:api private
+func ProcessWaitlist(ctx context.Context) (*ProcessWaitlistResponse, error) {
+	eb := errs.B()
+
+	expired := 0
+	for {
+		n, err := expireWaitlistBatch(ctx)
+		if err != nil {
+			rlog.Error("An error occurred while expiring waitlist offers", "ProcessWaitlist:err", err.Error())
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while expiring waitlist offers").Err()
+		}
+		expired += n
+
+		if n < waitlistBatchSize {
+			break
+		}
+	}
+
+	if err := offerAvailableTickets(ctx); err != nil {
+		rlog.Error("An error occurred while offering tickets to the waitlist", "ProcessWaitlist:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while offering tickets to the waitlist").Err()
+	}
+
+	notified := 0
+	for {
+		n, err := notifyWaitlistBatch(ctx)
+		if err != nil {
+			rlog.Error("An error occurred while mailing waitlist offers", "ProcessWaitlist:err", err.Error())
+			return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while mailing waitlist offers").Err()
+		}
+		notified += n
+
+		if n < waitlistBatchSize {
+			break
+		}
+	}
+
+	if expired > 0 || notified > 0 {
+		rlog.Info("Processed the waitlist", "expired", expired, "notified", notified)
+	}
+
+	return &ProcessWaitlistResponse{
+		Expired:  expired,
+		Notified: notified,
+	}, nil
+}
+
+// expireWaitlistBatch releases the tickets of one batch of unclaimed offers and offers them to the next in line.
+func expireWaitlistBatch(ctx context.Context) (int, error) {
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+	offers, err := qtx.ListExpiredWaitlistOffersForUpdate(ctx, waitlistBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	ticketTypeIDs := make([]pgtype.UUID, 0, len(offers))
+	for _, offer := range offers {
+		if err := releaseTickets(ctx, qtx, offer.TicketIds); err != nil {
+			return 0, err
+		}
+		err := qtx.UpdateWaitlistEntryState(ctx, db.UpdateWaitlistEntryStateParams{
+			State:   db.WaitlistStateExpired,
+			EntryID: offer.ID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		ticketTypeIDs = append(ticketTypeIDs, offer.TicketTypeID)
+	}
+
+	if err := offerToWaitlist(ctx, qtx, ticketTypeIDs); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	committed = true
+
+	return len(offers), nil
+}
+
+// offerAvailableTickets offers tickets that are on sale to the types people are still waiting on.
+func offerAvailableTickets(ctx context.Context) error {
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+	ticketTypeIDs, err := qtx.ListWaitlistedTicketTypes(ctx)
+	if err != nil {
+		return err
+	}
+	if err := offerToWaitlist(ctx, qtx, ticketTypeIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	committed = true
+
+	return nil
+}
+
+// notifyWaitlistBatch mails the claim links of one batch of new offers. Offers are marked as mailed before the
+// mails go out, so a failed mail is logged and not retried.
+func notifyWaitlistBatch(ctx context.Context) (int, error) {
+	tx, err := pgxDB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				rlog.Error("failed to rollback transaction", "err", err.Error())
+			}
+		}
+	}()
+
+	qtx := query.WithTx(tx)
+	offers, err := qtx.ListUnnotifiedWaitlistOffersForUpdate(ctx, waitlistBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, offer := range offers {
+		if err := qtx.MarkWaitlistOfferNotified(ctx, offer.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	committed = true
+
+	for _, offer := range offers {
+		sendWaitlistOfferMail(ctx, offer)
+	}
+
+	return len(offers), nil
+}
+
+// sendWaitlistOfferMail mails the claim link of an offer. Errors are logged only.
+func sendWaitlistOfferMail(ctx context.Context, offer db.ListUnnotifiedWaitlistOffersForUpdateRow) {
+	claimURL := fmt.Sprintf("%s?token=%s", secrets.WaitlistClaimURL, url.QueryEscape(offer.ClaimToken.String))
+
+	var buff bytes.Buffer
+	err := mailtempl.WaitlistOfferEmail(mailtempl.WaitlistOffer{
+		CustomerName: offer.Name,
+		TicketName:   offer.TicketName,
+		Quantity:     offer.Quantity,
+		ClaimURL:     claimURL,
+		ExpiresAt:    offer.OfferExpiresAt.Time,
+	}).Render(ctx, &buff)
+	if err != nil {
+		rlog.Error("Error: Error rendering waitlist offer email: ", err.Error())
+		return
+	}
+
+	err = mail.SendTicketMail(ctx, &mail.SendTicketMailRequest{
+		Subject:    fmt.Sprintf("%s tickets are waiting for you", offer.TicketName),
+		Recipients: []string{offer.Email},
+		Body:       buff.String(),
+	})
+	if err != nil {
+		rlog.Error("Error: Error sending waitlist offer mail: ", err.Error())
+	}
+}
@@ -0,0 +1,234 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"encore.dev/types/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lichtlabs/ggrims-service/events/db"
+)
+
+// processWebhook parses a stored callback payload with its provider and settles the payment it reports, recording
+// the outcome on the webhook. A transaction that an earlier webhook already settled is marked as a duplicate and
+// not applied again.
+func processWebhook(ctx context.Context, provider PaymentProvider, webhookID pgtype.UUID, contentType string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	update := db.UpdatePaymentWebhookParams{
+		WebhookID: webhookID,
+	}
+	record := func(status db.WebhookStatus, cause error) {
+		update.Status = status
+		update.Error = ""
+		if cause != nil {
+			update.Error = cause.Error()
+		}
+		if err := query.UpdatePaymentWebhook(ctx, update); err != nil {
+			rlog.Error("Error updating webhook", "webhookID", webhookID, "err", err)
+		}
+	}
+
+	tx, err := provider.ParseCallback(req)
+	if errors.Is(err, ErrInvalidCallback) {
+		update.SignatureValid = pgtype.Bool{
+			Bool:  false,
+			Valid: true,
+		}
+		record(db.WebhookStatusRejected, err)
+		return err
+	}
+	if err != nil {
+		rlog.Error("Error parsing callback", "provider", provider.Name(), "err", err)
+		record(db.WebhookStatusFailed, err)
+		return fmt.Errorf("%w: %v", errMalformedCallback, err)
+	}
+
+	update.SignatureValid = pgtype.Bool{
+		Bool:  true,
+		Valid: true,
+	}
+	update.TransactionID = pgtype.Text{
+		String: tx.ID,
+		Valid:  tx.ID != "",
+	}
+	update.BillLinkID = pgtype.Int4{
+		Int32: int32(tx.BillLinkID),
+		Valid: true,
+	}
+
+	if tx.ID != "" {
+		processed, err := query.HasProcessedPaymentWebhook(ctx, db.HasProcessedPaymentWebhookParams{
+			Provider:      provider.Name(),
+			TransactionID: update.TransactionID,
+			WebhookID:     webhookID,
+		})
+		if err != nil {
+			record(db.WebhookStatusFailed, err)
+			return err
+		}
+		if processed {
+			rlog.Info("Skipping duplicate callback", "provider", provider.Name(), "transactionID", tx.ID)
+			record(db.WebhookStatusDuplicate, nil)
+			return nil
+		}
+	}
+
+	err = settlePayment(ctx, provider, tx)
+	switch {
+	case err == nil:
+		record(db.WebhookStatusProcessed, nil)
+	case errors.Is(err, errReservationSettled):
+		record(db.WebhookStatusDuplicate, err)
+	case errors.Is(err, errPaymentNotSettled):
+		// nothing was applied, a later callback for the same transaction must not be skipped as a duplicate
+		record(db.WebhookStatusReceived, err)
+	default:
+		record(db.WebhookStatusFailed, err)
+	}
+
+	return err
+}
+
+// PaymentWebhook represents a payment callback as it was received, and what became of it.
+type PaymentWebhook struct {
+	ID             pgtype.UUID        `json:"id"`
+	Provider       string             `json:"provider"`
+	ContentType    string             `json:"content_type"`
+	Payload        string             `json:"payload"`
+	SignatureValid *bool              `json:"signature_valid"`
+	TransactionID  string             `json:"transaction_id"`
+	BillLinkID     *int32             `json:"bill_link_id"`
+	Status         db.WebhookStatus   `json:"status"`
+	Error          string             `json:"error"`
+	Attempts       int32              `json:"attempts"`
+	ProcessedAt    pgtype.Timestamptz `json:"processed_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+func toPaymentWebhook(webhook db.PaymentWebhook) PaymentWebhook {
+	data := PaymentWebhook{
+		ID:            webhook.ID,
+		Provider:      webhook.Provider,
+		ContentType:   webhook.ContentType,
+		Payload:       webhook.Payload,
+		TransactionID: webhook.TransactionID.String,
+		Status:        webhook.Status,
+		Error:         webhook.Error,
+		Attempts:      webhook.Attempts,
+		ProcessedAt:   webhook.ProcessedAt,
+		CreatedAt:     webhook.CreatedAt,
+		UpdatedAt:     webhook.UpdatedAt,
+	}
+	if webhook.SignatureValid.Valid {
+		data.SignatureValid = &webhook.SignatureValid.Bool
+	}
+	if webhook.BillLinkID.Valid {
+		data.BillLinkID = &webhook.BillLinkID.Int32
+	}
+
+	return data
+}
+
+// ListPaymentWebhooksQuery pages through the webhook inbox, optionally only webhooks with the given status.
+type ListPaymentWebhooksQuery struct {
+	Page    int32  `query:"page"`
+	Limit   int32  `query:"limit"`
+	OrderBy string `query:"order_by"`
+	Status  string `query:"status"`
+}
+
+// ListPaymentWebhooks List received payment callbacks, e.g. ?status=failed
+//
+//encore:api auth method=GET path=/v1/payment-webhooks
+func ListPaymentWebhooks(ctx context.Context, params *ListPaymentWebhooksQuery) (*BaseResponse[[]PaymentWebhook], error) {
+	eb := errs.B()
+
+	extractedParam := extractQuery(&ListQuery{
+		Page:    params.Page,
+		Limit:   params.Limit,
+		OrderBy: params.OrderBy,
+	})
+	data, err := query.ListPaymentWebhook(ctx, db.ListPaymentWebhookParams{
+		Status: pgtype.Text{
+			String: params.Status,
+			Valid:  params.Status != "",
+		},
+		OrderBy: extractedParam.OrderBy,
+		Offsets: extractedParam.Page,
+		Limits:  extractedParam.Limit,
+	})
+	if err != nil {
+		rlog.Error("An error occurred while retrieving payment webhooks", "ListPaymentWebhooks:err", err.Error())
+		return nil, eb.Code(errs.Internal).Msg("An error occurred while retrieving payment webhooks").Err()
+	}
+
+	webhooks := make([]PaymentWebhook, 0, len(data))
+	for _, webhook := range data {
+		webhooks = append(webhooks, toPaymentWebhook(webhook))
+	}
+
+	return &BaseResponse[[]PaymentWebhook]{
+		Data:    webhooks,
+		Message: "Payment webhooks retrieved successfully",
+	}, nil
+}
+
+// ReplayPaymentWebhook Process a failed or rejected payment callback again
+//
+// The stored payload goes through the provider's verification and settlement again, so a callback that failed
+// because of a fixed bug or a corrected secret can be applied without asking the provider to resend it.
+//
+//encore:api auth method=POST path=/v1/payment-webhooks/:id/replay
+func ReplayPaymentWebhook(ctx context.Context, id uuid.UUID) (*BaseResponse[PaymentWebhook], error) {
+	eb := errs.B()
+
+	webhookID := pgtype.UUID{
+		Bytes: id,
+		Valid: true,
+	}
+
+	webhook, err := query.GetPaymentWebhook(ctx, webhookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, eb.Code(errs.NotFound).Msg("Payment webhook not found").Err()
+	}
+	if err != nil {
+		rlog.Error("An error occurred while retrieving payment webhook", "ReplayPaymentWebhook:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving payment webhook").Err()
+	}
+	if webhook.Status == db.WebhookStatusProcessed || webhook.Status == db.WebhookStatusDuplicate {
+		return nil, eb.Code(errs.FailedPrecondition).Msgf("Payment webhook is already %s", webhook.Status).Err()
+	}
+
+	provider, err := paymentProvider(webhook.Provider)
+	if err != nil {
+		return nil, eb.Cause(err).Code(errs.FailedPrecondition).Msg("The webhook's payment provider is not available").Err()
+	}
+
+	// The outcome is recorded on the webhook, which is returned whether or not the replay succeeded
+	if err := processWebhook(ctx, provider, webhookID, webhook.ContentType, []byte(webhook.Payload)); err != nil {
+		rlog.Warn("Replayed webhook failed again", "webhookID", webhookID, "err", err.Error())
+	}
+
+	webhook, err = query.GetPaymentWebhook(ctx, webhookID)
+	if err != nil {
+		rlog.Error("An error occurred while retrieving payment webhook", "ReplayPaymentWebhook:err", err.Error())
+		return nil, eb.Cause(err).Code(errs.Internal).Msg("An error occurred while retrieving payment webhook").Err()
+	}
+
+	return &BaseResponse[PaymentWebhook]{
+		Data:    toPaymentWebhook(webhook),
+		Message: "Payment webhook replayed",
+	}, nil
+}
@@ -3,6 +3,8 @@ package mail
 import (
 	"fmt"
 	"log"
+	"strings"
+	"unicode"
 
 	"github.com/skip2/go-qrcode"
 )
@@ -33,3 +35,22 @@ func genTicketQR(hash string) []byte {
 
 	return png
 }
+
+// TicketAttachmentName names the QR code attachment of the i-th ticket, e.g. ticket-1-jane-doe.png, so buyers can
+// tell whose code is whose.
+func TicketAttachmentName(i int, label string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			return '-'
+		}
+		return -1
+	}, strings.TrimSpace(label))
+	if slug == "" {
+		return fmt.Sprintf("ticket-%d.png", i+1)
+	}
+
+	return fmt.Sprintf("ticket-%d-%s.png", i+1, slug)
+}
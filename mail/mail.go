@@ -17,8 +17,11 @@ var SenderName = fmt.Sprintf(
 )
 
 type SendTicketMailRequest struct {
+	Subject      string
 	Body         string
 	TicketHashes []string
+	// TicketLabels names who the ticket with the same index belongs to, and ends up in its attachment's name
+	TicketLabels []string
 	Recipients   []string
 }
 
@@ -30,7 +33,11 @@ func SendTicketMail(ctx context.Context, req *SendTicketMailRequest) error {
 	mailer.SetHeader("From", SenderName)
 	mailer.SetHeader("To", req.Recipients...)
 	mailer.SetAddressHeader("Cc", secrets.AdminMail, "Licht Labs Admin")
-	mailer.SetHeader("Subject", "Your ticket is here!")
+	subject := req.Subject
+	if subject == "" {
+		subject = "Your ticket is here!"
+	}
+	mailer.SetHeader("Subject", subject)
 	mailer.SetBody("text/html", req.Body)
 
 	createdFiles := []string{}
@@ -44,7 +51,11 @@ func SendTicketMail(ctx context.Context, req *SendTicketMailRequest) error {
 				rlog.Error("Failed to write qrcode to file", "error", err)
 				return err
 			}
-			mailer.Attach(filename)
+			label := ""
+			if i < len(req.TicketLabels) {
+				label = req.TicketLabels[i]
+			}
+			mailer.Attach(filename, gomail.Rename(TicketAttachmentName(i, label)))
 			createdFiles = append(createdFiles, filename)
 		}
 	}
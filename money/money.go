@@ -0,0 +1,147 @@
+// Package money represents prices as integer amounts in the minor units of a currency.
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used when a price does not name its currency.
+const DefaultCurrency = "IDR"
+
+// exponents holds the number of minor-unit digits of each supported currency.
+// IDR is treated as zero-decimal, the way Indonesian payment gateways bill it.
+var exponents = map[string]int{
+	"IDR": 0,
+	"SGD": 2,
+	"USD": 2,
+}
+
+// Money is an amount in the minor units of its ISO 4217 currency.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// New returns an amount of the given currency, falling back to DefaultCurrency when currency is empty.
+func New(amount int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	return Money{
+		Amount:   amount,
+		Currency: strings.ToUpper(currency),
+	}
+}
+
+// IsSupported reports whether currency is a known currency code.
+func IsSupported(currency string) bool {
+	_, ok := exponents[strings.ToUpper(currency)]
+	return ok
+}
+
+// Add returns the sum of m and o. Both must be in the same currency.
+func (m Money) Add(o Money) Money {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("money: cannot add %s to %s", o.Currency, m.Currency))
+	}
+
+	return Money{
+		Amount:   m.Amount + o.Amount,
+		Currency: m.Currency,
+	}
+}
+
+// Sub returns m minus o. Both must be in the same currency.
+func (m Money) Sub(o Money) Money {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("money: cannot subtract %s from %s", o.Currency, m.Currency))
+	}
+
+	return Money{
+		Amount:   m.Amount - o.Amount,
+		Currency: m.Currency,
+	}
+}
+
+// Mul returns m multiplied by n.
+func (m Money) Mul(n int64) Money {
+	return Money{
+		Amount:   m.Amount * n,
+		Currency: m.Currency,
+	}
+}
+
+// Percent returns the given share of m in basis points (1/100 of a percent), rounded half away from zero
+// to the nearest minor unit.
+func (m Money) Percent(basisPoints int64) Money {
+	product := m.Amount * basisPoints
+	amount := product / 10000
+	if rem := product % 10000; rem >= 5000 {
+		amount++
+	} else if rem <= -5000 {
+		amount--
+	}
+
+	return Money{
+		Amount:   amount,
+		Currency: m.Currency,
+	}
+}
+
+// Share returns part/whole of m, rounded down to the minor unit. The product is computed without overflowing, so
+// large amounts can be split by large weights.
+func (m Money) Share(part, whole int64) Money {
+	amount := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(part))
+	amount.Quo(amount, big.NewInt(whole))
+
+	return Money{
+		Amount:   amount.Int64(),
+		Currency: m.Currency,
+	}
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// String formats the amount with its currency code and thousands separators, e.g. "IDR 299,000" or "USD 12.50".
+func (m Money) String() string {
+	exp := exponents[m.Currency]
+
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	unit := pow10(exp)
+	major, minor := amount/unit, amount%unit
+
+	digits := strconv.FormatInt(major, 10)
+	var grouped strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(d)
+	}
+
+	if exp == 0 {
+		return fmt.Sprintf("%s %s%s", m.Currency, sign, grouped.String())
+	}
+	return fmt.Sprintf("%s %s%s.%0*d", m.Currency, sign, grouped.String(), exp, minor)
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
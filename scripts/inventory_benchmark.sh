@@ -0,0 +1,32 @@
+#!/bin/zsh
+#
+# Compares the rows and counter inventory modes: times creating a ticket type
+# of each mode with the given quantity, then fires the same purchase load at
+# both types. Purchases go through the payment provider, so run it against
+# the sandbox.
+#
+# usage: ./scripts/inventory_benchmark.sh <event_id> <auth_token> [quantity]
+
+EVENT_ID=${1:?event id required}
+AUTH_TOKEN=${2:?auth token required}
+QUANTITY=${3:-20000}
+BASE_URL="http://localhost:4000"
+SUFFIX=$(date +%s)
+
+for MODE in rows counter; do
+  TICKET_NAME="bench-${MODE}-${SUFFIX}"
+
+  CREATE_TIME=$(curl -s -o /dev/null -w "%{time_total}" -X POST \
+    -H "Authorization: Bearer ${AUTH_TOKEN}" \
+    -H "Content-Type: application/json" \
+    -d "{\"name\":\"${TICKET_NAME}\",\"description\":\"Inventory benchmark\",\"price\":10000,\"currency\":\"IDR\",\"benefits\":[],\"quantity\":${QUANTITY},\"inventory_mode\":\"${MODE}\"}" \
+    "${BASE_URL}/v1/events/${EVENT_ID}/ticket-types")
+  echo "${MODE}: created ${QUANTITY} tickets in ${CREATE_TIME}s"
+
+  echo "${MODE}: purchase throughput"
+  hey -n 1000 -c 50 -m POST \
+    -A "application/json" \
+    -H "Content-Type: application/json" \
+    -d "{\"ticket_name\":\"${TICKET_NAME}\",\"ticket_amount\":2,\"attendees\":[{\"name\":\"a\"},{\"name\":\"b\"}],\"buyer_name\":\"Bench\",\"buyer_email\":\"bench@example.com\"}" \
+    "${BASE_URL}/v1/events/${EVENT_ID}/tickets/buy" | grep -E "Requests/sec|Average|\[[0-9]+\]"
+done